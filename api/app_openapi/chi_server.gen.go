@@ -4,6 +4,7 @@
 package app_openapi
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
@@ -13,15 +14,45 @@ import (
 
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
-	// Request short url (token) for target url with expiration interval (in days) setting
+	// Request short url (token or alias) for target url with expiration interval (in days) setting
 	// (POST /)
 	CreateShortUrl(w http.ResponseWriter, r *http.Request)
+	// List short urls page by page
+	// (GET /links)
+	ListShortUrls(w http.ResponseWriter, r *http.Request, params ListShortUrlsParams)
+	// Delete short url (it can be restored within retention period of deleted links)
+	// (DELETE /{token})
+	DeleteShortUrl(w http.ResponseWriter, r *http.Request, token string)
 	// Redirect to target url by token
 	// (GET /{token})
 	HitShortUrl(w http.ResponseWriter, r *http.Request, token string)
+	// Update target url, expiration and / or targeting rules of short url
+	// (PATCH /{token})
+	UpdateShortUrl(w http.ResponseWriter, r *http.Request, token string)
+	// Redirect to target url of password protected short url by token and password
+	// (POST /{token})
+	UnlockShortUrl(w http.ResponseWriter, r *http.Request, token string)
+	// Get hit events of short url in [from, to) time range
+	// (GET /{token}/hits)
+	GetShortUrlHits(w http.ResponseWriter, r *http.Request, token string, params GetShortUrlHitsParams)
 	// Get short url info
 	// (GET /{token}/info)
 	GetShortUrlInfo(w http.ResponseWriter, r *http.Request, token string)
+	// Restore deleted short url (within retention period of deleted links)
+	// (POST /{token}/restore)
+	RestoreShortUrl(w http.ResponseWriter, r *http.Request, token string)
+	// Get hits of short url counted in hourly / daily time buckets
+	// (GET /{token}/stats)
+	GetShortUrlStats(w http.ResponseWriter, r *http.Request, token string, params GetShortUrlStatsParams)
+	// Estimate unique visitors of short url in time range (rounded to days)
+	// (GET /{token}/uniques)
+	GetShortUrlUniques(w http.ResponseWriter, r *http.Request, token string, params GetShortUrlUniquesParams)
+	// Redirect to target url of wildcard short url completed with path
+	// (GET /{token}/{path})
+	HitShortUrlPath(w http.ResponseWriter, r *http.Request, token string, path string)
+	// Redirect to target url of password protected wildcard short url completed with path by token and password
+	// (POST /{token}/{path})
+	UnlockShortUrlPath(w http.ResponseWriter, r *http.Request, token string, path string)
 }
 
 // ServerInterfaceWrapper converts contexts to parameters.
@@ -36,6 +67,8 @@ type MiddlewareFunc func(http.HandlerFunc) http.HandlerFunc
 func (siw *ServerInterfaceWrapper) CreateShortUrl(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{""})
+
 	var handler = func(w http.ResponseWriter, r *http.Request) {
 		siw.Handler.CreateShortUrl(w, r)
 	}
@@ -47,6 +80,122 @@ func (siw *ServerInterfaceWrapper) CreateShortUrl(w http.ResponseWriter, r *http
 	handler(w, r.WithContext(ctx))
 }
 
+// ListShortUrls operation middleware
+func (siw *ServerInterfaceWrapper) ListShortUrls(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListShortUrlsParams
+
+	// ------------- Optional query parameter "cursor" -------------
+	if paramValue := r.URL.Query().Get("cursor"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "cursor", r.URL.Query(), &params.Cursor)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter cursor: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+	if paramValue := r.URL.Query().Get("limit"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter limit: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "sort" -------------
+	if paramValue := r.URL.Query().Get("sort"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "sort", r.URL.Query(), &params.Sort)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter sort: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "order" -------------
+	if paramValue := r.URL.Query().Get("order"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "order", r.URL.Query(), &params.Order)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter order: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "expired" -------------
+	if paramValue := r.URL.Query().Get("expired"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "expired", r.URL.Query(), &params.Expired)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter expired: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "deleted" -------------
+	if paramValue := r.URL.Query().Get("deleted"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "deleted", r.URL.Query(), &params.Deleted)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter deleted: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListShortUrls(w, r, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// DeleteShortUrl operation middleware
+func (siw *ServerInterfaceWrapper) DeleteShortUrl(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "token" -------------
+	var token string
+
+	err = runtime.BindStyledParameter("simple", false, "token", chi.URLParam(r, "token"), &token)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter token: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteShortUrl(w, r, token)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
 // HitShortUrl operation middleware
 func (siw *ServerInterfaceWrapper) HitShortUrl(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -62,6 +211,8 @@ func (siw *ServerInterfaceWrapper) HitShortUrl(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{""})
+
 	var handler = func(w http.ResponseWriter, r *http.Request) {
 		siw.Handler.HitShortUrl(w, r, token)
 	}
@@ -73,6 +224,126 @@ func (siw *ServerInterfaceWrapper) HitShortUrl(w http.ResponseWriter, r *http.Re
 	handler(w, r.WithContext(ctx))
 }
 
+// UpdateShortUrl operation middleware
+func (siw *ServerInterfaceWrapper) UpdateShortUrl(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "token" -------------
+	var token string
+
+	err = runtime.BindStyledParameter("simple", false, "token", chi.URLParam(r, "token"), &token)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter token: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.UpdateShortUrl(w, r, token)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// UnlockShortUrl operation middleware
+func (siw *ServerInterfaceWrapper) UnlockShortUrl(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "token" -------------
+	var token string
+
+	err = runtime.BindStyledParameter("simple", false, "token", chi.URLParam(r, "token"), &token)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter token: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.UnlockShortUrl(w, r, token)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetShortUrlHits operation middleware
+func (siw *ServerInterfaceWrapper) GetShortUrlHits(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "token" -------------
+	var token string
+
+	err = runtime.BindStyledParameter("simple", false, "token", chi.URLParam(r, "token"), &token)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter token: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetShortUrlHitsParams
+
+	// ------------- Optional query parameter "from" -------------
+	if paramValue := r.URL.Query().Get("from"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "from", r.URL.Query(), &params.From)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter from: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "to" -------------
+	if paramValue := r.URL.Query().Get("to"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "to", r.URL.Query(), &params.To)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter to: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+	if paramValue := r.URL.Query().Get("limit"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter limit: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetShortUrlHits(w, r, token, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
 // GetShortUrlInfo operation middleware
 func (siw *ServerInterfaceWrapper) GetShortUrlInfo(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -88,6 +359,8 @@ func (siw *ServerInterfaceWrapper) GetShortUrlInfo(w http.ResponseWriter, r *htt
 		return
 	}
 
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{""})
+
 	var handler = func(w http.ResponseWriter, r *http.Request) {
 		siw.Handler.GetShortUrlInfo(w, r, token)
 	}
@@ -99,6 +372,225 @@ func (siw *ServerInterfaceWrapper) GetShortUrlInfo(w http.ResponseWriter, r *htt
 	handler(w, r.WithContext(ctx))
 }
 
+// RestoreShortUrl operation middleware
+func (siw *ServerInterfaceWrapper) RestoreShortUrl(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "token" -------------
+	var token string
+
+	err = runtime.BindStyledParameter("simple", false, "token", chi.URLParam(r, "token"), &token)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter token: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.RestoreShortUrl(w, r, token)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetShortUrlStats operation middleware
+func (siw *ServerInterfaceWrapper) GetShortUrlStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "token" -------------
+	var token string
+
+	err = runtime.BindStyledParameter("simple", false, "token", chi.URLParam(r, "token"), &token)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter token: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetShortUrlStatsParams
+
+	// ------------- Optional query parameter "from" -------------
+	if paramValue := r.URL.Query().Get("from"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "from", r.URL.Query(), &params.From)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter from: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "to" -------------
+	if paramValue := r.URL.Query().Get("to"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "to", r.URL.Query(), &params.To)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter to: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "granularity" -------------
+	if paramValue := r.URL.Query().Get("granularity"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "granularity", r.URL.Query(), &params.Granularity)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter granularity: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetShortUrlStats(w, r, token, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetShortUrlUniques operation middleware
+func (siw *ServerInterfaceWrapper) GetShortUrlUniques(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "token" -------------
+	var token string
+
+	err = runtime.BindStyledParameter("simple", false, "token", chi.URLParam(r, "token"), &token)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter token: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{""})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetShortUrlUniquesParams
+
+	// ------------- Optional query parameter "from" -------------
+	if paramValue := r.URL.Query().Get("from"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "from", r.URL.Query(), &params.From)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter from: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Optional query parameter "to" -------------
+	if paramValue := r.URL.Query().Get("to"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "to", r.URL.Query(), &params.To)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter to: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetShortUrlUniques(w, r, token, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// HitShortUrlPath operation middleware
+func (siw *ServerInterfaceWrapper) HitShortUrlPath(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "token" -------------
+	var token string
+
+	err = runtime.BindStyledParameter("simple", false, "token", chi.URLParam(r, "token"), &token)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter token: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Path parameter "path" -------------
+	var path string
+
+	err = runtime.BindStyledParameter("simple", false, "path", chi.URLParam(r, "path"), &path)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter path: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.HitShortUrlPath(w, r, token, path)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// UnlockShortUrlPath operation middleware
+func (siw *ServerInterfaceWrapper) UnlockShortUrlPath(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "token" -------------
+	var token string
+
+	err = runtime.BindStyledParameter("simple", false, "token", chi.URLParam(r, "token"), &token)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter token: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Path parameter "path" -------------
+	var path string
+
+	err = runtime.BindStyledParameter("simple", false, "path", chi.URLParam(r, "path"), &path)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid format for parameter path: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{""})
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.UnlockShortUrlPath(w, r, token, path)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
 // Handler creates http.Handler with routing matching OpenAPI spec.
 func Handler(si ServerInterface) http.Handler {
 	return HandlerWithOptions(si, ChiServerOptions{})
@@ -139,12 +631,42 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/", wrapper.CreateShortUrl)
 	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/links", wrapper.ListShortUrls)
+	})
+	r.Group(func(r chi.Router) {
+		r.Delete(options.BaseURL+"/{token}", wrapper.DeleteShortUrl)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/{token}", wrapper.HitShortUrl)
 	})
+	r.Group(func(r chi.Router) {
+		r.Patch(options.BaseURL+"/{token}", wrapper.UpdateShortUrl)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/{token}", wrapper.UnlockShortUrl)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/{token}/hits", wrapper.GetShortUrlHits)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/{token}/info", wrapper.GetShortUrlInfo)
 	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/{token}/restore", wrapper.RestoreShortUrl)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/{token}/stats", wrapper.GetShortUrlStats)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/{token}/uniques", wrapper.GetShortUrlUniques)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/{token}/{path}", wrapper.HitShortUrlPath)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/{token}/{path}", wrapper.UnlockShortUrlPath)
+	})
 
 	return r
 }
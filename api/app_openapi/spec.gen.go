@@ -18,19 +18,64 @@ import (
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/7xWXU/bShD9K6u59wF0fWNDfCXkN7htaVRUqiKeUB4WexIv2LvL7Jg2ivzfq13nwwlG",
-	"CBX6RLye2Tlz5pwxS8hNbY1GzQ6yJbi8xFqGnxdK3/u/loxFYoXhNCeUjMUp+4eZoVoyZFBIxn9Z1QgR",
-	"8MIiZOCYlJ5DG0GBFb4yBX9aRa9LKVXXwSZaaR4fbyOVZpwj+VCWNEe+pmonvqFq6F4296hfDmwjIHxo",
-	"PGrIblZZ/VJRj7oV2unmFnN7hzn7ct/xoUHHV6WhNcLdAayomegPcvEODe/3sckcBuus0Q6fR+t6b15i",
-	"eh070TPzaqCbQk9x+lC1urNAl5OyrIyGDK6pEgE7aiRfQnGF++fi9NsEInhEcl3W0SgZJR6wsailVZDB",
-	"eJSMxhCBlVyGvuNAhXFBwJ4Q6UtOCsjg/yCDDWVdG+j4zBSL4DCjGXVIlNZWKg+p8Z0zeutQ/+tvwhlk",
-	"8Fe8tXC88m+8L6N2ly+mBsNBN78A+ThJ3rD8njBC/V3yL794Do+Toz9atWO/8KXTruHd92eyECvyfMx/",
-	"QzETzUhaVuIK6RFJfCQyFBTpmrqWtIBs7WMRdCkaqsRB2AmHYmZIdLYKxz8UlyKYOrQsvG3pUVbiQGlR",
-	"yIU7FA6Z15qPl+Ga1qOa44C8PivuactKkjUykoPsZr+PUu3gIywUYc6CTR/gP0LpnLBGzcKvrUPwdoIs",
-	"iB0i0LL2nllvvF2RRb3R7Rt4uifAcTJ+yvUVorjksttjaZI+jfhqWHwyjS5+c16Dzd8uRNdYn/t4vU0G",
-	"B3COmwGETfbCELYDUF30u3D7luYO/xg8a+h3nNE59vUa6AowXEgZIvfC5LISjM77R3RxEIUPSgYls83i",
-	"uPIxpXGcLa0hbmO/7CUpeVt13PnTDvBMNpX/IJ2kqd/2qJvaf3tWjydpmsK0bdtp+ysAAP//Vn7P81cJ",
-	"AAA=",
+	"H4sIAAAAAAAC/+xcW3PcNrL+K108pyozdTiakUan7OjUebCdm2qdjePLvth6wJA9Q0QkwACgpIlL/32r",
+	"AZDDC+Yix5K9iWqr1hoSlwbQ/XX312A+RoksSilQGB2dfYx0kmHB7J8/cUP/lEqWqAxH+zDJOQpzXtLf",
+	"Zl1idBZpo7hYRbdxpHCJCtWWd79XqM15Gn5b5UgvUtSJ4qXhUkRn0fFkwTSmwEWKNyCXYJhaoeFiBdSB",
+	"nuhMKgOVysFkCBk3wDUoTLnCxGAKi3UMbKFRGJj47rZ1u2sUR0upCmais4gLMz+J4lpCLgyuUJGIhhdW",
+	"xKZpygxO7NN4uKBKo3q2QmECy/W7wRWm0dl7N/BFM4Zc/IaJoTFecnE5PAGWGH6FPyhZHC4Ny7k708Gb",
+	"RCEzmD4zhw+WYo537II3JVd365Jxozutt59MwW5+Orx1ybS+lip9paSxOtLal4WUOTLhmpnsFdPaZEpW",
+	"qyzc6PcK1brX6r8VLqOz6L+mG8Oaequa/tpvb1XBKetbO/ru/q/bbb3Z6L2dbCPSYKv+71Q+tDQsSrOG",
+	"pVRQbw+U9f60bKwSOWoN3HyjwRu0tTHgRoO8FqhAKmBpwUXbqJyNDY7YyEsUnVPb0rAS/PcK/8U1N1Lp",
+	"gPTa8ILUGERVLEiIJbg+cOU7wWixBgdewEtgIgUyUWBko+PDEKAyxb69fmeKoX3bZba3v212XtO32f8r",
+	"tsIhBuRcXNo/uMFirwLQONFtMwFTiq3pt8Ab86JSWqrhlib2ucXcDIFaQslW2ICpFPZFzrR7MTy23i44",
+	"kUPL/DVgRF1hrJlByRQrNInkVQ+YQquvmIKRLXSP67bX3GRwiWu9cR70vn7NFJ59EJeIJUwgVbIsMYVR",
+	"sB1cYmnGMcgrVIqnCBNQWOYsIWc06BEDK0sUKUyApWlfPN/o/z6IFJesysk1uSVyLb4xfkkfSGdQVAXt",
+	"HskYxVE9exRHboLoYrDvcfS6hyjdzcyMKUEbZiq/l65xfwc3oilZGVRHdcMJzUc9NaorVBsh57PjeD47",
+	"ieezeTyfPYnns6cXB9nVa3ecbwhmPDrtcnnd5ZD/aCFUwmgHFwhLmefymtAJl1IhjIyqRGJBwkjQmEiR",
+	"6nF7nW4W0FwkCNZAaYb4ri62b0fayAIsBsDoiglu1mDbjiGRQnNtwxm5dE34H4RJeZmxBRrQ62Ihc73D",
+	"o56L79j6E/xkV8gNatLqdHc7O5s5ksJtAZA9w//DcWcLK5HzghtMQ4BacMEL0pTjXW55KF39Bmo4oQN0",
+	"ErUkHUmROz+UMZ0B185iI7vslyhWJovOnpxYMeqfx/2NjaNrxQ3+IvJ1dGZUheFAoCvfNc/ThKm2n5wA",
+	"9fJCWoSw58+1x4UAIpgMRhoRph9t29vpR3p2O47iv1bcsdfXf6KTbeYIuRiPMO9ELpPL7TjT1sHd3qxp",
+	"uWu2kgBj+2yfEBQPrL6riSw3qASzMGYkNBPAxP1tEQ24gJStNSyVLEDI68OCn3s78tvgDupSCr1j83Tr",
+	"zT6Vqtuei6U8SJ72STcTBU86mLba3LRgJsmwCZOBL4HlNu/kRhP0p5yaaxgxAzkybUAKdNmrm31s444C",
+	"TRT3k3CeBkI2geZaqkuaYhPojvBodQTHsyP7v+nTcWiDciZWlQ80u2PWb8CwlR8rRZAKSjN5/nrsVmkB",
+	"LkMobf5PAE0rkUt4liRYmslLP0hsR6GYTFYGFK5IG+t9YmLtH4UkLHNm6NgC3sG/gRRNnfLDO41q4tLv",
+	"TQzFpY7iiIlUSZ4S1HORymttPURi3+VcVDfBkOpu+nwHYKqtqruoLs+hIckwubRECEiVogIpmsAt7tEa",
+	"dBJLrrTZHI5XrNqLR/FheYPVbhc3nLv2J7NhFvHGMKOfV8klBuiiO2Tx2jB1MBT2jdT23ZFKvRtkkF05",
+	"l3eiU4w8vO0wd927F73FWdnspIPRgks1ASupTNEkM4GEpKVOMGrlNRmS7vSyp3H8QRRca69ZLj0yjAIc",
+	"61JoLo0JzbxJEwjxlnxVMwituTOmaaLC5js9nGNFyfhKhJkrKUyYX4ujAlNeFcFXWlYqweArg6oIu/7e",
+	"JtMwmFSKm/UbshUn7QKZQvWsMoEYkZWc8lDgWlcOoj5EOqPl2+zU0QEfIhjV9ImLGAmtrTXa+M+Ov9EX",
+	"SuSiWxKGe8fWnfOdysE6UBSuGzc59p/Ds1fnURxdodKecyU/QbshSxSs5NFZND+aHc0jFwnblU7p/0qp",
+	"7ebTgdnAgqjd6IVdSeO3G973uUzX9kg3p8bKMueJ7Tr9TUuxoZ/3h6PdhPG2azI+cFc+iLAin8xmn3H6",
+	"XnRi5+9u/i//gNEmH/CwrFmBbdXHG66NHtNun8yOH1Q+d06pVThP6MK0jhhhr+Q2TBF4jamV/tTtbneK",
+	"5ywFf1KuzXFARwWrTCYV/4Nk2VgJcHHFcp6CVJt4yI3y7XCUF1Isc07YZTNr6s9yhSxde1ySqpZae8VP",
+	"HTfEhDQZblhPO8f/hlZzLmxsncMbh2ffKyUdVuuqKJhaR2d14N/OSl3iR1btcn6iWFv7aIXoxOYG1RXL",
+	"YeSj9DFoNKbGoWlD/K0wYHwv+cYotDVYxQo0qHR09n43w0ccHow2jGDzXOEVl5W2DQiOeEPIRXEkWIHN",
+	"UFHc0s8BhvanL9hNi62166pJRc8nhmay9EJnIk8+uNhkyDmwG885zGa7GYihhJoOcckxT7cIQw3CskQ8",
+	"bQee6W7Gd/smWRFsxLdFhPpdSAamk5YQ7hcNf9DMS065JDmqlnYSh0bMp6VaPFbEsGS5bp4Kaeo327TF",
+	"vw6pS0Nx7BLIwtVAHA9iAXH8m23i+Ne7xbm4R3fS0PxBN/Kw8PrJ0PeSt3HPwQUdVukWFkc1r+WGpy0f",
+	"4td39nkreNgJYK3Sr6+wcEcXmmxzuvWrbnSwC6mGR3063JJ/Snjhzh5GjX7d7RS6kV4n8XcjzYcj/SDV",
+	"gqcpUcmb1S8wl2KlwcjGndlSnB8mKL2BH2Ql0k85ch/42gNph7zvL24v2hrhDrPtC7mp+WSF2kjlnTAX",
+	"oJC2kmy6RMVlSi6hjkqsa7Br8T6vK2mTt/iSRqv8/7YuVDQiSLWnmgGj+WzuQMbCKOU63bCHDNgJZn13",
+	"K7HqzERxlR/SD6VpLFfmaZWwuA5WsIAliVSpo46hT/d2pupK2CEAdl2XaMiBliQNzc+FNsjS3uYJtDla",
+	"12Z/4uZQg814JzQKV57gf4CLRGFBtkWOcnx/ht3HcIM3ZpqZIu+Cd3+gYLDfVCksGSWXOwvpVp3nIaz4",
+	"WV5hCq9QFYy8RL6GUUed57Nj3/kkBA+VSAcdTnyHAJ68QYRfLGL0O819pyfDTm+xKKViag2vW5xBp/cT",
+	"3/vpsHeztO29n7aR6zMcTwN31ioTWZDaaymF81K9W0TMmoKQpi4IrtGyt/bSg2MxqgakjwPY+aMU2MZn",
+	"IjgWiGJjXhT7kmq7ctufzTmCVrRYe6/oKlhJNvS2vfrEF/S298YQ9Jb4wDyBu3fxQEHd1xJO7EnQ2yo6",
+	"nKgLkPcVmTit6Nx2aCU5TBAT0qTpGxK+jRPWrIIEWK/G+JVb1c3k+vp6Qi5rUqkcRSJTdyHubmbWXfMB",
+	"ZrbfG62YSi1V3LqjYv0DIbORoFBXi4KbjZ/tAOBdaanP4Ga6dtmNBxzPQ6pK66mzrw7r9Pfyd6cn336m",
+	"1b6VEn6m6qE/VA2jJeM5brYXmDFYlKYXouNNgmhrIXSRjRfc3Isn3hMKNp7aAk/dspMyT+sqWpD0+xGb",
+	"ANze7vkyiDPgaxQTKwRbmCMyM8krza+wc1togSsuRH39ydXPQgyNL4BtJj+sQhiWCMks8CYkj7sLEZLA",
+	"yM8wf5fxzNxZ3ZHktDTmTpZzH835Z5msgyrH9OnA4MLpl2e3vkpOhVofh8c7J6qBEmFanwVQprwDVIwu",
+	"BIx7EPQj2pwZ8IqOowt3XMB7sqQYjBxbcwNrEV2oqUuJ+6DG3qU5GGq4a/1Aafz9Rey7zh1G+70qUSyV",
+	"2HJj3jLWf84F0fn3dr1zup53216+fe0a/KdRsH5d6fivmTS1jlR3Sxq7Y7JdtGo7Dio/mf8/NOXyehWY",
+	"fXQHCritytqww4Iiez/pK4yK2pHHySlksqKvVOgfWCkmqpzRzo5JR+czd2NzlLJ196W/3N6ENg8UQT1g",
+	"1ETtYGGvl4Hmf2yLEVu7sqUcSjvbqof6nylbh+qhDxIltW/OPUZLXyxa6sVJiazsEFxYm8zXMIWU8XwN",
+	"LVXUXTBy1/IOgqN3vunXDUg14vzl8OU+o8feJdNHE75/E/7ef/k5+Nyzn/ps8h0YKZLaVVztFauuLbvv",
+	"blqm3P8ayWT7PuTpTG6pv95HRDCyW3zNNTqejZZoUwMqEnMDBVvXn4fZ0fAKFctB46qwmV19jbYp9Uqx",
+	"+eSgvtJmsn5RmjKC2GJeDEdHR+Pd9dxXDmy+Cpyy+7pjz8MT+1+PNeLHGvFjjTjETAe+XexdcLEmdGC1",
+	"6+8KGI+VtsdK22Ol7SuttB0GcVvLcD2W6zYOEl1W1hDcvZQJy8Gg+8y/+U8mVCr3X/CcTac5tcmkNmcf",
+	"S6nM7ZQ+yGGKs4X/OJCedsmMp6en8xaZ4X8+PT09jS5ub28vbv89AOFRn+nYSQAA",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
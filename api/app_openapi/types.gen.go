@@ -7,20 +7,158 @@ import (
 	"time"
 )
 
+const (
+	BearerAuthScopes = "bearerAuth.Scopes"
+)
+
+// Defines values for QueryPassthrough.
+const (
+	QueryPassthroughAppend QueryPassthrough = "append"
+
+	QueryPassthroughKeep QueryPassthrough = "keep"
+
+	QueryPassthroughOverride QueryPassthrough = "override"
+)
+
+// Defines values for RedirectType.
+const (
+	RedirectTypeN301 RedirectType = 301
+
+	RedirectTypeN302 RedirectType = 302
+
+	RedirectTypeN303 RedirectType = 303
+
+	RedirectTypeN307 RedirectType = 307
+
+	RedirectTypeN308 RedirectType = 308
+)
+
+// Defines values for RulePlatform.
+const (
+	RulePlatformAndroid RulePlatform = "android"
+
+	RulePlatformIos RulePlatform = "ios"
+
+	RulePlatformLinux RulePlatform = "linux"
+
+	RulePlatformMacos RulePlatform = "macos"
+
+	RulePlatformWindows RulePlatform = "windows"
+)
+
+// Hit defines model for Hit.
+type Hit struct {
+	ClientIp  *string `json:"clientIp,omitempty"`
+	Referer   *string `json:"referer,omitempty"`
+	RequestId *string `json:"requestId,omitempty"`
+
+	// 1-based index of targeting rule of short url the hit is redirected by, absent - target url of short url
+	Rule      *int32    `json:"rule,omitempty"`
+	Time      time.Time `json:"time"`
+	UserAgent *string   `json:"userAgent,omitempty"`
+}
+
 // Link defines model for Link.
 type Link struct {
-	CreatedAt time.Time  `json:"createdAt"`
-	DeletedAt *time.Time `json:"deletedAt,omitempty"`
-	ExpiredAt *time.Time `json:"expiredAt,omitempty"`
-	Hits      int32      `json:"hits"`
-	TargetUrl string     `json:"targetUrl"`
-	Token     string     `json:"token"`
+	ActiveFrom        *time.Time `json:"activeFrom,omitempty"`
+	Alias             *string    `json:"alias,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	DeletedAt         *time.Time `json:"deletedAt,omitempty"`
+	ExpiredAt         *time.Time `json:"expiredAt,omitempty"`
+	Hits              int32      `json:"hits"`
+	MaxHits           *int32     `json:"maxHits,omitempty"`
+	PasswordProtected *bool      `json:"passwordProtected,omitempty"`
+	PathPassthrough   *bool      `json:"pathPassthrough,omitempty"`
+
+	// query params of request are passed to target url, params with keys of target url params are:
+	// keep - dropped (target url params are kept), override - replacing target url params, append - added to target url params;
+	// default - query isn't passed
+	QueryPassthrough *QueryPassthrough `json:"queryPassthrough,omitempty"`
+
+	// http status of redirect to target url, default - router.redirect-type of server
+	RedirectType *RedirectType `json:"redirectType,omitempty"`
+
+	// targeting rules checked in order on redirect, target url of the first matching one is followed
+	Rules *Rules `json:"rules,omitempty"`
+
+	// empty for password protected short url unless it's requested by its owner or admin
+	TargetUrl string `json:"targetUrl"`
+	Token     string `json:"token"`
+
+	// estimated number of unique visitors (by client ip and user agent)
+	UniqueVisitors *int32 `json:"uniqueVisitors,omitempty"`
+
+	// utm params added to target url on redirect (replacing the ones of target url),
+	// missing ones are taken from utm section of server config unless target url has them
+	Utm *Utm `json:"utm,omitempty"`
 }
 
+// LinkPage defines model for LinkPage.
+type LinkPage struct {
+	Links []Link `json:"links"`
+
+	// cursor of the next page, absent on the last page
+	NextCursor *string `json:"nextCursor,omitempty"`
+}
+
+// query params of request are passed to target url, params with keys of target url params are:
+// keep - dropped (target url params are kept), override - replacing target url params, append - added to target url params;
+// default - query isn't passed
+type QueryPassthrough string
+
+// http status of redirect to target url, default - router.redirect-type of server
+type RedirectType int32
+
 // RequestShortUrl defines model for RequestShortUrl.
 type RequestShortUrl struct {
+	// time short url can't be followed before (truncated to seconds), default - active since creation
+	ActiveFrom *time.Time `json:"activeFrom,omitempty"`
+
+	// custom token (vanity alias) consisting of tokenizer alphabet symbols
+	Alias         *string `json:"alias,omitempty"`
+	ExpiredInDays *int32  `json:"expiredInDays,omitempty"`
+
+	// number of times short url can be followed (one-time link = 1), default - unlimited
+	MaxHits *int32 `json:"maxHits,omitempty"`
+
+	// password required to follow short url (only its hash is kept)
+	Password *string `json:"password,omitempty"`
+
+	// wildcard short url - path following token is appended to target url path (see /{token}/{path})
+	PathPassthrough *bool `json:"pathPassthrough,omitempty"`
+
+	// query params of request are passed to target url, params with keys of target url params are:
+	// keep - dropped (target url params are kept), override - replacing target url params, append - added to target url params;
+	// default - query isn't passed
+	QueryPassthrough *QueryPassthrough `json:"queryPassthrough,omitempty"`
+
+	// http status of redirect to target url, default - router.redirect-type of server
+	RedirectType *RedirectType `json:"redirectType,omitempty"`
+
+	// targeting rules checked in order on redirect, target url of the first matching one is followed
+	Rules     *Rules `json:"rules,omitempty"`
+	TargetUrl string `json:"targetUrl"`
+
+	// utm params added to target url on redirect (replacing the ones of target url),
+	// missing ones are taken from utm section of server config unless target url has them
+	Utm *Utm `json:"utm,omitempty"`
+}
+
+// RequestUnlockShortUrl defines model for RequestUnlockShortUrl.
+type RequestUnlockShortUrl struct {
+	Password string `json:"password"`
+}
+
+// RequestUpdateShortUrl defines model for RequestUpdateShortUrl.
+type RequestUpdateShortUrl struct {
+	ExpiredAt *time.Time `json:"expiredAt,omitempty"`
+
+	// alternative to expiredAt - expiration in days from now
 	ExpiredInDays *int32 `json:"expiredInDays,omitempty"`
-	TargetUrl     string `json:"targetUrl"`
+
+	// targeting rules checked in order on redirect, target url of the first matching one is followed
+	Rules     *Rules  `json:"rules,omitempty"`
+	TargetUrl *string `json:"targetUrl,omitempty"`
 }
 
 // ResponseShortUrl defines model for ResponseShortUrl.
@@ -29,8 +167,119 @@ type ResponseShortUrl struct {
 	ShortUrlInfo *string `json:"shortUrlInfo,omitempty"`
 }
 
+// rule matches request if all of its conditions (at least one is required) are met
+type Rule struct {
+	// network of client ip (e.g. 10.0.0.0/8)
+	Cidr *string `json:"cidr,omitempty"`
+
+	// language tag (e.g. de or pt-BR) matching the preferred one of Accept-Language, tag without region matches any region
+	Language *string `json:"language,omitempty"`
+
+	// platform detected by User-Agent
+	Platform  *RulePlatform `json:"platform,omitempty"`
+	TargetUrl string        `json:"targetUrl"`
+}
+
+// platform detected by User-Agent
+type RulePlatform string
+
+// targeting rules checked in order on redirect, target url of the first matching one is followed
+type Rules []Rule
+
+// StatsBucket defines model for StatsBucket.
+type StatsBucket struct {
+	Hits  int32     `json:"hits"`
+	Start time.Time `json:"start"`
+}
+
+// UniqueVisitors defines model for UniqueVisitors.
+type UniqueVisitors struct {
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	UniqueVisitors int32     `json:"uniqueVisitors"`
+}
+
+// utm params added to target url on redirect (replacing the ones of target url),
+// missing ones are taken from utm section of server config unless target url has them
+type Utm struct {
+	Campaign *string `json:"campaign,omitempty"`
+	Content  *string `json:"content,omitempty"`
+	Medium   *string `json:"medium,omitempty"`
+	Source   *string `json:"source,omitempty"`
+	Term     *string `json:"term,omitempty"`
+}
+
 // CreateShortUrlJSONBody defines parameters for CreateShortUrl.
 type CreateShortUrlJSONBody RequestShortUrl
 
+// ListShortUrlsParams defines parameters for ListShortUrls.
+type ListShortUrlsParams struct {
+	// cursor of the page (nextCursor of the previous page)
+	Cursor *string `json:"cursor,omitempty"`
+
+	// max number of links on the page
+	Limit *int32 `json:"limit,omitempty"`
+
+	// sort field
+	Sort *ListShortUrlsParamsSort `json:"sort,omitempty"`
+
+	// sort order
+	Order *ListShortUrlsParamsOrder `json:"order,omitempty"`
+
+	// filter by expiration (true - only expired, false - only not expired)
+	Expired *bool `json:"expired,omitempty"`
+
+	// filter by deletion (true - only deleted, false - only not deleted)
+	Deleted *bool `json:"deleted,omitempty"`
+}
+
+// ListShortUrlsParamsSort defines parameters for ListShortUrls.
+type ListShortUrlsParamsSort string
+
+// ListShortUrlsParamsOrder defines parameters for ListShortUrls.
+type ListShortUrlsParamsOrder string
+
+// UpdateShortUrlJSONBody defines parameters for UpdateShortUrl.
+type UpdateShortUrlJSONBody RequestUpdateShortUrl
+
+// GetShortUrlHitsParams defines parameters for GetShortUrlHits.
+type GetShortUrlHitsParams struct {
+	// range start (inclusive), default - beginning of time
+	From *time.Time `json:"from,omitempty"`
+
+	// range end (exclusive), default - now
+	To *time.Time `json:"to,omitempty"`
+
+	// max number of hits
+	Limit *int32 `json:"limit,omitempty"`
+}
+
+// GetShortUrlStatsParams defines parameters for GetShortUrlStats.
+type GetShortUrlStatsParams struct {
+	// range start, default - 24 hours (hour granularity) or 30 days (day granularity) before range end
+	From *time.Time `json:"from,omitempty"`
+
+	// range end, default - now
+	To *time.Time `json:"to,omitempty"`
+
+	// time bucket size
+	Granularity *GetShortUrlStatsParamsGranularity `json:"granularity,omitempty"`
+}
+
+// GetShortUrlStatsParamsGranularity defines parameters for GetShortUrlStats.
+type GetShortUrlStatsParamsGranularity string
+
+// GetShortUrlUniquesParams defines parameters for GetShortUrlUniques.
+type GetShortUrlUniquesParams struct {
+	// range start, default - 30 days before range end
+	From *time.Time `json:"from,omitempty"`
+
+	// range end, default - now
+	To *time.Time `json:"to,omitempty"`
+}
+
 // CreateShortUrlJSONRequestBody defines body for CreateShortUrl for application/json ContentType.
 type CreateShortUrlJSONRequestBody CreateShortUrlJSONBody
+
+// UpdateShortUrlJSONRequestBody defines body for UpdateShortUrl for application/json ContentType.
+type UpdateShortUrlJSONRequestBody UpdateShortUrlJSONBody
@@ -0,0 +1,67 @@
+package router
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nj-eka/shurl/app"
+	cu "github.com/nj-eka/shurl/internal/contexts"
+	"github.com/nj-eka/shurl/internal/errs"
+	"github.com/nj-eka/shurl/internal/logging"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// adminOnly passes requests authorized with admin bearer token, admin endpoints are hidden if there is no token configured
+func (art *AppRouter) adminOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if art.cfg.AdminToken == "" {
+			http.NotFound(w, r)
+			return
+		}
+		header := r.Header.Get("Authorization")
+		if !strings.HasPrefix(header, "Bearer ") || subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(header, "Bearer ")), []byte(art.cfg.AdminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (art *AppRouter) GetBackup(w http.ResponseWriter, r *http.Request) {
+	ctx := cu.BuildContext(r.Context(), cu.AddContextOperation("get_backup"), errs.SetDefaultErrsKind(errs.KindRouter))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="shurl-%s.db"`, time.Now().UTC().Format("20060102T150405Z")))
+	n, err := art.a.Backup(ctx, w)
+	if err != nil {
+		if errors.Is(err, app.ErrNotSupported) {
+			w.Header().Del("Content-Disposition")
+			http.Error(w, "", http.StatusNotImplemented)
+			return
+		}
+		logging.LogError(ctx, err)
+		if n == 0 {
+			http.Error(w, "", http.StatusInternalServerError)
+			return
+		}
+		// snapshot is partially sent, so client is to get broken response rather than truncated file
+		panic(http.ErrAbortHandler)
+	}
+	logging.Msg(ctx).Infof("backup of %d bytes is sent", n)
+}
+
+func (art *AppRouter) GetReaperStats(w http.ResponseWriter, r *http.Request) {
+	ctx := cu.BuildContext(r.Context(), cu.AddContextOperation("get_reaper_stats"), errs.SetDefaultErrsKind(errs.KindRouter))
+	stats, ok := art.a.ReaperStats()
+	if !ok {
+		http.Error(w, "", http.StatusNotImplemented)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		logging.LogError(ctx, fmt.Errorf("encoding [%v] to json failed: %w", stats, err))
+	}
+}
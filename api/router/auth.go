@@ -0,0 +1,51 @@
+package router
+
+import (
+	"crypto/subtle"
+	"errors"
+	"github.com/nj-eka/shurl/app"
+	cu "github.com/nj-eka/shurl/internal/contexts"
+	"github.com/nj-eka/shurl/internal/errs"
+	"github.com/nj-eka/shurl/internal/logging"
+	"net/http"
+	"strings"
+)
+
+// authenticate resolves caller of request by bearer token: admin token or api key,
+// requests without token or with invalid one are passed as anonymous ones (app or handler decides whether they are allowed,
+// so invalid credentials are rejected only where principal is required)
+func (art *AppRouter) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if !strings.HasPrefix(header, "Bearer ") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token := strings.TrimPrefix(header, "Bearer ")
+		if art.cfg.AdminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(art.cfg.AdminToken)) == 1 {
+			next.ServeHTTP(w, r.WithContext(app.WithPrincipal(r.Context(), &app.Principal{Admin: true})))
+			return
+		}
+		ctx := cu.BuildContext(r.Context(), cu.AddContextOperation("authenticate"), errs.SetDefaultErrsKind(errs.KindRouter))
+		p, err := art.a.Authenticate(ctx, token)
+		if err != nil {
+			switch {
+			case errors.Is(err, app.ErrNotSupported):
+				next.ServeHTTP(w, r)
+			case errors.Is(err, app.ErrUnauthorized):
+				logging.LogError(ctx, err)
+				next.ServeHTTP(w, r)
+			default:
+				logging.LogError(ctx, err)
+				http.Error(w, "", http.StatusInternalServerError)
+			}
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(app.WithPrincipal(r.Context(), p)))
+	})
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	http.Error(w, "", http.StatusUnauthorized)
+}
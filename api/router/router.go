@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"github.com/go-chi/chi"
 	chi_middleware "github.com/go-chi/chi/middleware"
+	chi_v5 "github.com/go-chi/chi/v5"
 	api "github.com/nj-eka/shurl/api/app_openapi"
 	"github.com/nj-eka/shurl/app"
 	"github.com/nj-eka/shurl/config"
@@ -20,7 +21,9 @@ import (
 	"github.com/nj-eka/shurl/internal/logging"
 	"github.com/sirupsen/logrus"
 	"html/template"
+	"net"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"time"
 )
@@ -36,6 +39,9 @@ type AppRouter struct {
 func NewAppRouter(ctx context.Context, a *app.App, cfg *config.RouterConfig) (*AppRouter, error) {
 	ctx = cu.BuildContext(ctx, cu.AddContextOperation("router.init"), errs.SetDefaultErrsKind(errs.KindRouter), errs.SetDefaultErrsSeverity(errs.SeverityCritical))
 	logging.Msg(ctx).Debugf("router config: %v", cfg)
+	if cfg.RedirectType != 0 && !app.IsRedirectType(cfg.RedirectType) {
+		return nil, errs.E(ctx, errs.KindInvalidValue, fmt.Errorf("redirect type [%d]: %w", cfg.RedirectType, app.ErrInvalidRedirect))
+	}
 	art := &AppRouter{a: a, cfg: cfg}
 	r := chi.NewRouter()
 
@@ -44,6 +50,7 @@ func NewAppRouter(ctx context.Context, a *app.App, cfg *config.RouterConfig) (*A
 	r.Use(chi_middleware.RealIP)
 	r.Use(NewStructuredLogger(logrus.StandardLogger()))
 	r.Use(chi_middleware.Recoverer)
+	r.Use(art.authenticate)
 	//r.Use(chi_middleware.URLFormat)
 	swagger, err := api.GetSwagger()
 	if err != nil {
@@ -77,9 +84,20 @@ func NewAppRouter(ctx context.Context, a *app.App, cfg *config.RouterConfig) (*A
 		http.StripPrefix("/static", fileServer).ServeHTTP(w, r)
 	})
 
+	// admin endpoints (not part of public api)
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(art.adminOnly)
+		r.Get("/backup", art.GetBackup)
+		r.Get("/reaper", art.GetReaperStats)
+	})
+
 	//register AppRouter as handler for api.ServerInterface
 	//api.HandlerFromMux(art, r)
-	r.Mount("/", api.Handler(art))
+	apiRouter := chi_v5.NewRouter()
+	// path of wildcard link may consist of several segments that {path} parameter of api spec doesn't match
+	apiRouter.Get("/{token}/*", art.wildcard(art.HitShortUrlPath))
+	apiRouter.Post("/{token}/*", art.wildcard(art.UnlockShortUrlPath))
+	r.Mount("/", api.HandlerFromMux(art, apiRouter))
 
 	art.Handler = r
 	logging.Msg(ctx).Debug("router init - ok")
@@ -132,9 +150,50 @@ func (art *AppRouter) CreateShortUrl(w http.ResponseWriter, r *http.Request) {
 		t := time.Now().UTC().AddDate(0, 0, int(*requestShurl.ExpiredInDays))
 		expiredAt = &t
 	}
-	token, added, err := art.a.CreateToken(ctx, requestShurl.TargetUrl, expiredAt)
+	link := &app.Link{
+		TargetUrl:  requestShurl.TargetUrl,
+		ExpiredAt:  expiredAt,
+		ActiveFrom: requestShurl.ActiveFrom,
+	}
+	if requestShurl.RedirectType != nil {
+		link.RedirectType = int(*requestShurl.RedirectType)
+	}
+	if requestShurl.QueryPassthrough != nil {
+		link.QueryPassthrough = app.QueryPassthrough(*requestShurl.QueryPassthrough)
+	}
+	if requestShurl.PathPassthrough != nil {
+		link.PathPassthrough = *requestShurl.PathPassthrough
+	}
+	if u := requestShurl.Utm; u != nil {
+		link.Utm = &app.Utm{Source: deref(u.Source), Medium: deref(u.Medium), Campaign: deref(u.Campaign), Term: deref(u.Term), Content: deref(u.Content)}
+	}
+	if requestShurl.Rules != nil {
+		link.Rules = fromApiRules(*requestShurl.Rules)
+	}
+	if requestShurl.Alias != nil {
+		link.Alias = *requestShurl.Alias
+	}
+	if requestShurl.Password != nil {
+		link.Password = *requestShurl.Password
+	}
+	if requestShurl.MaxHits != nil {
+		if *requestShurl.MaxHits < 1 {
+			http.Error(w, "invalid max hits", http.StatusBadRequest)
+			return
+		}
+		link.MaxHits = int(*requestShurl.MaxHits)
+	}
+	token, added, err := art.a.CreateToken(ctx, link)
 	if err != nil {
 		logging.LogError(ctx, err)
+		if errors.Is(err, app.ErrAliasExists) || errors.Is(err, app.ErrConflict) {
+			http.Error(w, "", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, app.ErrUnauthorized) {
+			unauthorized(w)
+			return
+		}
 		http.Error(w, "", http.StatusBadRequest)
 		return
 	}
@@ -154,20 +213,156 @@ func (art *AppRouter) CreateShortUrl(w http.ResponseWriter, r *http.Request) {
 }
 
 func (art *AppRouter) HitShortUrl(w http.ResponseWriter, r *http.Request, token string) {
+	art.hitShortUrl(w, r, token, "")
+}
+
+func (art *AppRouter) HitShortUrlPath(w http.ResponseWriter, r *http.Request, token string, path string) {
+	art.hitShortUrl(w, r, token, path)
+}
+
+// wildcard adapts handler of /{token}/{path} to /{token}/* route
+func (art *AppRouter) wildcard(handler func(w http.ResponseWriter, r *http.Request, token string, path string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path, err := url.PathUnescape(chi_v5.URLParam(r, "*"))
+		if err != nil {
+			http.Error(w, "", http.StatusBadRequest)
+			return
+		}
+		handler(w, r, chi_v5.URLParam(r, "token"), path)
+	}
+}
+
+func (art *AppRouter) hitShortUrl(w http.ResponseWriter, r *http.Request, token string, path string) {
 	ctx := cu.BuildContext(r.Context(), cu.AddContextOperation("hit_shurl"), errs.SetDefaultErrsKind(errs.KindRouter))
-	link, err := art.a.HitLink(ctx, token)
+	hit := &app.Hit{
+		Referer:        r.Referer(),
+		UserAgent:      r.UserAgent(),
+		ClientIP:       clientIP(r),
+		AcceptLanguage: r.Header.Get("Accept-Language"),
+		Path:           path,
+		Query:          r.URL.Query(),
+	}
+	link, err := art.a.HitLink(ctx, token, hit)
 	if err != nil {
 		if errors.Is(err, app.ErrNotFound) {
 			http.Error(w, "", http.StatusNotFound)
 			return
 		}
+		if errors.Is(err, app.ErrNotActive) {
+			art.renderComingSoon(ctx, w, token)
+			return
+		}
+		if errors.Is(err, app.ErrHitsExhausted) {
+			http.Error(w, "", http.StatusGone)
+			return
+		}
+		if errors.Is(err, app.ErrPasswordRequired) {
+			art.renderPasswordForm(ctx, w, http.StatusOK, r.URL.RequestURI(), "")
+			return
+		}
 		logging.LogError(ctx, err)
 		http.Error(w, "", http.StatusInternalServerError)
 		return
 	}
+	http.Redirect(w, r, link.TargetUrl, art.redirectType(link))
+}
+
+// redirectType returns http status of redirect to target url of link: its own one or default of router
+func (art *AppRouter) redirectType(link *app.Link) int {
+	switch {
+	case link.RedirectType != 0:
+		return link.RedirectType
+	case art.cfg.RedirectType != 0:
+		return art.cfg.RedirectType
+	}
+	return http.StatusSeeOther
+}
+
+// UnlockShortUrl is submit of password form of password protected link,
+// it always redirects with 303 See Other not to resubmit password form to target url (as 307 / 308 would)
+func (art *AppRouter) UnlockShortUrl(w http.ResponseWriter, r *http.Request, token string) {
+	art.unlockShortUrl(w, r, token, "")
+}
+
+func (art *AppRouter) UnlockShortUrlPath(w http.ResponseWriter, r *http.Request, token string, path string) {
+	art.unlockShortUrl(w, r, token, path)
+}
+
+func (art *AppRouter) unlockShortUrl(w http.ResponseWriter, r *http.Request, token string, path string) {
+	ctx := cu.BuildContext(r.Context(), cu.AddContextOperation("unlock_shurl"), errs.SetDefaultErrsKind(errs.KindRouter))
+	r.Body = http.MaxBytesReader(w, r.Body, 4096)
+	if err := r.ParseForm(); err != nil {
+		logging.LogError(ctx, fmt.Errorf("invalid request format: %w", err))
+		http.Error(w, "", http.StatusBadRequest)
+		return
+	}
+	hit := &app.Hit{
+		Referer:        r.Referer(),
+		UserAgent:      r.UserAgent(),
+		ClientIP:       clientIP(r),
+		AcceptLanguage: r.Header.Get("Accept-Language"),
+		Path:           path,
+		Query:          r.URL.Query(),
+	}
+	link, err := art.a.UnlockLink(ctx, token, r.PostForm.Get("password"), hit)
+	if err != nil {
+		switch {
+		case errors.Is(err, app.ErrNotFound):
+			http.Error(w, "", http.StatusNotFound)
+		case errors.Is(err, app.ErrNotActive):
+			art.renderComingSoon(ctx, w, token)
+		case errors.Is(err, app.ErrHitsExhausted):
+			http.Error(w, "", http.StatusGone)
+		case errors.Is(err, app.ErrInvalidPassword):
+			art.renderPasswordForm(ctx, w, http.StatusUnauthorized, r.URL.RequestURI(), "Invalid password")
+		case errors.Is(err, app.ErrTooManyAttempts):
+			logging.LogError(ctx, err)
+			art.renderPasswordForm(ctx, w, http.StatusTooManyRequests, r.URL.RequestURI(), "Too many attempts, try again later")
+		default:
+			logging.LogError(ctx, err)
+			http.Error(w, "", http.StatusInternalServerError)
+		}
+		return
+	}
 	http.Redirect(w, r, link.TargetUrl, http.StatusSeeOther)
 }
 
+// renderPasswordForm renders password form submitted to action (request uri of short url to keep its path and query)
+func (art *AppRouter) renderPasswordForm(ctx context.Context, w http.ResponseWriter, status int, action, message string) {
+	ts, err := template.ParseFiles(filepath.Join(art.cfg.WebPath, "templates/password.html"))
+	if err != nil {
+		logging.LogError(ctx, errs.SeverityCritical, fmt.Errorf("parsing password template failed: %w", err))
+		http.Error(w, "Internal Server Error", 500)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err = ts.Execute(w, struct{ Action, Error string }{action, message}); err != nil {
+		logging.LogError(ctx, errs.SeverityCritical, fmt.Errorf("executing password template failed: %w", err))
+	}
+}
+
+// renderComingSoon responds to link that is not active yet: it's not found unless coming soon template is configured
+func (art *AppRouter) renderComingSoon(ctx context.Context, w http.ResponseWriter, token string) {
+	if art.cfg.ComingSoonTemplate == "" {
+		http.Error(w, "", http.StatusNotFound)
+		return
+	}
+	ts, err := template.ParseFiles(filepath.Join(art.cfg.WebPath, "templates", art.cfg.ComingSoonTemplate))
+	if err != nil {
+		logging.LogError(ctx, errs.SeverityCritical, fmt.Errorf("parsing coming soon template failed: %w", err))
+		http.Error(w, "", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusNotFound)
+	if err = ts.Execute(w, struct{ Token string }{token}); err != nil {
+		logging.LogError(ctx, errs.SeverityCritical, fmt.Errorf("executing coming soon template failed: %w", err))
+	}
+}
+
 func (art *AppRouter) GetShortUrlInfo(w http.ResponseWriter, r *http.Request, token string) {
 	ctx := cu.BuildContext(r.Context(), cu.AddContextOperation("hit_shurl"), errs.SetDefaultErrsKind(errs.KindRouter))
 	link, err := art.a.GetLink(ctx, token)
@@ -180,6 +375,234 @@ func (art *AppRouter) GetShortUrlInfo(w http.ResponseWriter, r *http.Request, to
 		http.Error(w, "", http.StatusInternalServerError)
 		return
 	}
+	result := toApiLink(link)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logging.LogError(ctx, fmt.Errorf("encoding [%v] to json failed: %w", result, err))
+	}
+}
+
+// UpdateShortUrl requires caller to be authenticated the same way as DeleteShortUrl
+func (art *AppRouter) UpdateShortUrl(w http.ResponseWriter, r *http.Request, token string) {
+	ctx := cu.BuildContext(r.Context(), cu.AddContextOperation("update_shurl"), errs.SetDefaultErrsKind(errs.KindRouter))
+	if app.PrincipalFrom(ctx) == nil {
+		unauthorized(w)
+		return
+	}
+	defer func() {
+		_ = r.Body.Close()
+	}()
+	var request api.RequestUpdateShortUrl
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logging.LogError(ctx, fmt.Errorf("invalid request format: %w", err))
+		http.Error(w, "", http.StatusBadRequest)
+		return
+	}
+	if request.ExpiredAt != nil && request.ExpiredInDays != nil {
+		http.Error(w, "either expiredAt or expiredInDays is expected", http.StatusBadRequest)
+		return
+	}
+	patch := &app.LinkPatch{TargetUrl: request.TargetUrl, ExpiredAt: request.ExpiredAt}
+	if request.ExpiredInDays != nil {
+		t := time.Now().UTC().AddDate(0, 0, int(*request.ExpiredInDays))
+		patch.ExpiredAt = &t
+	}
+	if request.Rules != nil {
+		rules := fromApiRules(*request.Rules)
+		patch.Rules = &rules
+	}
+	if patch.TargetUrl == nil && patch.ExpiredAt == nil && patch.Rules == nil {
+		http.Error(w, "nothing to update", http.StatusBadRequest)
+		return
+	}
+	link, err := art.a.UpdateLink(ctx, token, patch)
+	if err != nil {
+		switch {
+		case errors.Is(err, app.ErrInvalidUrl):
+			http.Error(w, "invalid url", http.StatusBadRequest)
+		case errors.Is(err, app.ErrInvalidRule):
+			http.Error(w, "invalid rule", http.StatusBadRequest)
+		case errors.Is(err, app.ErrNotFound):
+			http.Error(w, "", http.StatusNotFound)
+		case errors.Is(err, app.ErrUnauthorized):
+			unauthorized(w)
+		case errors.Is(err, app.ErrForbidden):
+			http.Error(w, "", http.StatusForbidden)
+		case errors.Is(err, app.ErrConflict):
+			http.Error(w, "url belongs to another link", http.StatusConflict)
+		default:
+			logging.LogError(ctx, err)
+			http.Error(w, "", http.StatusInternalServerError)
+		}
+		return
+	}
+	result := toApiLink(link)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logging.LogError(ctx, fmt.Errorf("encoding [%v] to json failed: %w", result, err))
+	}
+}
+
+// DeleteShortUrl requires caller to be authenticated (by api key or admin token) even if api keys are turned off
+func (art *AppRouter) DeleteShortUrl(w http.ResponseWriter, r *http.Request, token string) {
+	ctx := cu.BuildContext(r.Context(), cu.AddContextOperation("delete_shurl"), errs.SetDefaultErrsKind(errs.KindRouter))
+	if app.PrincipalFrom(ctx) == nil {
+		unauthorized(w)
+		return
+	}
+	if err := art.a.DeleteLink(ctx, token); err != nil {
+		switch {
+		case errors.Is(err, app.ErrNotFound):
+			http.Error(w, "", http.StatusNotFound)
+		case errors.Is(err, app.ErrUnauthorized):
+			unauthorized(w)
+		case errors.Is(err, app.ErrForbidden):
+			http.Error(w, "", http.StatusForbidden)
+		default:
+			logging.LogError(ctx, err)
+			http.Error(w, "", http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreShortUrl requires caller to be authenticated the same way as DeleteShortUrl
+func (art *AppRouter) RestoreShortUrl(w http.ResponseWriter, r *http.Request, token string) {
+	ctx := cu.BuildContext(r.Context(), cu.AddContextOperation("restore_shurl"), errs.SetDefaultErrsKind(errs.KindRouter))
+	if app.PrincipalFrom(ctx) == nil {
+		unauthorized(w)
+		return
+	}
+	if err := art.a.RestoreLink(ctx, token); err != nil {
+		switch {
+		case errors.Is(err, app.ErrNotFound):
+			http.Error(w, "", http.StatusNotFound)
+		case errors.Is(err, app.ErrUnauthorized):
+			unauthorized(w)
+		case errors.Is(err, app.ErrForbidden):
+			http.Error(w, "", http.StatusForbidden)
+		case errors.Is(err, app.ErrNotDeleted):
+			http.Error(w, "not deleted", http.StatusConflict)
+		case errors.Is(err, app.ErrRetentionExpired):
+			http.Error(w, "retention period expired", http.StatusGone)
+		default:
+			logging.LogError(ctx, err)
+			http.Error(w, "", http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (art *AppRouter) ListShortUrls(w http.ResponseWriter, r *http.Request, params api.ListShortUrlsParams) {
+	ctx := cu.BuildContext(r.Context(), cu.AddContextOperation("list_shurls"), errs.SetDefaultErrsKind(errs.KindRouter))
+	q := app.ListQuery{
+		SortBy:  app.SortById,
+		Expired: params.Expired,
+		Deleted: params.Deleted,
+	}
+	if params.Sort != nil {
+		switch sortBy := app.ListSort(*params.Sort); sortBy {
+		case app.SortById, app.SortByCreatedAt, app.SortByHits:
+			q.SortBy = sortBy
+		default:
+			http.Error(w, "invalid sort field", http.StatusBadRequest)
+			return
+		}
+	}
+	if params.Order != nil {
+		switch *params.Order {
+		case "asc":
+		case "desc":
+			q.Desc = true
+		default:
+			http.Error(w, "invalid sort order", http.StatusBadRequest)
+			return
+		}
+	}
+	if params.Limit != nil {
+		if *params.Limit < 1 || *params.Limit > app.MaxListLimit {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		q.Limit = int(*params.Limit)
+	}
+	if params.Cursor != nil && *params.Cursor != "" {
+		cursor, err := app.DecodeListCursor(*params.Cursor)
+		if err != nil {
+			logging.LogError(ctx, errs.SeverityWarning, err)
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+		q.After = cursor
+	}
+	links, next, err := art.a.ListLinks(ctx, q)
+	if err != nil {
+		if errors.Is(err, app.ErrUnauthorized) {
+			unauthorized(w)
+			return
+		}
+		logging.LogError(ctx, err)
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+	result := api.LinkPage{Links: make([]api.Link, 0, len(links))}
+	for _, link := range links {
+		result.Links = append(result.Links, toApiLink(link))
+	}
+	if next != nil {
+		nextCursor := next.Encode()
+		result.NextCursor = &nextCursor
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logging.LogError(ctx, fmt.Errorf("encoding [%v] to json failed: %w", result, err))
+	}
+}
+
+// deref returns value of optional string param, "" - param is missing
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// optional returns optional string param of value, nil - value is empty
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func fromApiRules(rules api.Rules) []app.Rule {
+	result := make([]app.Rule, 0, len(rules))
+	for _, rule := range rules {
+		r := app.Rule{Language: deref(rule.Language), CIDR: deref(rule.Cidr), TargetUrl: rule.TargetUrl}
+		if rule.Platform != nil {
+			r.Platform = app.Platform(*rule.Platform)
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+func toApiRules(rules []app.Rule) api.Rules {
+	result := make(api.Rules, 0, len(rules))
+	for _, rule := range rules {
+		r := api.Rule{Language: optional(rule.Language), Cidr: optional(rule.CIDR), TargetUrl: rule.TargetUrl}
+		if rule.Platform != "" {
+			platform := api.RulePlatform(rule.Platform)
+			r.Platform = &platform
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+func toApiLink(link *app.Link) api.Link {
 	result := api.Link{
 		CreatedAt: link.CreatedAt,
 		DeletedAt: link.DeletedAt,
@@ -188,8 +611,213 @@ func (art *AppRouter) GetShortUrlInfo(w http.ResponseWriter, r *http.Request, to
 		TargetUrl: link.TargetUrl,
 		Token:     link.Key,
 	}
+	if link.PasswordHash != "" {
+		protected := true
+		result.PasswordProtected = &protected
+	}
+	if link.MaxHits > 0 {
+		maxHits := int32(link.MaxHits)
+		result.MaxHits = &maxHits
+	}
+	result.ActiveFrom = link.ActiveFrom
+	if link.RedirectType != 0 {
+		redirectType := api.RedirectType(link.RedirectType)
+		result.RedirectType = &redirectType
+	}
+	if link.QueryPassthrough != "" {
+		queryPassthrough := api.QueryPassthrough(link.QueryPassthrough)
+		result.QueryPassthrough = &queryPassthrough
+	}
+	if link.PathPassthrough {
+		result.PathPassthrough = &link.PathPassthrough
+	}
+	if u := link.Utm; !u.IsZero() {
+		result.Utm = &api.Utm{Source: optional(u.Source), Medium: optional(u.Medium), Campaign: optional(u.Campaign), Term: optional(u.Term), Content: optional(u.Content)}
+	}
+	if len(link.Rules) > 0 {
+		rules := toApiRules(link.Rules)
+		result.Rules = &rules
+	}
+	if link.Alias != "" {
+		result.Alias = &link.Alias
+	}
+	if link.UniqueVisitors > 0 {
+		uniques := int32(link.UniqueVisitors)
+		result.UniqueVisitors = &uniques
+	}
+	return result
+}
+
+func (art *AppRouter) GetShortUrlHits(w http.ResponseWriter, r *http.Request, token string, params api.GetShortUrlHitsParams) {
+	ctx := cu.BuildContext(r.Context(), cu.AddContextOperation("get_shurl_hits"), errs.SetDefaultErrsKind(errs.KindRouter))
+	params.From, params.To = timeParam(params.From), timeParam(params.To)
+	var from time.Time
+	to := time.Now().UTC()
+	if params.From != nil {
+		from = *params.From
+	}
+	if params.To != nil {
+		to = *params.To
+	}
+	limit := 0
+	if params.Limit != nil {
+		if *params.Limit < 1 || *params.Limit > app.MaxHitsLimit {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = int(*params.Limit)
+	}
+	hits, err := art.a.ListHits(ctx, token, from, to, limit)
+	if err != nil {
+		if errors.Is(err, app.ErrNotFound) {
+			http.Error(w, "", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, app.ErrUnauthorized) {
+			unauthorized(w)
+			return
+		}
+		if errors.Is(err, app.ErrForbidden) {
+			http.Error(w, "", http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, app.ErrNotSupported) {
+			http.Error(w, "", http.StatusNotImplemented)
+			return
+		}
+		logging.LogError(ctx, err)
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+	result := make([]api.Hit, 0, len(hits))
+	for _, hit := range hits {
+		result = append(result, toApiHit(hit))
+	}
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(result); err != nil {
 		logging.LogError(ctx, fmt.Errorf("encoding [%v] to json failed: %w", result, err))
 	}
 }
+
+func (art *AppRouter) GetShortUrlStats(w http.ResponseWriter, r *http.Request, token string, params api.GetShortUrlStatsParams) {
+	ctx := cu.BuildContext(r.Context(), cu.AddContextOperation("get_shurl_stats"), errs.SetDefaultErrsKind(errs.KindRouter))
+	params.From, params.To = timeParam(params.From), timeParam(params.To)
+	g := app.GranularityHour
+	if params.Granularity != nil {
+		g = app.Granularity(*params.Granularity)
+	}
+	to := time.Now().UTC()
+	if params.To != nil {
+		to = *params.To
+	}
+	var from time.Time
+	switch {
+	case params.From != nil:
+		from = *params.From
+	case g == app.GranularityDay:
+		from = to.AddDate(0, 0, -30)
+	default:
+		from = to.Add(-24 * time.Hour)
+	}
+	buckets, err := art.a.Stats(ctx, token, g, from, to)
+	if err != nil {
+		switch {
+		case errors.Is(err, app.ErrInvalidRange):
+			http.Error(w, "invalid range", http.StatusBadRequest)
+		case errors.Is(err, app.ErrNotFound):
+			http.Error(w, "", http.StatusNotFound)
+		case errors.Is(err, app.ErrUnauthorized):
+			unauthorized(w)
+		case errors.Is(err, app.ErrForbidden):
+			http.Error(w, "", http.StatusForbidden)
+		case errors.Is(err, app.ErrNotSupported):
+			http.Error(w, "", http.StatusNotImplemented)
+		default:
+			logging.LogError(ctx, err)
+			http.Error(w, "", http.StatusInternalServerError)
+		}
+		return
+	}
+	result := make([]api.StatsBucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		result = append(result, api.StatsBucket{Start: bucket.Start, Hits: int32(bucket.Hits)})
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logging.LogError(ctx, fmt.Errorf("encoding [%v] to json failed: %w", result, err))
+	}
+}
+
+func (art *AppRouter) GetShortUrlUniques(w http.ResponseWriter, r *http.Request, token string, params api.GetShortUrlUniquesParams) {
+	ctx := cu.BuildContext(r.Context(), cu.AddContextOperation("get_shurl_uniques"), errs.SetDefaultErrsKind(errs.KindRouter))
+	params.From, params.To = timeParam(params.From), timeParam(params.To)
+	to := time.Now().UTC()
+	if params.To != nil {
+		to = *params.To
+	}
+	from := to.AddDate(0, 0, -30)
+	if params.From != nil {
+		from = *params.From
+	}
+	uniques, err := art.a.UniqueVisitors(ctx, token, from, to)
+	if err != nil {
+		switch {
+		case errors.Is(err, app.ErrInvalidRange):
+			http.Error(w, "invalid range", http.StatusBadRequest)
+		case errors.Is(err, app.ErrNotFound):
+			http.Error(w, "", http.StatusNotFound)
+		case errors.Is(err, app.ErrUnauthorized):
+			unauthorized(w)
+		case errors.Is(err, app.ErrForbidden):
+			http.Error(w, "", http.StatusForbidden)
+		case errors.Is(err, app.ErrNotSupported):
+			http.Error(w, "", http.StatusNotImplemented)
+		default:
+			logging.LogError(ctx, err)
+			http.Error(w, "", http.StatusInternalServerError)
+		}
+		return
+	}
+	result := api.UniqueVisitors{From: from, To: to, UniqueVisitors: int32(uniques)}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logging.LogError(ctx, fmt.Errorf("encoding [%v] to json failed: %w", result, err))
+	}
+}
+
+func toApiHit(hit *app.Hit) api.Hit {
+	result := api.Hit{Time: hit.Time}
+	if hit.Referer != "" {
+		result.Referer = &hit.Referer
+	}
+	if hit.UserAgent != "" {
+		result.UserAgent = &hit.UserAgent
+	}
+	if hit.ClientIP != "" {
+		result.ClientIp = &hit.ClientIP
+	}
+	if hit.RequestId != "" {
+		result.RequestId = &hit.RequestId
+	}
+	if hit.Rule > 0 {
+		rule := int32(hit.Rule)
+		result.Rule = &rule
+	}
+	return result
+}
+
+// clientIP returns remote address (already resolved from X-Forwarded-For / X-Real-IP by RealIP middleware) without port
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// timeParam returns optional time param, nil - param is missing (runtime binds missing time params as zero time)
+func timeParam(t *time.Time) *time.Time {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	return t
+}
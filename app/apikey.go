@@ -0,0 +1,147 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	cu "github.com/nj-eka/shurl/internal/contexts"
+	"github.com/nj-eka/shurl/internal/errs"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrInvalidOwner = errors.New("invalid owner")
+)
+
+var ownerPattern = regexp.MustCompile(`^[0-9A-Za-z_.@-]{1,64}$`)
+
+// ApiKey authenticates link owner, key itself is shown once on issuing: <id>.<secret>, only its hash is kept
+type ApiKey struct {
+	Id        string
+	Owner     string
+	Hash      string
+	Admin     bool
+	CreatedAt time.Time
+}
+
+// KeyStore is implemented by link stores keeping api keys along with links
+type KeyStore interface {
+	// AddKey saves key, ErrConflict is returned if key id is taken
+	AddKey(ctx context.Context, key *ApiKey) errs.Error
+	GetKey(ctx context.Context, id string) (*ApiKey, errs.Error)
+	ListKeys(ctx context.Context) ([]*ApiKey, errs.Error)
+	DeleteKey(ctx context.Context, id string) errs.Error
+}
+
+// Principal is authenticated caller: owner of links or admin
+type Principal struct {
+	Owner string
+	Admin bool
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns caller authenticated by api key or nil for anonymous one
+func PrincipalFrom(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// WithKeyStore turns on api key authentication: links are created by key owners and managed by them (or admins) only
+func WithKeyStore(keys KeyStore) Option {
+	return func(a *App) {
+		a.keys = keys
+	}
+}
+
+// IssueApiKey generates key of owner and saves it to store, it returns key to be handed over to owner
+func IssueApiKey(ctx context.Context, keys KeyStore, owner string, admin bool) (string, *ApiKey, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("app.IssueKey"))
+	if !ownerPattern.MatchString(owner) {
+		return "", nil, errs.E(ctx, errs.KindInvalidValue, errs.SeverityWarning, fmt.Errorf("[%s]: %w", owner, ErrInvalidOwner))
+	}
+	id, secret := make([]byte, 6), make([]byte, 24)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, errs.E(ctx, errs.KindInternal, fmt.Errorf("generating key failed: %w", err))
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, errs.E(ctx, errs.KindInternal, fmt.Errorf("generating key failed: %w", err))
+	}
+	key := &ApiKey{
+		Id:        hex.EncodeToString(id),
+		Owner:     owner,
+		Admin:     admin,
+		CreatedAt: time.Now().UTC(),
+	}
+	token := key.Id + "." + base64.RawURLEncoding.EncodeToString(secret)
+	key.Hash = hashApiKey(token)
+	if err := keys.AddKey(ctx, key); err != nil {
+		return "", nil, err
+	}
+	return token, key, nil
+}
+
+func hashApiKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Authenticate returns principal of api key
+func (a App) Authenticate(ctx context.Context, token string) (*Principal, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("app.Authenticate"))
+	if a.keys == nil {
+		return nil, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("api keys: %w", ErrNotSupported))
+	}
+	id := strings.SplitN(token, ".", 2)[0]
+	key, err := a.keys.GetKey(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("api key [%s]: %w", id, ErrUnauthorized))
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashApiKey(token)), []byte(key.Hash)) != 1 {
+		return nil, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("api key [%s]: %w", id, ErrUnauthorized))
+	}
+	return &Principal{Owner: key.Owner, Admin: key.Admin}, nil
+}
+
+// authorize checks that caller can manage link: it's owner or admin (all is allowed without api keys)
+func (a App) authorize(ctx context.Context, link *Link) errs.Error {
+	if a.keys == nil {
+		return nil
+	}
+	p := PrincipalFrom(ctx)
+	switch {
+	case p == nil:
+		return errs.E(ctx, errs.SeverityWarning, fmt.Errorf("link with id [%d]: %w", link.Id, ErrUnauthorized))
+	case !p.Admin && p.Owner != link.Owner:
+		return errs.E(ctx, errs.SeverityWarning, fmt.Errorf("link with id [%d] of [%s] by [%s]: %w", link.Id, link.Owner, p.Owner, ErrForbidden))
+	}
+	return nil
+}
+
+// getOwned returns link if caller can manage it
+func (a App) getOwned(ctx context.Context, id int) (*Link, errs.Error) {
+	link, err := a.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = a.authorize(ctx, link); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
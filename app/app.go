@@ -2,36 +2,122 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	cu "github.com/nj-eka/shurl/internal/contexts"
 	"github.com/nj-eka/shurl/internal/errs"
+	"github.com/nj-eka/shurl/internal/logging"
+	"io"
 	"net/url"
+	"strings"
 	"time"
 )
 
+// ReservedAliases are the top-level router paths that can't be taken by link aliases
+var ReservedAliases = []string{"openapi", "static", "info", "links", "admin"}
+
+var (
+	ErrNotSupported     = errors.New("not supported")
+	ErrNotDeleted       = errors.New("not deleted")
+	ErrRetentionExpired = errors.New("retention period expired")
+)
+
 type App struct {
 	store     LinkStore
 	tokenizer Tokenizer
+	hits      HitStore
+	backup    Backuper
+	reaper    *Reaper
+	auditor   Auditor
+	keys      KeyStore
+	attempts  *attemptLimiter
+	utm       Utm
 }
 
-func NewApp(store LinkStore, tokenizer Tokenizer) *App {
-	return &App{
+type Option func(a *App)
+
+// WithHitStore turns on recording of hit events
+func WithHitStore(hits HitStore) Option {
+	return func(a *App) {
+		a.hits = hits
+	}
+}
+
+func NewApp(store LinkStore, tokenizer Tokenizer, opts ...Option) *App {
+	a := &App{
 		store:     store,
 		tokenizer: tokenizer,
+		auditor:   logAuditor{},
+		attempts:  newAttemptLimiter(DefaultPasswordAttempts, DefaultPasswordWindow),
+	}
+	for _, opt := range opts {
+		opt(a)
 	}
+	return a
 }
 
-func (a App) CreateToken(ctx context.Context, targetUrl string, expiredAt *time.Time) (key string, added bool, err errs.Error) {
+// CreateToken adds link with given TargetUrl and optional Alias, ExpiredAt, ActiveFrom, Password, MaxHits, RedirectType, passthrough modes, Utm and Rules,
+// it returns alias as key if one is requested otherwise key is encoded from link id;
+// deleted or expired link with the same url is renewed with given settings (it is counted as added)
+func (a App) CreateToken(ctx context.Context, link *Link) (key string, added bool, err errs.Error) {
 	ctx = cu.BuildContext(ctx, cu.AddContextOperation("app.Create"))
-	if _, ie := url.ParseRequestURI(targetUrl); ie != nil {
+	if _, ie := url.ParseRequestURI(link.TargetUrl); ie != nil {
 		return "", false, errs.E(
 			ctx,
 			errs.KindInvalidValue,
 			ErrInvalidUrl,
 		)
 	}
-	if id, added, err := a.store.Create(ctx, targetUrl, expiredAt); err != nil {
+	if link.Alias != "" {
+		if err := a.validateAlias(link.Alias); err != nil {
+			return "", false, errs.E(ctx, errs.KindInvalidValue, errs.SeverityWarning, err)
+		}
+	}
+	if a.keys != nil {
+		p := PrincipalFrom(ctx)
+		if p == nil {
+			return "", false, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("creating link: %w", ErrUnauthorized))
+		}
+		link.Owner = p.Owner
+	}
+	if link.MaxHits < 0 {
+		return "", false, errs.E(ctx, errs.KindInvalidValue, errs.SeverityWarning, fmt.Errorf("max hits [%d]: %w", link.MaxHits, ErrInvalidMaxHits))
+	}
+	if link.RedirectType != 0 && !IsRedirectType(link.RedirectType) {
+		return "", false, errs.E(ctx, errs.KindInvalidValue, errs.SeverityWarning, fmt.Errorf("redirect type [%d]: %w", link.RedirectType, ErrInvalidRedirect))
+	}
+	if !link.QueryPassthrough.IsValid() {
+		return "", false, errs.E(ctx, errs.KindInvalidValue, errs.SeverityWarning, fmt.Errorf("query passthrough [%s]: %w", link.QueryPassthrough, ErrInvalidPassthrough))
+	}
+	if link.Utm.IsZero() {
+		link.Utm = nil
+	}
+	if err := validateRules(link.Rules); err != nil {
+		return "", false, errs.E(ctx, errs.KindInvalidValue, errs.SeverityWarning, err)
+	}
+	if len(link.Rules) == 0 {
+		link.Rules = nil
+	}
+	if link.ActiveFrom != nil {
+		// activation is scheduled to the second, stores keep times with different precision
+		activeFrom := link.ActiveFrom.UTC().Truncate(time.Second)
+		if link.ExpiredAt != nil && !activeFrom.Before(*link.ExpiredAt) {
+			return "", false, errs.E(ctx, errs.KindInvalidValue, errs.SeverityWarning, fmt.Errorf("active from [%v] expired at [%v]: %w", activeFrom, link.ExpiredAt, ErrInvalidActiveFrom))
+		}
+		link.ActiveFrom = &activeFrom
+	}
+	if link.Password != "" {
+		var ie error
+		if link.PasswordHash, ie = HashPassword(link.Password); ie != nil {
+			return "", false, errs.E(ctx, errs.KindInvalidValue, errs.SeverityWarning, ie)
+		}
+	}
+	if id, added, err := a.store.Create(ctx, link); err != nil {
 		return "", added, err
+	} else if added, err = a.checkExisting(ctx, id, added, link); err != nil {
+		return "", false, err
+	} else if link.Alias != "" {
+		return link.Alias, added, nil
 	} else if key, err := a.tokenizer.Encode(id); err != nil {
 		if err2 := a.store.Delete(ctx, id); err2 != nil {
 			return "", true, errs.E(
@@ -52,48 +138,450 @@ func (a App) CreateToken(ctx context.Context, targetUrl string, expiredAt *time.
 	}
 }
 
+// GetLink returns link, target url of password protected link is shown to its owner and admins only,
+// link that is not active yet is found for them only as well
 func (a App) GetLink(ctx context.Context, key string) (*Link, errs.Error) {
 	ctx = cu.BuildContext(ctx, cu.AddContextOperation("app.Get"))
-	id, err := a.tokenizer.Decode(key)
+	id, err := a.resolve(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	link, err := a.store.Get(ctx, id)
 	if err != nil {
-		return nil, errs.E(ctx, errs.SeverityCritical, errs.KindTokenizer, fmt.Errorf("decoding key [%s] failed: %w", key, err))
+		return nil, err
+	}
+	if !link.IsActive(time.Now().UTC()) && a.authorize(ctx, link) != nil {
+		return nil, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("getting link with id[%d] not active yet: %w", id, ErrNotFound))
 	}
-	return a.store.Get(ctx, id) // return keyless obj, it is known
+	hideTarget(ctx, link)
+	if a.hits != nil {
+		if link.UniqueVisitors, err = a.hits.Uniques(ctx, id, time.Time{}, time.Time{}); err != nil {
+			logging.LogError(ctx, errs.SeverityWarning, err)
+		}
+	}
+	return link, nil // return keyless obj, it is known
 }
 
-func (a App) HitLink(ctx context.Context, key string) (*Link, errs.Error) {
+// HitLink increments link hits and records hit event (if hit store is set) completed with link id, time, request id and matched rule,
+// target url of returned link (or of its first rule matching hit) is completed with utm params and path and query of hit according to passthrough modes of link,
+// ErrNotActive is returned before link ActiveFrom time, ErrPasswordRequired is returned for password protected link (see UnlockLink)
+func (a App) HitLink(ctx context.Context, key string, hit *Hit) (*Link, errs.Error) {
 	ctx = cu.BuildContext(ctx, cu.AddContextOperation("app.Hit"))
-	id, err := a.tokenizer.Decode(key)
+	return a.hit(ctx, key, hit, nil)
+}
+
+// UnlockLink hits password protected link if password is valid,
+// ErrTooManyAttempts is returned once failed attempts of link exceed the limit
+func (a App) UnlockLink(ctx context.Context, key string, password string, hit *Hit) (*Link, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("app.Unlock"))
+	return a.hit(ctx, key, hit, &password)
+}
+
+func (a App) hit(ctx context.Context, key string, hit *Hit, password *string) (*Link, errs.Error) {
+	id, err := a.resolve(ctx, key)
 	if err != nil {
-		return nil, errs.E(ctx, errs.SeverityCritical, errs.KindTokenizer, fmt.Errorf("decoding key [%s] failed: %w", key, err))
+		return nil, err
 	}
 	now := time.Now().UTC()
-	if link, err := a.store.Get(ctx, id); err != nil {
+	link, err := a.store.Get(ctx, id)
+	if err != nil {
 		return nil, err
-	} else {
-		if link.DeletedAt != nil && now.After(link.DeletedAt.UTC()) {
-			return nil, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("hit deleted link with id[%d]: %w", id, ErrNotFound))
+	}
+	if link.IsDeleted(now) {
+		return nil, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("hit deleted link with id[%d]: %w", id, ErrNotFound))
+	}
+	if link.IsExpired(now) {
+		return nil, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("hit expired link with id[%d]: %w", id, ErrNotFound))
+	}
+	if hit != nil && hit.Path != "" && !link.PathPassthrough {
+		return nil, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("hit link with id[%d] by path [%s]: %w", id, hit.Path, ErrNotFound))
+	}
+	if !link.IsActive(now) {
+		return nil, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("hit link with id[%d] active from [%v]: %w", id, link.ActiveFrom, ErrNotActive))
+	}
+	if link.IsExhausted() {
+		return nil, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("hit link with id[%d]: %w", id, ErrHitsExhausted))
+	}
+	if link.PasswordHash != "" {
+		switch {
+		case password == nil:
+			return nil, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("hit link with id[%d]: %w", id, ErrPasswordRequired))
+		case !a.attempts.reserve(id, now):
+			return nil, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("unlock link with id[%d]: %w", id, ErrTooManyAttempts))
+		case !checkPassword(link.PasswordHash, *password):
+			return nil, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("unlock link with id[%d]: %w", id, ErrInvalidPassword))
+		default:
+			a.attempts.credit(id)
+		}
+	}
+	// link may be exhausted since it was read, store checks it once again along with hit
+	if link, err = a.store.Hit(ctx, id); err != nil {
+		return nil, err
+	}
+	var suffix string
+	var query url.Values
+	if hit != nil {
+		suffix, query = hit.Path, hit.Query
+		if hit.Rule = matchRule(link.Rules, hit); hit.Rule > 0 {
+			link.TargetUrl = link.Rules[hit.Rule-1].TargetUrl
+		}
+	}
+	var ie error
+	if link.TargetUrl, ie = link.redirectUrl(suffix, query, a.utm); ie != nil {
+		return nil, errs.E(ctx, errs.KindInternal, fmt.Errorf("completing target url [%s] of link with id[%d] failed: %w", link.TargetUrl, id, ie))
+	}
+	if a.hits != nil && hit != nil {
+		hit.LinkId = id
+		hit.Time = now
+		if hit.RequestId == "" {
+			hit.RequestId = cu.GetRequestID(ctx)
+		}
+		if err := a.hits.AddHit(ctx, hit); err != nil {
+			// redirect is more important than its record
+			logging.LogError(ctx, errs.SeverityWarning, err)
+		}
+	}
+	return link, nil // return keyless obj, it is known
+}
+
+// ListHits returns hit events of link in [from, to) time range
+func (a App) ListHits(ctx context.Context, key string, from, to time.Time, limit int) ([]*Hit, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("app.ListHits"))
+	if a.hits == nil {
+		return nil, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("hit events: %w", ErrNotSupported))
+	}
+	id, err := a.resolve(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := a.getOwned(ctx, id); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultHitsLimit
+	} else if limit > MaxHitsLimit {
+		limit = MaxHitsLimit
+	}
+	return a.hits.ListHits(ctx, id, from, to, limit)
+}
+
+// Stats returns link hits counted in time buckets of granularity g covering [from, to) time range (empty buckets included)
+func (a App) Stats(ctx context.Context, key string, g Granularity, from, to time.Time) ([]*StatsBucket, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("app.Stats"))
+	if a.hits == nil {
+		return nil, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("hit stats: %w", ErrNotSupported))
+	}
+	step := g.Duration()
+	if step == 0 {
+		return nil, errs.E(ctx, errs.SeverityWarning, errs.KindInvalidValue, fmt.Errorf("granularity [%s]: %w", g, ErrInvalidRange))
+	}
+	from, to = g.Truncate(from), g.Truncate(to.Add(step-1))
+	if !from.Before(to) || to.Sub(from)/step > MaxStatsBuckets {
+		return nil, errs.E(ctx, errs.SeverityWarning, errs.KindInvalidValue, fmt.Errorf("[%v, %v) by %s: %w", from, to, g, ErrInvalidRange))
+	}
+	id, err := a.resolve(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := a.getOwned(ctx, id); err != nil {
+		return nil, err
+	}
+	buckets, err := a.hits.Stats(ctx, id, g, from, to)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*StatsBucket, 0, to.Sub(from)/step)
+	for t, i := from, 0; t.Before(to); t = t.Add(step) {
+		if i < len(buckets) && buckets[i].Start.Equal(t) {
+			result = append(result, buckets[i])
+			i++
+		} else {
+			result = append(result, &StatsBucket{Start: t})
+		}
+	}
+	return result, nil
+}
+
+// UniqueVisitors estimates number of unique visitors of link in [from, to) time range rounded to UniquesGranularity
+func (a App) UniqueVisitors(ctx context.Context, key string, from, to time.Time) (int, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("app.Uniques"))
+	if a.hits == nil {
+		return 0, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("unique visitors: %w", ErrNotSupported))
+	}
+	g := UniquesGranularity
+	from, to = g.Truncate(from), g.Truncate(to.Add(g.Duration()-1))
+	if !from.Before(to) {
+		return 0, errs.E(ctx, errs.SeverityWarning, errs.KindInvalidValue, fmt.Errorf("[%v, %v): %w", from, to, ErrInvalidRange))
+	}
+	id, err := a.resolve(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := a.getOwned(ctx, id); err != nil {
+		return 0, err
+	}
+	return a.hits.Uniques(ctx, id, from, to)
+}
+
+// ListLinks returns page of links (with keys) and cursor of the next page if there is one
+func (a App) ListLinks(ctx context.Context, q ListQuery) ([]*Link, *ListCursor, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("app.List"))
+	if q.Limit <= 0 {
+		q.Limit = DefaultListLimit
+	} else if q.Limit > MaxListLimit {
+		q.Limit = MaxListLimit
+	}
+	limit := q.Limit
+	q.Limit++ // to find out if there is next page
+	if q.Now.IsZero() {
+		q.Now = time.Now().UTC()
+	}
+	if a.keys != nil {
+		p := PrincipalFrom(ctx)
+		if p == nil {
+			return nil, nil, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("listing links: %w", ErrUnauthorized))
 		}
-		if link.ExpiredAt != nil && now.After(link.ExpiredAt.UTC()) {
-			return nil, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("hit expired link with id[%d]: %w", id, ErrNotFound))
+		if !p.Admin {
+			q.Owner = &p.Owner
 		}
-		return a.store.Hit(ctx, id) // return keyless obj, it is known
 	}
+	links, err := a.store.List(ctx, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	var next *ListCursor
+	if len(links) > limit {
+		links = links[:limit]
+		next = CursorOf(links[limit-1])
+	}
+	for _, link := range links {
+		hideTarget(ctx, link)
+		if link.Alias != "" {
+			link.Key = link.Alias
+		} else if link.Key, err = a.encode(ctx, link.Id); err != nil {
+			return nil, nil, err
+		}
+	}
+	return links, next, nil
+}
+
+// UpdateLink changes target url, expiration and / or targeting rules of link
+func (a App) UpdateLink(ctx context.Context, key string, patch *LinkPatch) (*Link, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("app.Update"))
+	if patch.TargetUrl != nil {
+		if _, ie := url.ParseRequestURI(*patch.TargetUrl); ie != nil {
+			return nil, errs.E(ctx, errs.KindInvalidValue, errs.SeverityWarning, ErrInvalidUrl)
+		}
+	}
+	if patch.Rules != nil {
+		if err := validateRules(*patch.Rules); err != nil {
+			return nil, errs.E(ctx, errs.KindInvalidValue, errs.SeverityWarning, err)
+		}
+	}
+	id, err := a.resolve(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	link, err := a.getOwned(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if patch.TargetUrl != nil {
+		link.TargetUrl = *patch.TargetUrl
+	}
+	if patch.ExpiredAt != nil {
+		link.ExpiredAt = patch.ExpiredAt
+	}
+	if patch.Rules != nil {
+		link.Rules = nil
+		if len(*patch.Rules) > 0 {
+			link.Rules = *patch.Rules
+		}
+	}
+	if err = a.store.Update(ctx, link); err != nil {
+		return nil, err
+	}
+	a.audit(ctx, AuditUpdate, id, key)
+	return link, nil
 }
 
 func (a App) DeleteLink(ctx context.Context, key string) errs.Error {
 	ctx = cu.BuildContext(ctx, cu.AddContextOperation("app.Delete"))
-	id, err := a.tokenizer.Decode(key)
+	id, err := a.resolve(ctx, key)
 	if err != nil {
-		return errs.E(ctx, errs.SeverityCritical, errs.KindTokenizer, fmt.Errorf("decoding key [%s] failed: %w", key, err))
+		return err
+	}
+	if _, err = a.getOwned(ctx, id); err != nil {
+		return err
+	}
+	if err = a.store.SetDeleted(ctx, id); err != nil {
+		return err
+	}
+	a.audit(ctx, AuditDelete, id, key)
+	return nil
+}
+
+// RestoreLink undeletes link unless it has been deleted longer than reaper retention period ago
+func (a App) RestoreLink(ctx context.Context, key string) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("app.Restore"))
+	id, err := a.resolve(ctx, key)
+	if err != nil {
+		return err
+	}
+	link, err := a.getOwned(ctx, id)
+	if err != nil {
+		return err
+	}
+	if link.DeletedAt == nil {
+		return errs.E(ctx, errs.SeverityWarning, fmt.Errorf("restoring link [%s] failed: %w", key, ErrNotDeleted))
+	}
+	if a.reaper != nil && link.DeletedAt.Before(time.Now().UTC().Add(-a.reaper.retention)) {
+		return errs.E(ctx, errs.SeverityWarning, fmt.Errorf("restoring link [%s] deleted at [%v] failed: %w", key, link.DeletedAt, ErrRetentionExpired))
+	}
+	if err = a.store.Restore(ctx, id); err != nil {
+		return err
+	}
+	a.audit(ctx, AuditRestore, id, key)
+	return nil
+}
+
+// Backup writes snapshot of store to w
+func (a App) Backup(ctx context.Context, w io.Writer) (int64, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("app.Backup"))
+	if a.backup == nil {
+		return 0, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("backup: %w", ErrNotSupported))
 	}
-	return a.store.SetDeleted(ctx, id)
+	return a.backup.Backup(ctx, w)
+}
+
+// ReaperStats returns metrics of reaper if it's turned on
+func (a App) ReaperStats() (ReaperStats, bool) {
+	if a.reaper == nil {
+		return ReaperStats{}, false
+	}
+	return a.reaper.Stats(), true
 }
 
 func (a App) Close(ctx context.Context) errs.Error {
 	ctx = cu.BuildContext(ctx, cu.AddContextOperation("app.Close"))
+	if a.reaper != nil {
+		a.reaper.Stop()
+	}
+	if a.hits != nil {
+		if err := a.hits.Close(ctx); err != nil {
+			logging.LogError(ctx, err)
+		}
+	}
 	if a.store != nil {
 		return a.store.Close(ctx)
 	}
 	return nil
 }
+
+// checkExisting makes sure that existing link (found by url instead of being added) is protected by the same password, limited
+// by the same max hits, activated at the same time and redirects the same way as requested, otherwise requester would get
+// short url of public link instead of protected one (or vice versa)
+// checkExisting returns whether link is added: existing link (with the same url) is either renewed if it is dead (deleted or expired)
+// or checked to have the same settings as requested
+func (a App) checkExisting(ctx context.Context, id int, added bool, requested *Link) (bool, errs.Error) {
+	if added {
+		return true, nil
+	}
+	link, err := a.store.Get(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if now := time.Now().UTC(); link.IsDeleted(now) || link.IsExpired(now) {
+		return true, a.renew(ctx, link, requested)
+	}
+	if link.MaxHits != requested.MaxHits {
+		return false, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("adding link with id [%d] limited differently: %w", id, ErrConflict))
+	}
+	if (link.ActiveFrom == nil) != (requested.ActiveFrom == nil) || link.ActiveFrom != nil && !link.ActiveFrom.Equal(*requested.ActiveFrom) {
+		return false, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("adding link with id [%d] scheduled differently: %w", id, ErrConflict))
+	}
+	if link.RedirectType != requested.RedirectType || link.QueryPassthrough != requested.QueryPassthrough ||
+		link.PathPassthrough != requested.PathPassthrough || link.Utm.Encode() != requested.Utm.Encode() ||
+		EncodeRules(link.Rules) != EncodeRules(requested.Rules) {
+		return false, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("adding link with id [%d] redirected differently: %w", id, ErrConflict))
+	}
+	password := requested.Password
+	if link.PasswordHash == "" && password == "" || link.PasswordHash != "" && password != "" && checkPassword(link.PasswordHash, password) {
+		return false, nil
+	}
+	return false, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("adding link with id [%d] protected differently: %w", id, ErrConflict))
+}
+
+// renew replaces dead link with requested one keeping its id (and alias if none is requested),
+// so the link starts over: it is created now, not deleted and has no hits
+func (a App) renew(ctx context.Context, dead *Link, requested *Link) errs.Error {
+	renewed := *requested
+	renewed.Id = dead.Id
+	renewed.Owner = dead.Owner
+	renewed.CreatedAt = time.Now().UTC()
+	renewed.DeletedAt = nil
+	renewed.Hits = 0
+	if renewed.Alias == "" {
+		renewed.Alias = dead.Alias
+	}
+	if a.hits != nil {
+		if _, err := a.hits.Purge(ctx, dead.Id); err != nil {
+			return err
+		}
+	}
+	if err := a.store.Import(ctx, &renewed); err != nil {
+		return err
+	}
+	key := renewed.Alias
+	if key == "" {
+		var err errs.Error
+		if key, err = a.encode(ctx, dead.Id); err != nil {
+			return err
+		}
+	}
+	a.audit(ctx, AuditRenew, dead.Id, key)
+	return nil
+}
+
+// resolve returns link id by key which is either token encoded by tokenizer or link alias
+func (a App) resolve(ctx context.Context, key string) (int, errs.Error) {
+	id, err := a.tokenizer.Decode(key)
+	if err == nil {
+		return id, nil
+	}
+	if a.validateAlias(key) != nil {
+		return -1, errs.E(ctx, errs.SeverityWarning, errs.KindTokenizer, fmt.Errorf("decoding key [%s] failed: %v: %w", key, err, ErrNotFound))
+	}
+	return a.store.Lookup(ctx, key)
+}
+
+func (a App) encode(ctx context.Context, id int) (string, errs.Error) {
+	key, err := a.tokenizer.Encode(id)
+	if err != nil {
+		return "", errs.E(ctx, errs.SeverityCritical, errs.KindTokenizer, fmt.Errorf("encoding id [%d] failed: %w", id, err))
+	}
+	return key, nil
+}
+
+// validateAlias checks that alias consists of tokenizer alphabet symbols, doesn't match any reserved path
+// and can't be confused with token of another link
+func (a App) validateAlias(alias string) error {
+	if alias == "" {
+		return fmt.Errorf("empty alias: %w", ErrInvalidAlias)
+	}
+	alphabet := a.tokenizer.Alphabet()
+	for _, r := range alias {
+		if !strings.ContainsRune(alphabet, r) {
+			return fmt.Errorf("alias [%s] contains symbol [%c] out of alphabet: %w", alias, r, ErrInvalidAlias)
+		}
+	}
+	for _, reserved := range ReservedAliases {
+		if strings.EqualFold(alias, reserved) {
+			return fmt.Errorf("alias [%s] is reserved: %w", alias, ErrInvalidAlias)
+		}
+	}
+	if _, err := a.tokenizer.Decode(alias); err == nil {
+		return fmt.Errorf("alias [%s] is decodable as token: %w", alias, ErrInvalidAlias)
+	}
+	return nil
+}
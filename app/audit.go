@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"github.com/nj-eka/shurl/internal/errs"
+	"github.com/nj-eka/shurl/internal/logging"
+	"time"
+)
+
+const (
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	// AuditRenew is recreation of dead (deleted or expired) link by adding its url again
+	AuditRenew = "renew"
+)
+
+// AuditEvent is record of audit trail of link changes,
+// Owner and Admin are of principal who made the change (both empty - anonymous caller)
+type AuditEvent struct {
+	Time   time.Time
+	Action string
+	LinkId int
+	Key    string
+	Owner  string
+	Admin  bool
+}
+
+type Auditor interface {
+	Audit(ctx context.Context, event *AuditEvent) errs.Error
+}
+
+// WithAuditor replaces default audit trail (records in app log)
+func WithAuditor(auditor Auditor) Option {
+	return func(a *App) {
+		a.auditor = auditor
+	}
+}
+
+// logAuditor writes audit trail to app log
+type logAuditor struct{}
+
+func (logAuditor) Audit(ctx context.Context, event *AuditEvent) errs.Error {
+	logging.Msg(ctx).WithFields(map[string]interface{}{
+		"rec":     "audit",
+		"action":  event.Action,
+		"link_id": event.LinkId,
+		"key":     event.Key,
+		"owner":   event.Owner,
+		"admin":   event.Admin,
+		"at":      event.Time.Format(time.RFC3339Nano),
+	}).Infof("link %s: %s", event.Action, event.Key)
+	return nil
+}
+
+// audit records event, failures of audit trail don't fail audited ops
+func (a App) audit(ctx context.Context, action string, id int, key string) {
+	event := &AuditEvent{Time: time.Now().UTC(), Action: action, LinkId: id, Key: key}
+	if p := PrincipalFrom(ctx); p != nil {
+		event.Owner, event.Admin = p.Owner, p.Admin
+	}
+	if err := a.auditor.Audit(ctx, event); err != nil {
+		logging.LogError(ctx, err)
+	}
+}
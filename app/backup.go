@@ -0,0 +1,23 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"github.com/nj-eka/shurl/internal/errs"
+	"io"
+)
+
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// Backuper is implemented by stores able to write consistent snapshot of their data while serving requests
+type Backuper interface {
+	// Backup writes snapshot to w and returns number of bytes written
+	Backup(ctx context.Context, w io.Writer) (int64, errs.Error)
+}
+
+// WithBackuper turns on online backups
+func WithBackuper(backup Backuper) Option {
+	return func(a *App) {
+		a.backup = backup
+	}
+}
@@ -10,7 +10,8 @@ import (
 )
 
 type HashidTokenizer struct {
-	h *hashids.HashID
+	h        *hashids.HashID
+	alphabet string
 }
 
 func (htz *HashidTokenizer) Decode(key string) (int, error) {
@@ -32,6 +33,10 @@ func (htz *HashidTokenizer) Encode(id int) (string, error) {
 	}
 }
 
+func (htz *HashidTokenizer) Alphabet() string {
+	return htz.alphabet
+}
+
 func NewHashidTokenizer(cfg *config.HashidTokenizerConfig) (app.Tokenizer, error) {
 	logging.Msg(cu.Operation("hashid_init")).Debugf("config: %v", cfg)
 	hd := hashids.NewData()
@@ -47,7 +52,7 @@ func NewHashidTokenizer(cfg *config.HashidTokenizerConfig) (app.Tokenizer, error
 		}
 	}
 	if h, err := hashids.NewWithData(hd); err == nil {
-		return &HashidTokenizer{h: h}, nil
+		return &HashidTokenizer{h: h, alphabet: hd.Alphabet}, nil
 	} else {
 		return nil, err
 	}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"context"
+	"github.com/nj-eka/shurl/internal/errs"
+	"hash/fnv"
+	"net/url"
+	"time"
+)
+
+const (
+	DefaultHitsLimit = 100
+	MaxHitsLimit     = 1000
+)
+
+// Hit is redirect event
+type Hit struct {
+	LinkId    int
+	Time      time.Time
+	Referer   string
+	UserAgent string
+	ClientIP  string
+	RequestId string
+	// AcceptLanguage is Accept-Language header of request matched with targeting rules of link, it is not recorded
+	AcceptLanguage string `json:"-"`
+	// Rule is 1-based index of targeting rule of link the hit is redirected by, 0 - target url of link
+	Rule int
+	// Path (suffix of wildcard short url) and Query of request are passed to target url, they are not recorded
+	Path  string     `json:"-"`
+	Query url.Values `json:"-"`
+}
+
+// Fingerprint identifies visitor by client ip and user agent
+func (h *Hit) Fingerprint() uint64 {
+	f := fnv.New64a()
+	_, _ = f.Write([]byte(h.ClientIP))
+	_, _ = f.Write([]byte{0})
+	_, _ = f.Write([]byte(h.UserAgent))
+	return f.Sum64()
+}
+
+type HitStore interface {
+	// AddHit records hit, updates its time buckets counters of all Granularities
+	// and adds hit fingerprint to unique visitors sketches of UniquesGranularity time bucket and of all time
+	AddHit(ctx context.Context, hit *Hit) errs.Error
+	// ListHits returns link hits in [from, to) time range ordered by time, limit = 0 - no limit
+	ListHits(ctx context.Context, linkId int, from, to time.Time, limit int) ([]*Hit, errs.Error)
+	// Stats returns non-empty time buckets of link hits with starts in [from, to) ordered by time
+	Stats(ctx context.Context, linkId int, g Granularity, from, to time.Time) ([]*StatsBucket, errs.Error)
+	// Uniques estimates number of unique visitors of link by merging sketches of UniquesGranularity time buckets
+	// with starts in [from, to), zero range (both from and to are zero) - of all time
+	Uniques(ctx context.Context, linkId int, from, to time.Time) (int, errs.Error)
+	// Purge deletes hits, time buckets counters and unique visitors sketches of link, it returns number of deleted records
+	Purge(ctx context.Context, linkId int) (int, errs.Error)
+	Close(ctx context.Context) errs.Error
+}
+
+// HitBatcher is hit store able to record many hits at once (see HitStore.AddHit), buffered hits are flushed with it
+type HitBatcher interface {
+	AddHits(ctx context.Context, hits []*Hit) errs.Error
+}
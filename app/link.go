@@ -2,17 +2,93 @@ package app
 
 import (
 	"errors"
+	"net/http"
 	"time"
 )
 
-var ErrInvalidUrl = errors.New("invalid url")
+var (
+	ErrInvalidUrl        = errors.New("invalid url")
+	ErrInvalidAlias      = errors.New("invalid alias")
+	ErrAliasExists       = errors.New("alias already exists")
+	ErrInvalidMaxHits    = errors.New("invalid max hits")
+	ErrInvalidActiveFrom = errors.New("invalid active from")
+	ErrInvalidRedirect   = errors.New("invalid redirect type")
+	// ErrNotActive is returned on hitting link before its ActiveFrom time
+	ErrNotActive = errors.New("not active yet")
+	// ErrHitsExhausted is returned on hitting link that has been hit MaxHits times
+	ErrHitsExhausted = errors.New("hits exhausted")
+)
 
 type Link struct {
 	Id        int
 	Key       string
+	Alias     string
 	TargetUrl string
-	CreatedAt time.Time
+	// Owner is name of api key owner who created link, empty - link is created without api keys
+	Owner string
+	// Password is plain password requested for link being created, only its hash is kept (PasswordHash)
+	Password     string
+	PasswordHash string
+	CreatedAt    time.Time
+	ExpiredAt    *time.Time
+	// ActiveFrom is time link can't be followed before, nil - link is active since it's created
+	ActiveFrom *time.Time
+	DeletedAt  *time.Time
+	Hits       int
+	// MaxHits is number of hits link can be followed, 0 = unlimited
+	MaxHits int
+	// RedirectType is http status of redirect to target url (one of RedirectTypes), 0 = default of router
+	RedirectType int
+	// QueryPassthrough is mode of passing query of request to target url
+	QueryPassthrough QueryPassthrough
+	// PathPassthrough makes link wildcard: path following its key is appended to target url path
+	PathPassthrough bool
+	// Utm is utm params of link added to target url on redirect (replacing the ones of target url), nil - no params
+	Utm *Utm
+	// Rules are targeting rules of link checked in order on hit, target url of the first matching one is followed
+	Rules []Rule
+	// UniqueVisitors is estimated by app (if hits are tracked), it is not kept by link stores
+	UniqueVisitors int
+}
+
+// RedirectTypes are http statuses link can redirect with
+var RedirectTypes = []int{
+	http.StatusMovedPermanently,
+	http.StatusFound,
+	http.StatusSeeOther,
+	http.StatusTemporaryRedirect,
+	http.StatusPermanentRedirect,
+}
+
+func IsRedirectType(status int) bool {
+	for _, rt := range RedirectTypes {
+		if status == rt {
+			return true
+		}
+	}
+	return false
+}
+
+// LinkPatch describes changes of link, nil fields are left as is
+type LinkPatch struct {
+	TargetUrl *string
 	ExpiredAt *time.Time
-	DeletedAt *time.Time
-	Hits      int
+	// Rules replace targeting rules of link, empty - rules are removed
+	Rules *[]Rule
+}
+
+func (l *Link) IsDeleted(now time.Time) bool {
+	return l.DeletedAt != nil && now.After(l.DeletedAt.UTC())
+}
+
+func (l *Link) IsActive(now time.Time) bool {
+	return l.ActiveFrom == nil || !now.Before(l.ActiveFrom.UTC())
+}
+
+func (l *Link) IsExhausted() bool {
+	return l.MaxHits > 0 && l.Hits >= l.MaxHits
+}
+
+func (l *Link) IsExpired(now time.Time) bool {
+	return l.ExpiredAt != nil && now.After(l.ExpiredAt.UTC())
 }
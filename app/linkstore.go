@@ -4,16 +4,32 @@ import (
 	"context"
 	"errors"
 	"github.com/nj-eka/shurl/internal/errs"
-	"time"
 )
 
 var ErrNotFound = errors.New("not found")
+var ErrConflict = errors.New("conflicts with another link")
 
 type LinkStore interface {
-	Create(ctx context.Context, targetUrl string, expiredAt *time.Time) (int, bool, errs.Error)
+	// Create adds link (with all its settings, while Id, CreatedAt, DeletedAt and Hits are set by store) or returns id of existing one with the same TargetUrl of the same Owner
+	// (alias is set to existing link if it has none, its expiration is left as is)
+	Create(ctx context.Context, link *Link) (int, bool, errs.Error)
 	Get(ctx context.Context, id int) (*Link, errs.Error)
+	// Lookup returns id of link with given alias
+	Lookup(ctx context.Context, alias string) (int, errs.Error)
+	// List returns links matched by query in query order (see ListQuery.Match and ListQuery.Sort)
+	List(ctx context.Context, q ListQuery) ([]*Link, errs.Error)
+	// Hit increments hits of link unless it is exhausted (ErrHitsExhausted is returned), check and increment are atomic
 	Hit(ctx context.Context, id int) (*Link, errs.Error)
+	// AddHits adds hits deltas (by link id) at once, links not found are skipped
+	AddHits(ctx context.Context, deltas map[int]int) errs.Error
+	// Update sets TargetUrl, ExpiredAt and Rules of link with link.Id, ErrConflict is returned if url belongs to another link of the same owner
+	Update(ctx context.Context, link *Link) errs.Error
+	// Import saves link as is (with its id and times) replacing the one with the same id,
+	// ids of links created later follow the imported ones; ErrConflict is returned if url (of the same owner) or alias belongs to another link
+	Import(ctx context.Context, link *Link) errs.Error
 	SetDeleted(ctx context.Context, id int) errs.Error
+	// Restore clears deleted time of link
+	Restore(ctx context.Context, id int) errs.Error
 	Delete(ctx context.Context, id int) errs.Error
 	Close(ctx context.Context) errs.Error
 }
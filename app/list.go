@@ -0,0 +1,126 @@
+package app
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+type ListSort string
+
+const (
+	SortById        ListSort = "id"
+	SortByCreatedAt ListSort = "createdAt"
+	SortByHits      ListSort = "hits"
+)
+
+// ListQuery describes page of links: state filters, ordering and cursor the page starts after
+type ListQuery struct {
+	SortBy ListSort
+	Desc   bool
+	// Expired and Deleted filters: nil - any, true - only expired (deleted) links, false - only not expired (not deleted) ones
+	Expired *bool
+	Deleted *bool
+	// Owner filter: nil - links of any owner
+	Owner *string
+	After *ListCursor
+	// Limit is max number of links to return, 0 - no limit
+	Limit int
+	// Now is the moment expiration and deletion are checked at
+	Now time.Time
+}
+
+// ListCursor is position of link in ordered list (link id makes order strict)
+type ListCursor struct {
+	Id        int
+	CreatedAt time.Time
+	Hits      int
+}
+
+func CursorOf(link *Link) *ListCursor {
+	return &ListCursor{Id: link.Id, CreatedAt: link.CreatedAt, Hits: link.Hits}
+}
+
+func (c *ListCursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d.%d.%d", c.Id, c.CreatedAt.UnixNano(), c.Hits)))
+}
+
+func DecodeListCursor(s string) (*ListCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("decoding cursor [%s] failed: %v: %w", s, err, ErrInvalidCursor)
+	}
+	var id, hits int
+	var ct int64
+	if _, err := fmt.Sscanf(string(data), "%d.%d.%d", &id, &ct, &hits); err != nil {
+		return nil, fmt.Errorf("parsing cursor [%s] failed: %v: %w", s, err, ErrInvalidCursor)
+	}
+	return &ListCursor{Id: id, CreatedAt: time.Unix(0, ct).UTC(), Hits: hits}, nil
+}
+
+// Match checks whether link passes query filters and is positioned after query cursor
+func (q *ListQuery) Match(link *Link) bool {
+	if q.Deleted != nil && *q.Deleted != link.IsDeleted(q.Now) {
+		return false
+	}
+	if q.Expired != nil && *q.Expired != link.IsExpired(q.Now) {
+		return false
+	}
+	if q.Owner != nil && *q.Owner != link.Owner {
+		return false
+	}
+	if q.After != nil && !q.Before(q.After, CursorOf(link)) {
+		return false
+	}
+	return true
+}
+
+// Before reports whether position a precedes position b in query order
+func (q *ListQuery) Before(a, b *ListCursor) bool {
+	var cmp int
+	switch q.SortBy {
+	case SortByCreatedAt:
+		if a.CreatedAt.Before(b.CreatedAt) {
+			cmp = -1
+		} else if a.CreatedAt.After(b.CreatedAt) {
+			cmp = 1
+		}
+	case SortByHits:
+		if a.Hits < b.Hits {
+			cmp = -1
+		} else if a.Hits > b.Hits {
+			cmp = 1
+		}
+	}
+	if cmp == 0 {
+		if a.Id < b.Id {
+			cmp = -1
+		} else if a.Id > b.Id {
+			cmp = 1
+		}
+	}
+	if q.Desc {
+		return cmp > 0
+	}
+	return cmp < 0
+}
+
+// Sort orders links (already matched) and cuts them by query limit
+func (q *ListQuery) Sort(links []*Link) []*Link {
+	sort.Slice(links, func(i, j int) bool {
+		return q.Before(CursorOf(links[i]), CursorOf(links[j]))
+	})
+	if q.Limit > 0 && len(links) > q.Limit {
+		links = links[:q.Limit]
+	}
+	return links
+}
@@ -0,0 +1,82 @@
+package app
+
+import (
+	"errors"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// QueryPassthrough is mode of passing query of short url request to target url, "" - query isn't passed
+type QueryPassthrough string
+
+const (
+	// QueryKeep passes request params unless target url has params with the same keys
+	QueryKeep QueryPassthrough = "keep"
+	// QueryOverride replaces target url params with request params of the same keys
+	QueryOverride QueryPassthrough = "override"
+	// QueryAppend adds request params to target url params of the same keys
+	QueryAppend QueryPassthrough = "append"
+)
+
+var ErrInvalidPassthrough = errors.New("invalid passthrough")
+
+func (m QueryPassthrough) IsValid() bool {
+	switch m {
+	case "", QueryKeep, QueryOverride, QueryAppend:
+		return true
+	}
+	return false
+}
+
+// redirectUrl returns target url of link completed with its utm params (and default ones),
+// path suffix (of wildcard link) and query of request
+func (l *Link) redirectUrl(suffix string, query url.Values, utm Utm) (string, error) {
+	if suffix == "" && (l.QueryPassthrough == "" || len(query) == 0) && l.Utm.IsZero() && utm.IsZero() {
+		return l.TargetUrl, nil
+	}
+	u, err := url.Parse(l.TargetUrl)
+	if err != nil {
+		return "", err
+	}
+	if suffix != "" {
+		// suffix is cleaned as rooted path not to climb above target url path
+		u.Path = strings.TrimSuffix(u.Path, "/") + path.Clean("/"+suffix)
+		u.RawPath = ""
+	}
+	u.RawQuery = applyUtm(u.RawQuery, l.Utm, utm)
+	if l.QueryPassthrough != "" {
+		u.RawQuery = mergeQuery(u.RawQuery, query, l.QueryPassthrough)
+	}
+	return u.String(), nil
+}
+
+// mergeQuery adds request query to raw target query, target query is kept as is unless its params are overridden
+func mergeQuery(rawQuery string, query url.Values, mode QueryPassthrough) string {
+	target, _ := url.ParseQuery(rawQuery)
+	extra := make(url.Values, len(query))
+	overridden := false
+	for key, values := range query {
+		if _, ok := target[key]; ok {
+			switch mode {
+			case QueryKeep:
+				continue
+			case QueryOverride:
+				target[key] = values
+				overridden = true
+				continue
+			}
+		}
+		extra[key] = values
+	}
+	if overridden {
+		rawQuery = target.Encode()
+	}
+	switch {
+	case len(extra) == 0:
+		return rawQuery
+	case rawQuery == "":
+		return extra.Encode()
+	}
+	return rawQuery + "&" + extra.Encode()
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nj-eka/shurl/config"
+	"golang.org/x/crypto/bcrypt"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultPasswordAttempts = 5
+	DefaultPasswordWindow   = 15 * time.Minute
+	// MaxPasswordLength is bcrypt limit
+	MaxPasswordLength = 72
+	// limiter forgets outdated failures once it tracks more links than that
+	maxTrackedLinks = 10000
+)
+
+var (
+	ErrPasswordRequired = errors.New("password required")
+	ErrInvalidPassword  = errors.New("invalid password")
+	ErrTooManyAttempts  = errors.New("too many attempts")
+)
+
+// WithPasswordAttempts sets limit of failed password attempts per link
+func WithPasswordAttempts(cfg config.PasswordConfig) Option {
+	return func(a *App) {
+		a.attempts = newAttemptLimiter(cfg.MaxAttempts, cfg.Window)
+	}
+}
+
+// HashPassword returns bcrypt hash of link password
+func HashPassword(password string) (string, error) {
+	if password == "" || len(password) > MaxPasswordLength {
+		return "", fmt.Errorf("password length [%d] out of [1, %d]: %w", len(password), MaxPasswordLength, ErrInvalidPassword)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func checkPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// attemptLimiter counts password attempts of links in fixed windows,
+// attempts are counted up front (so concurrent ones can't exceed the limit) and successful ones are credited back
+type attemptLimiter struct {
+	max    int
+	window time.Duration
+	mu     sync.Mutex
+	fails  map[int]*attempts
+}
+
+type attempts struct {
+	count int
+	since time.Time
+}
+
+func newAttemptLimiter(max int, window time.Duration) *attemptLimiter {
+	if max <= 0 {
+		max = DefaultPasswordAttempts
+	}
+	if window <= 0 {
+		window = DefaultPasswordWindow
+	}
+	return &attemptLimiter{max: max, window: window, fails: make(map[int]*attempts)}
+}
+
+// reserve counts attempt of link password, false - limit of attempts is exceeded so password can't be tried
+func (l *attemptLimiter) reserve(id int, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if a, ok := l.fails[id]; ok && now.Sub(a.since) < l.window {
+		if a.count >= l.max {
+			return false
+		}
+		a.count++
+		return true
+	}
+	if len(l.fails) >= maxTrackedLinks {
+		for fid, a := range l.fails {
+			if now.Sub(a.since) >= l.window {
+				delete(l.fails, fid)
+			}
+		}
+	}
+	l.fails[id] = &attempts{count: 1, since: now}
+	return true
+}
+
+// credit returns reserved attempt of link back once password turned out to be valid
+func (l *attemptLimiter) credit(id int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if a, ok := l.fails[id]; ok && a.count > 0 {
+		a.count--
+	}
+}
+
+// hideTarget clears target url (and targeting rules) of password protected link unless caller is its owner or admin
+func hideTarget(ctx context.Context, link *Link) {
+	if link.PasswordHash == "" {
+		return
+	}
+	if p := PrincipalFrom(ctx); p == nil || !p.Admin && p.Owner != link.Owner {
+		link.TargetUrl = ""
+		link.Rules = nil
+	}
+}
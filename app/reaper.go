@@ -0,0 +1,192 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nj-eka/shurl/config"
+	cu "github.com/nj-eka/shurl/internal/contexts"
+	"github.com/nj-eka/shurl/internal/errs"
+	"github.com/nj-eka/shurl/internal/logging"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultReapInterval  = time.Hour
+	DefaultReapRetention = 30 * 24 * time.Hour
+	DefaultReapBatch     = 100
+)
+
+// ReaperStats are reaper metrics since start
+type ReaperStats struct {
+	DryRun bool
+	Runs   int
+	// Expired and Deleted are numbers of links reaped (or found to be reaped in dry run) for being expired / deleted
+	Expired int
+	Deleted int
+	// Purged is number of hit records (hits, stats buckets and sketches) deleted along with reaped links (none in dry run)
+	Purged  int
+	Errors  int
+	LastRun time.Time
+}
+
+// Reaper hard-deletes links that have been expired or (soft) deleted for longer than retention period
+// along with their hits
+type Reaper struct {
+	store     LinkStore
+	hits      HitStore
+	interval  time.Duration
+	retention time.Duration
+	batch     int
+	dryRun    bool
+	mu        sync.Mutex
+	stats     ReaperStats
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// WithReaper makes app to stop reaper on closing (reaper is to be started separately)
+func WithReaper(r *Reaper) Option {
+	return func(a *App) {
+		a.reaper = r
+	}
+}
+
+// NewReaper returns reaper of links of store and their hits of hit store (nil - no hits are recorded)
+func NewReaper(store LinkStore, hits HitStore, cfg config.ReaperConfig) (*Reaper, error) {
+	if cfg.Interval < 0 || cfg.Retention < 0 || cfg.Batch < 0 {
+		return nil, fmt.Errorf("invalid reaper config: interval [%v] retention [%v] batch [%d]", cfg.Interval, cfg.Retention, cfg.Batch)
+	}
+	r := &Reaper{
+		store:     store,
+		hits:      hits,
+		interval:  cfg.Interval,
+		retention: cfg.Retention,
+		batch:     cfg.Batch,
+		dryRun:    cfg.DryRun,
+		stats:     ReaperStats{DryRun: cfg.DryRun},
+	}
+	if r.interval == 0 {
+		r.interval = DefaultReapInterval
+	}
+	if r.retention == 0 {
+		r.retention = DefaultReapRetention
+	}
+	if r.batch == 0 {
+		r.batch = DefaultReapBatch
+	}
+	return r, nil
+}
+
+// Start runs reaping every interval till Stop (or ctx is done)
+func (r *Reaper) Start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.done = make(chan struct{})
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_, _, _ = r.Reap(ctx, time.Now().UTC())
+			}
+		}
+	}()
+}
+
+// Stop stops reaping and waits for the current run (if any) to be interrupted
+func (r *Reaper) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+	r.cancel = nil
+}
+
+// Retention is period links are kept for after they expired or were deleted
+func (r *Reaper) Retention() time.Duration {
+	return r.retention
+}
+
+func (r *Reaper) Stats() ReaperStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+// Reap deletes links expired or deleted before now - retention (only counts them in dry run),
+// it returns numbers of such links
+func (r *Reaper) Reap(ctx context.Context, now time.Time) (expired, deleted int, err errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("app.Reap"))
+	yes, no := true, false
+	var purged, expiredPurged int
+	// links deleted long ago are counted as deleted even if they are expired as well
+	deleted, purged, err = r.reap(ctx, ListQuery{SortBy: SortById, Deleted: &yes, Now: now.Add(-r.retention)})
+	if err == nil {
+		expired, expiredPurged, err = r.reap(ctx, ListQuery{SortBy: SortById, Expired: &yes, Deleted: &no, Now: now.Add(-r.retention)})
+		purged += expiredPurged
+	}
+	r.mu.Lock()
+	r.stats.Runs++
+	r.stats.Expired += expired
+	r.stats.Deleted += deleted
+	r.stats.Purged += purged
+	if err != nil {
+		r.stats.Errors++
+	}
+	r.stats.LastRun = now
+	r.mu.Unlock()
+	if err != nil {
+		logging.LogError(ctx, err)
+	}
+	if r.dryRun {
+		logging.Msg(ctx).Infof("reaper dry run: %d expired and %d deleted links are to be reaped", expired, deleted)
+	} else {
+		logging.Msg(ctx).Infof("reaper: %d expired and %d deleted links are reaped with %d hit records", expired, deleted, purged)
+	}
+	return expired, deleted, err
+}
+
+// reap deletes links matched by q, it returns numbers of links and their hit records deleted
+func (r *Reaper) reap(ctx context.Context, q ListQuery) (int, int, errs.Error) {
+	count, purged := 0, 0
+	q.Limit = r.batch
+	for {
+		if ctx.Err() != nil {
+			return count, purged, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("reaping interrupted: %w", ctx.Err()))
+		}
+		links, err := r.store.List(ctx, q)
+		if err != nil {
+			return count, purged, err
+		}
+		for _, link := range links {
+			// links not deleted at now - retention may have been deleted since then, they are still restorable
+			if q.Deleted != nil && !*q.Deleted && link.DeletedAt != nil {
+				continue
+			}
+			if !r.dryRun {
+				// hits go first, so they are not left behind if link deletion fails (it is retried next run)
+				if r.hits != nil {
+					n, err := r.hits.Purge(ctx, link.Id)
+					if err != nil {
+						return count, purged, err
+					}
+					purged += n
+				}
+				if err := r.store.Delete(ctx, link.Id); err != nil && !errors.Is(err, ErrNotFound) {
+					return count, purged, err
+				}
+			}
+			count++
+		}
+		if len(links) < q.Limit {
+			return count, purged, nil
+		}
+		q.After = CursorOf(links[len(links)-1])
+	}
+}
@@ -0,0 +1,189 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MaxRules is max number of targeting rules of link
+const MaxRules = 20
+
+var ErrInvalidRule = errors.New("invalid rule")
+
+// Platform is client platform detected by user agent
+type Platform string
+
+const (
+	PlatformIOS     Platform = "ios"
+	PlatformAndroid Platform = "android"
+	PlatformWindows Platform = "windows"
+	PlatformMacOS   Platform = "macos"
+	PlatformLinux   Platform = "linux"
+)
+
+// platformMarks are user agent substrings of platforms in order of detection (android agents mention linux, ios ones - mac os x)
+var platformMarks = []struct {
+	platform Platform
+	marks    []string
+}{
+	{PlatformIOS, []string{"iPhone", "iPad", "iPod"}},
+	{PlatformAndroid, []string{"Android"}},
+	{PlatformWindows, []string{"Windows"}},
+	{PlatformMacOS, []string{"Macintosh", "Mac OS X"}},
+	{PlatformLinux, []string{"Linux"}},
+}
+
+func (p Platform) IsValid() bool {
+	for _, pm := range platformMarks {
+		if p == pm.platform {
+			return true
+		}
+	}
+	return p == ""
+}
+
+// PlatformOf detects platform by user agent, "" - unknown
+func PlatformOf(userAgent string) Platform {
+	for _, pm := range platformMarks {
+		for _, mark := range pm.marks {
+			if strings.Contains(userAgent, mark) {
+				return pm.platform
+			}
+		}
+	}
+	return ""
+}
+
+// Rule redirects hits matching all of its non-empty conditions to its own target url
+type Rule struct {
+	Platform Platform `json:"platform,omitempty"`
+	// Language is language tag (e.g. "de" or "pt-BR") matched with the preferred language of Accept-Language header,
+	// tag without region matches any region of the language
+	Language string `json:"language,omitempty"`
+	// CIDR is network of client ip
+	CIDR      string `json:"cidr,omitempty"`
+	TargetUrl string `json:"url"`
+}
+
+// Validate checks that rule has valid target url and at least one valid condition
+func (r *Rule) Validate() error {
+	if _, err := url.ParseRequestURI(r.TargetUrl); err != nil {
+		return fmt.Errorf("target url [%s]: %w", r.TargetUrl, ErrInvalidRule)
+	}
+	if r.Platform == "" && r.Language == "" && r.CIDR == "" {
+		return fmt.Errorf("rule of [%s] has no conditions: %w", r.TargetUrl, ErrInvalidRule)
+	}
+	if !r.Platform.IsValid() {
+		return fmt.Errorf("platform [%s]: %w", r.Platform, ErrInvalidRule)
+	}
+	if strings.ContainsAny(r.Language, ",;= ") {
+		return fmt.Errorf("language [%s]: %w", r.Language, ErrInvalidRule)
+	}
+	if r.CIDR != "" {
+		if _, _, err := net.ParseCIDR(r.CIDR); err != nil {
+			return fmt.Errorf("cidr [%s]: %w", r.CIDR, ErrInvalidRule)
+		}
+	}
+	return nil
+}
+
+// Match checks rule conditions against hit
+func (r *Rule) Match(hit *Hit) bool {
+	if r.Platform != "" && PlatformOf(hit.UserAgent) != r.Platform {
+		return false
+	}
+	if r.Language != "" {
+		lang := preferredLanguage(hit.AcceptLanguage)
+		if !strings.EqualFold(lang, r.Language) && !strings.HasPrefix(strings.ToLower(lang), strings.ToLower(r.Language)+"-") {
+			return false
+		}
+	}
+	if r.CIDR != "" {
+		_, network, err := net.ParseCIDR(r.CIDR)
+		ip := net.ParseIP(hit.ClientIP)
+		if err != nil || ip == nil || !network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// preferredLanguage returns language tag of Accept-Language header with the highest quality, "" - any
+func preferredLanguage(header string) string {
+	type tag struct {
+		lang string
+		q    float64
+	}
+	tags := make([]tag, 0)
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		t := tag{lang: strings.TrimSpace(fields[0]), q: 1}
+		for _, param := range fields[1:] {
+			if v := strings.TrimSpace(param); strings.HasPrefix(v, "q=") {
+				if q, err := strconv.ParseFloat(v[2:], 64); err == nil {
+					t.q = q
+				}
+			}
+		}
+		if t.lang != "" && t.lang != "*" && t.q > 0 {
+			tags = append(tags, t)
+		}
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	return tags[0].lang
+}
+
+// matchRule returns 1-based index of the first rule matching hit, 0 - none
+func matchRule(rules []Rule, hit *Hit) int {
+	if hit == nil {
+		return 0
+	}
+	for i := range rules {
+		if rules[i].Match(hit) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func validateRules(rules []Rule) error {
+	if len(rules) > MaxRules {
+		return fmt.Errorf("[%d] rules exceed limit [%d]: %w", len(rules), MaxRules, ErrInvalidRule)
+	}
+	for i := range rules {
+		if err := rules[i].Validate(); err != nil {
+			return fmt.Errorf("rule [%d]: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// EncodeRules returns rules as json kept by stores, "" - no rules
+func EncodeRules(rules []Rule) string {
+	if len(rules) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(rules)
+	return string(data)
+}
+
+// DecodeRules parses rules encoded by EncodeRules, "" - nil
+func DecodeRules(data string) ([]Rule, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var rules []Rule
+	if err := json.Unmarshal([]byte(data), &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
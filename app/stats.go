@@ -0,0 +1,44 @@
+package app
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrInvalidRange = errors.New("invalid range")
+
+const MaxStatsBuckets = 24 * 62
+
+type Granularity string
+
+const (
+	GranularityHour Granularity = "hour"
+	GranularityDay  Granularity = "day"
+)
+
+// Granularities are aggregated by hit stores on every hit
+var Granularities = []Granularity{GranularityHour, GranularityDay}
+
+// UniquesGranularity is time bucket size of unique visitors sketches (they are too heavy to keep hourly)
+const UniquesGranularity = GranularityDay
+
+func (g Granularity) Duration() time.Duration {
+	switch g {
+	case GranularityHour:
+		return time.Hour
+	case GranularityDay:
+		return 24 * time.Hour
+	}
+	return 0
+}
+
+// Truncate returns start (in UTC) of time bucket t belongs to
+func (g Granularity) Truncate(t time.Time) time.Time {
+	return t.UTC().Truncate(g.Duration())
+}
+
+// StatsBucket is number of hits in [Start, Start + granularity)
+type StatsBucket struct {
+	Start time.Time
+	Hits  int
+}
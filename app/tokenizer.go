@@ -9,4 +9,6 @@ var ErrInvalidToken = errors.New("invalid token")
 type Tokenizer interface {
 	Decode(key string) (int, error)
 	Encode(id int) (string, error)
+	// Alphabet returns set of symbols allowed in keys
+	Alphabet() string
 }
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"github.com/nj-eka/shurl/config"
+	"net/url"
+)
+
+// Utm is set of utm params added to target url on redirect, empty params aren't added
+type Utm struct {
+	Source   string
+	Medium   string
+	Campaign string
+	Term     string
+	Content  string
+}
+
+// WithUtm sets default utm params of links: they are added to target urls unless links or target urls have their own ones
+func WithUtm(cfg config.UtmConfig) Option {
+	return func(a *App) {
+		a.utm = Utm{Source: cfg.Source, Medium: cfg.Medium, Campaign: cfg.Campaign, Term: cfg.Term, Content: cfg.Content}
+	}
+}
+
+func (u *Utm) IsZero() bool {
+	return u == nil || *u == Utm{}
+}
+
+func (u *Utm) params() [][2]string {
+	return [][2]string{
+		{"utm_source", u.Source},
+		{"utm_medium", u.Medium},
+		{"utm_campaign", u.Campaign},
+		{"utm_term", u.Term},
+		{"utm_content", u.Content},
+	}
+}
+
+// Encode returns utm params as url query ("" for nil), it's how link stores keep them as string
+func (u *Utm) Encode() string {
+	if u.IsZero() {
+		return ""
+	}
+	values := make(url.Values)
+	for _, p := range u.params() {
+		if p[1] != "" {
+			values.Set(p[0], p[1])
+		}
+	}
+	return values.Encode()
+}
+
+// DecodeUtm returns utm params of url query made by Encode, nil - query is empty
+func DecodeUtm(query string) (*Utm, error) {
+	if query == "" {
+		return nil, nil
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return &Utm{
+		Source:   values.Get("utm_source"),
+		Medium:   values.Get("utm_medium"),
+		Campaign: values.Get("utm_campaign"),
+		Term:     values.Get("utm_term"),
+		Content:  values.Get("utm_content"),
+	}, nil
+}
+
+// applyUtm sets utm params of link to raw target query and adds default ones target query doesn't have
+func applyUtm(rawQuery string, utm *Utm, defaults Utm) string {
+	if utm.IsZero() && defaults.IsZero() {
+		return rawQuery
+	}
+	if utm == nil {
+		utm = &Utm{}
+	}
+	target, _ := url.ParseQuery(rawQuery)
+	changed := false
+	own := utm.params()
+	for i, p := range defaults.params() {
+		switch {
+		case own[i][1] != "":
+			target.Set(own[i][0], own[i][1])
+		case p[1] != "" && target.Get(p[0]) == "":
+			target.Set(p[0], p[1])
+		default:
+			continue
+		}
+		changed = true
+	}
+	if !changed {
+		return rawQuery
+	}
+	return target.Encode()
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/nj-eka/shurl/config"
+	cu "github.com/nj-eka/shurl/internal/contexts"
+	"github.com/nj-eka/shurl/store/bolt_store"
+	"github.com/nj-eka/shurl/utils/fsutils"
+	"io"
+	"net/http"
+	"os"
+)
+
+// backup saves snapshot of bolt store to file:
+// shurl backup --out <file> [--config <config>] [--url <server>/admin/backup [--token <admin token>]],
+// snapshot is taken from running server by url or directly from db file otherwise, it's validated before being saved
+func backup(args []string) error {
+	var configPath, outPath, url, token string
+	fs := flag.NewFlagSet(appName+" backup", flag.ExitOnError)
+	fs.StringVar(&configPath, "config", defaultAppConfigPath, "path to config file")
+	fs.StringVar(&outPath, "out", "", "path to snapshot file")
+	fs.StringVar(&url, "url", "", "url of admin backup endpoint of running server")
+	fs.StringVar(&token, "token", "", "admin token (default router.admin-token of config)")
+	_ = fs.Parse(args)
+	if outPath == "" {
+		fs.Usage()
+		return fmt.Errorf("invalid backup arguments")
+	}
+	ctx := cu.BuildContext(context.Background(), cu.SetContextOperation("0.backup"))
+	cfg, err := readConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := outPath + ".tmp"
+	out, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("creating snapshot file [%s] failed: %w", tmpPath, err)
+	}
+	defer func() {
+		_ = os.Remove(tmpPath)
+	}()
+	var n int64
+	if url != "" {
+		if token == "" && cfg.Router != nil {
+			token = cfg.Router.AdminToken
+		}
+		n, err = download(ctx, url, token, out)
+	} else {
+		var boltCfg *config.BoltStoreConfig
+		if boltCfg, err = boltConfig(cfg); err == nil {
+			n, err = bolt_store.BackupFile(ctx, *boltCfg, out)
+		}
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("taking snapshot failed: %w", err)
+	}
+	count, err := bolt_store.ValidateSnapshot(ctx, tmpPath)
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(tmpPath, outPath); err != nil {
+		return fmt.Errorf("saving snapshot file [%s] failed: %w", outPath, err)
+	}
+	fmt.Printf("snapshot of %d bytes with %d links is saved to %s\n", n, count, outPath)
+	return nil
+}
+
+// restore replaces db file of bolt store with validated snapshot, server is to be stopped:
+// shurl restore --in <file> [--config <config>]
+func restore(args []string) error {
+	var configPath, inPath string
+	fs := flag.NewFlagSet(appName+" restore", flag.ExitOnError)
+	fs.StringVar(&configPath, "config", defaultAppConfigPath, "path to config file")
+	fs.StringVar(&inPath, "in", "", "path to snapshot file")
+	_ = fs.Parse(args)
+	if inPath == "" {
+		fs.Usage()
+		return fmt.Errorf("invalid restore arguments")
+	}
+	ctx := cu.BuildContext(context.Background(), cu.SetContextOperation("0.restore"))
+	cfg, err := readConfig(configPath)
+	if err != nil {
+		return err
+	}
+	boltCfg, err := boltConfig(cfg)
+	if err != nil {
+		return err
+	}
+	count, err := bolt_store.Restore(ctx, *boltCfg, inPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d links are restored to %s (previous db is saved to %s.bak)\n", count, boltCfg.FilePath, boltCfg.FilePath)
+	return nil
+}
+
+// boltConfig returns bolt store config (with resolved path) if bolt is the store in use
+func boltConfig(cfg *config.AppConfig) (*config.BoltStoreConfig, error) {
+	s := cfg.Store
+	if s == nil || s.Bolt == nil || s.Postgres != nil || s.Redis != nil || s.Sqlite != nil || s.Mem != nil {
+		return nil, fmt.Errorf("backup is supported by bolt store only")
+	}
+	path, err := fsutils.ResolvePath(s.Bolt.FilePath, usr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bolt db path [%s]: %w", s.Bolt.FilePath, err)
+	}
+	boltCfg := *s.Bolt
+	boltCfg.FilePath = path
+	return &boltCfg, nil
+}
+
+func download(ctx context.Context, url, token string, w io.Writer) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("server responded with [%s]", resp.Status)
+	}
+	return io.Copy(w, resp.Body)
+}
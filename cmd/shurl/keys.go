@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/nj-eka/shurl/app"
+	cu "github.com/nj-eka/shurl/internal/contexts"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// apiKeys manages api keys kept in store, server doesn't need to be stopped for stores other than bolt:
+// shurl keys create --owner <owner> [--admin] [--config <config>]
+// shurl keys list [--config <config>]
+// shurl keys revoke --id <key id> [--config <config>]
+func apiKeys(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: %s keys create|list|revoke [OPTIONS]", appName)
+	}
+	var configPath, owner, id string
+	var admin bool
+	fs := flag.NewFlagSet(appName+" keys "+args[0], flag.ExitOnError)
+	fs.StringVar(&configPath, "config", defaultAppConfigPath, "path to config file")
+	switch args[0] {
+	case "create":
+		fs.StringVar(&owner, "owner", "", "owner of links created with key")
+		fs.BoolVar(&admin, "admin", false, "key of admin managing links of all owners")
+	case "revoke":
+		fs.StringVar(&id, "id", "", "id of key (part of key before dot)")
+	case "list":
+	default:
+		return fmt.Errorf("unknown keys command [%s]", args[0])
+	}
+	_ = fs.Parse(args[1:])
+	if (args[0] == "create" && owner == "") || (args[0] == "revoke" && id == "") {
+		fs.Usage()
+		return fmt.Errorf("invalid keys arguments")
+	}
+	ctx := cu.BuildContext(context.Background(), cu.SetContextOperation("0.keys"))
+	store, hits, err := loadMigrateStore(ctx, configPath)
+	if err != nil {
+		return err
+	}
+	defer closeStore(ctx, store, hits)
+	keys, ok := store.(app.KeyStore)
+	if !ok {
+		return fmt.Errorf("api keys aren't supported by store")
+	}
+	switch args[0] {
+	case "create":
+		token, key, err := app.IssueApiKey(ctx, keys, owner, admin)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("api key [%s] of [%s] is created, it's shown only once:\n%s\n", key.Id, key.Owner, token)
+	case "list":
+		list, err := keys.ListKeys(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tOWNER\tADMIN\tCREATED")
+		for _, key := range list {
+			fmt.Fprintf(w, "%s\t%s\t%t\t%s\n", key.Id, key.Owner, key.Admin, key.CreatedAt.Format(time.RFC3339))
+		}
+		return w.Flush()
+	case "revoke":
+		if err := keys.DeleteKey(ctx, id); err != nil {
+			return err
+		}
+		fmt.Printf("api key [%s] is revoked\n", id)
+	}
+	return nil
+}
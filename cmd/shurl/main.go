@@ -12,8 +12,6 @@ import (
 	cu "github.com/nj-eka/shurl/internal/contexts"
 	"github.com/nj-eka/shurl/internal/errs"
 	"github.com/nj-eka/shurl/internal/logging"
-	"github.com/nj-eka/shurl/store/bolt_store"
-	"github.com/nj-eka/shurl/store/mem_store"
 	"github.com/nj-eka/shurl/utils/fsutils"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
@@ -59,6 +57,13 @@ var (
 	currConfigSaveToPath string
 	usr                  *user.User
 	a                    *app.App
+	// commands run instead of server: shurl <command> [OPTIONS]
+	commands = map[string]func(args []string) error{
+		"migrate": migrate,
+		"backup":  backup,
+		"restore": restore,
+		"keys":    apiKeys,
+	}
 )
 
 func prepareConfig() {
@@ -85,7 +90,11 @@ func prepareConfig() {
 	//_ = viper.BindEnv("server.addr")
 	_ = viper.BindEnv("server.host")
 	_ = viper.BindEnv("server.port", "PORT")
+	_ = viper.BindEnv("router.admin-token")
+	_ = viper.BindEnv("router.api-keys")
 	_ = viper.BindEnv("store.bolt.path")
+	_ = viper.BindEnv("store.postgres.dsn", "DATABASE_URL") // heroku postgres addon
+	_ = viper.BindEnv("store.redis.url", "REDIS_URL")       // heroku redis addon
 	_ = viper.BindEnv("tokenizer.salt")
 	viper.AutomaticEnv()
 	if err = viper.ReadInConfig(); err != nil {
@@ -110,7 +119,7 @@ func prepareConfig() {
 }
 
 // app init, exit on error
-func init() {
+func initApp() {
 	fmt.Printf("short url generator has version %s built from %s on %s\n", app.Version, app.Commit, app.BuildTime)
 	prepareConfig()
 	// logging is initialized
@@ -147,32 +156,15 @@ func init() {
 		logging.LogError(ctx, errs.KindTokenizer, "no tokenizer config")
 		log.Exit(1)
 	}
-	var store app.LinkStore
-	if appCfg.Store != nil { // todo: add store loader
-		if appCfg.Store.Bolt != nil {
-			if appCfg.Store.Bolt.FilePath, err = fsutils.SafeParentResolvePath(appCfg.Store.Bolt.FilePath, usr, 0700); err == nil {
-				store, err = bolt_store.NewBoltLinkStore(ctx, *appCfg.Store.Bolt)
-			}
-			if err != nil {
-				logging.LogError(ctx, errs.KindStore, fmt.Errorf("init bolt store failed: %w", err))
-				log.Exit(1)
-			}
-		} else if appCfg.Store.Mem != nil {
-			if appCfg.Store.Mem.FilePath != "" {
-				if appCfg.Store.Mem.FilePath, err = fsutils.SafeParentResolvePath(appCfg.Store.Mem.FilePath, usr, 0700); err != nil {
-					logging.LogError(ctx, errs.KindStore, fmt.Errorf("init mem store failed: %w", err))
-					log.Exit(1)
-				}
-			}
-			if store, err = mem_store.NewMemStore(ctx, *appCfg.Store.Mem); err != nil {
-				logging.LogError(ctx, errs.KindStore, fmt.Errorf("init mem store failed: %w", err))
-				log.Exit(1)
-			}
-		}
-	} else {
+	if appCfg.Store == nil {
 		logging.LogError(ctx, errs.KindStore, "no store config")
 		log.Exit(1)
 	}
+	store, hits, backuper, keys, err := loadStore(ctx, appCfg.Store)
+	if err != nil {
+		logging.LogError(ctx, errs.KindStore, err)
+		log.Exit(1)
+	}
 	if currConfigSaveToPath != "" {
 		if err = viper.WriteConfigAs(currConfigSaveToPath); err != nil {
 			logging.LogError(ctx, errs.KindIO, fmt.Errorf("saving config to file [%s] failed: %w", currConfigSaveToPath, err))
@@ -180,10 +172,45 @@ func init() {
 		}
 	}
 
-	a = app.NewApp(store, tokenizer)
+	opts := []app.Option{app.WithHitStore(hits), app.WithBackuper(backuper)}
+	if appCfg.Reaper != nil {
+		reaper, err := app.NewReaper(store, hits, *appCfg.Reaper)
+		if err != nil {
+			logging.LogError(ctx, errs.KindInvalidValue, err)
+			log.Exit(1)
+		}
+		if err = checkRetention(appCfg.Store, reaper.Retention()); err != nil {
+			logging.LogError(ctx, errs.KindInvalidValue, err)
+			log.Exit(1)
+		}
+		reaper.Start(cu.BuildContext(context.Background(), cu.SetContextOperation("0.reaper")))
+		opts = append(opts, app.WithReaper(reaper))
+	}
+	if appCfg.Router.ApiKeys {
+		if keys == nil {
+			logging.LogError(ctx, errs.KindStore, "api keys aren't supported by store")
+			log.Exit(1)
+		}
+		opts = append(opts, app.WithKeyStore(keys))
+	}
+	if appCfg.Passwords != nil {
+		opts = append(opts, app.WithPasswordAttempts(*appCfg.Passwords))
+	}
+	if appCfg.Utm != nil {
+		opts = append(opts, app.WithUtm(*appCfg.Utm))
+	}
+	a = app.NewApp(store, tokenizer, opts...)
 }
 
 func main() {
+	if len(os.Args) > 1 && commands[os.Args[1]] != nil {
+		if err := commands[os.Args[1]](os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	initApp()
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	ctx = cu.BuildContext(ctx, cu.SetContextOperation("0.main"))
 	defer func() {
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/nj-eka/shurl/app"
+	"github.com/nj-eka/shurl/config"
+	cu "github.com/nj-eka/shurl/internal/contexts"
+	"github.com/nj-eka/shurl/internal/errs"
+	"github.com/spf13/viper"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const defaultMigrateBatch = 500
+
+// migrate copies all api keys (with their ids and hashes) and links (with their ids, times and hits) from one store to another:
+// shurl migrate --from <config> --to <config> [--progress <file>] [--batch <n>],
+// ids of the last copied link and key are saved to progress file after each batch so interrupted migration resumes from them
+func migrate(args []string) error {
+	var (
+		fromPath, toPath, progressPath string
+		batch                          int
+	)
+	fs := flag.NewFlagSet(appName+" migrate", flag.ExitOnError)
+	fs.StringVar(&fromPath, "from", "", "path to config file of source store")
+	fs.StringVar(&toPath, "to", "", "path to config file of destination store")
+	fs.StringVar(&progressPath, "progress", "", "path to progress file (default <to>.migrate)")
+	fs.IntVar(&batch, "batch", defaultMigrateBatch, "number of links (keys) copied at once")
+	_ = fs.Parse(args)
+	if fromPath == "" || toPath == "" || batch <= 0 {
+		fs.Usage()
+		return fmt.Errorf("invalid migrate arguments")
+	}
+	if progressPath == "" {
+		progressPath = toPath + ".migrate"
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	ctx = cu.BuildContext(ctx, cu.SetContextOperation("0.migrate"))
+
+	from, fromHits, err := loadMigrateStore(ctx, fromPath)
+	if err != nil {
+		return fmt.Errorf("opening source store failed: %w", err)
+	}
+	defer closeStore(ctx, from, fromHits)
+	to, toHits, err := loadMigrateStore(ctx, toPath)
+	if err != nil {
+		return fmt.Errorf("opening destination store failed: %w", err)
+	}
+	defer closeStore(ctx, to, toHits)
+
+	lastId, lastKeyId, err := readProgress(progressPath)
+	if err != nil {
+		return err
+	}
+	if lastId > 0 || lastKeyId != "" {
+		fmt.Printf("resuming migration after link id %d and key id [%s]\n", lastId, lastKeyId)
+	}
+	// keys go first, so that links don't get to destination with owners no key maps to
+	fromKeys, toKeys, err := keyStores(from, to)
+	if err != nil {
+		return err
+	}
+	if fromKeys != nil {
+		if lastKeyId, err = copyKeys(ctx, fromKeys, toKeys, lastKeyId, batch, func(keyId string) error {
+			return writeProgress(progressPath, lastId, keyId)
+		}); err != nil {
+			return err
+		}
+	}
+	copied := 0
+	for {
+		links, err := from.List(ctx, app.ListQuery{SortBy: app.SortById, After: &app.ListCursor{Id: lastId}, Limit: batch})
+		if err != nil {
+			return fmt.Errorf("listing source links after id [%d] failed: %w", lastId, err)
+		}
+		if len(links) == 0 {
+			break
+		}
+		for _, link := range links {
+			if err := to.Import(ctx, link); err != nil {
+				return fmt.Errorf("importing link with id [%d] failed: %w", link.Id, err)
+			}
+		}
+		lastId = links[len(links)-1].Id
+		copied += len(links)
+		if err := writeProgress(progressPath, lastId, lastKeyId); err != nil {
+			return err
+		}
+		fmt.Printf("copied %d links (last id %d)\n", copied, lastId)
+	}
+
+	fromCount, err := countLinks(ctx, from)
+	if err != nil {
+		return fmt.Errorf("counting source links failed: %w", err)
+	}
+	toCount, err := countLinks(ctx, to)
+	if err != nil {
+		return fmt.Errorf("counting destination links failed: %w", err)
+	}
+	if fromCount != toCount {
+		return fmt.Errorf("verification failed: source has %d links, destination has %d", fromCount, toCount)
+	}
+	keysCount := 0
+	if fromKeys != nil {
+		fromList, err := fromKeys.ListKeys(ctx)
+		if err != nil {
+			return fmt.Errorf("counting source keys failed: %w", err)
+		}
+		toList, err := toKeys.ListKeys(ctx)
+		if err != nil {
+			return fmt.Errorf("counting destination keys failed: %w", err)
+		}
+		if len(fromList) != len(toList) {
+			return fmt.Errorf("verification failed: source has %d keys, destination has %d", len(fromList), len(toList))
+		}
+		keysCount = len(toList)
+	}
+	if err := os.Remove(progressPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing progress file [%s] failed: %w", progressPath, err)
+	}
+	fmt.Printf("migration completed: %d links and %d keys verified\n", toCount, keysCount)
+	return nil
+}
+
+// keyStores returns key stores of source and destination, nil - source keeps no keys
+func keyStores(from, to app.LinkStore) (app.KeyStore, app.KeyStore, error) {
+	fromKeys, ok := from.(app.KeyStore)
+	if !ok {
+		return nil, nil, nil
+	}
+	toKeys, ok := to.(app.KeyStore)
+	if !ok {
+		return nil, nil, fmt.Errorf("api keys of source store aren't supported by destination store")
+	}
+	return fromKeys, toKeys, nil
+}
+
+// copyKeys copies keys with ids greater than lastKeyId in id order, saving progress after each batch,
+// it returns id of the last copied key
+func copyKeys(ctx context.Context, from, to app.KeyStore, lastKeyId string, batch int, save func(keyId string) error) (string, error) {
+	keys, err := from.ListKeys(ctx)
+	if err != nil {
+		return lastKeyId, fmt.Errorf("listing source keys failed: %w", err)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Id < keys[j].Id })
+	copied := 0
+	for _, key := range keys {
+		if key.Id <= lastKeyId {
+			continue
+		}
+		if err := to.AddKey(ctx, key); err != nil {
+			// key may be copied just before interruption (ahead of saving progress)
+			if !errors.Is(err, app.ErrConflict) {
+				return lastKeyId, fmt.Errorf("adding key [%s] failed: %w", key.Id, err)
+			}
+			if existing, err := to.GetKey(ctx, key.Id); err != nil || existing.Hash != key.Hash {
+				return lastKeyId, fmt.Errorf("adding key [%s] failed: another key with the same id exists in destination", key.Id)
+			}
+		}
+		lastKeyId = key.Id
+		if copied++; copied%batch == 0 {
+			if err := save(lastKeyId); err != nil {
+				return lastKeyId, err
+			}
+		}
+	}
+	if copied > 0 {
+		if err := save(lastKeyId); err != nil {
+			return lastKeyId, err
+		}
+		fmt.Printf("copied %d keys (last id %s)\n", copied, lastKeyId)
+	}
+	return lastKeyId, nil
+}
+
+// loadMigrateStore opens store described by store section of config file without cache and batch decorators
+func loadMigrateStore(ctx context.Context, configPath string) (app.LinkStore, app.HitStore, error) {
+	cfg, err := readConfig(configPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.Store == nil {
+		return nil, nil, fmt.Errorf("no store config in [%s]", configPath)
+	}
+	cfg.Store.Batch, cfg.Store.Cache = nil, nil
+	store, hits, _, _, err := loadStore(ctx, cfg.Store)
+	return store, hits, err
+}
+
+// readConfig reads config file (with no env overrides) apart from app config
+func readConfig(configPath string) (*config.AppConfig, error) {
+	v := viper.New()
+	v.SetConfigFile(configPath)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("reading config [%s] failed: %w", configPath, err)
+	}
+	var cfg config.AppConfig
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid config [%s]: %w", configPath, err)
+	}
+	return &cfg, nil
+}
+
+func closeStore(ctx context.Context, store app.LinkStore, hits app.HitStore) {
+	if hits != nil {
+		if err := hits.Close(ctx); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	if err := store.Close(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// readProgress returns ids of the last copied link and key (line by line in progress file),
+// 0 and "" - if there is no progress file (or no key line in file of previous versions)
+func readProgress(path string) (int, string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, "", nil
+		}
+		return 0, "", fmt.Errorf("reading progress file [%s] failed: %w", path, err)
+	}
+	lines := strings.SplitN(strings.TrimSpace(string(data)), "\n", 2)
+	id, err := strconv.Atoi(strings.TrimSpace(lines[0]))
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid progress file [%s]: %w", path, err)
+	}
+	if len(lines) == 1 {
+		return id, "", nil
+	}
+	return id, strings.TrimSpace(lines[1]), nil
+}
+
+func writeProgress(path string, lastId int, lastKeyId string) error {
+	if err := ioutil.WriteFile(path, []byte(strconv.Itoa(lastId)+"\n"+lastKeyId), 0600); err != nil {
+		return fmt.Errorf("saving progress to file [%s] failed: %w", path, err)
+	}
+	return nil
+}
+
+func countLinks(ctx context.Context, store app.LinkStore) (int, errs.Error) {
+	links, err := store.List(ctx, app.ListQuery{SortBy: app.SortById})
+	if err != nil {
+		return 0, err
+	}
+	return len(links), nil
+}
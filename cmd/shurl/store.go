@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/nj-eka/shurl/app"
+	"github.com/nj-eka/shurl/config"
+	"github.com/nj-eka/shurl/store/batch_store"
+	"github.com/nj-eka/shurl/store/bolt_store"
+	"github.com/nj-eka/shurl/store/cache_store"
+	"github.com/nj-eka/shurl/store/mem_store"
+	"github.com/nj-eka/shurl/store/postgres_store"
+	"github.com/nj-eka/shurl/store/redis_store"
+	"github.com/nj-eka/shurl/store/sqlite_store"
+	"github.com/nj-eka/shurl/utils/fsutils"
+	"time"
+)
+
+// loadStore opens link store (and hit store, backuper and key store if they are supported) wrapped with configured decorators,
+// explicitly configured stores take precedence over default bolt one
+func loadStore(ctx context.Context, cfg *config.StoreConfig) (store app.LinkStore, hits app.HitStore, backuper app.Backuper, keys app.KeyStore, err error) {
+	switch {
+	case cfg.Postgres != nil:
+		if store, err = postgres_store.NewPostgresLinkStore(ctx, *cfg.Postgres); err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("init postgres store failed: %w", err)
+		}
+	case cfg.Redis != nil:
+		if store, err = redis_store.NewRedisLinkStore(ctx, *cfg.Redis); err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("init redis store failed: %w", err)
+		}
+	case cfg.Sqlite != nil:
+		if cfg.Sqlite.FilePath, err = fsutils.SafeParentResolvePath(cfg.Sqlite.FilePath, usr, 0700); err == nil {
+			store, err = sqlite_store.NewSqliteLinkStore(ctx, *cfg.Sqlite)
+		}
+		if err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("init sqlite store failed: %w", err)
+		}
+	case cfg.Mem != nil:
+		if cfg.Mem.FilePath != "" {
+			if cfg.Mem.FilePath, err = fsutils.SafeParentResolvePath(cfg.Mem.FilePath, usr, 0700); err != nil {
+				return nil, nil, nil, nil, fmt.Errorf("init mem store failed: %w", err)
+			}
+		}
+		if cfg.Mem.HitsFilePath != "" {
+			if cfg.Mem.HitsFilePath, err = fsutils.SafeParentResolvePath(cfg.Mem.HitsFilePath, usr, 0700); err != nil {
+				return nil, nil, nil, nil, fmt.Errorf("init mem hit store failed: %w", err)
+			}
+		}
+		if store, err = mem_store.NewMemStore(ctx, *cfg.Mem); err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("init mem store failed: %w", err)
+		}
+		if hits, err = mem_store.NewMemHitStore(ctx, *cfg.Mem); err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("init mem hit store failed: %w", err)
+		}
+	case cfg.Bolt != nil:
+		if cfg.Bolt.FilePath, err = fsutils.SafeParentResolvePath(cfg.Bolt.FilePath, usr, 0700); err == nil {
+			if store, err = bolt_store.NewBoltLinkStore(ctx, *cfg.Bolt); err == nil {
+				hits, err = bolt_store.NewBoltHitStore(ctx, store)
+			}
+		}
+		if err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("init bolt store failed: %w", err)
+		}
+	default:
+		return nil, nil, nil, nil, fmt.Errorf("no store config")
+	}
+	backuper, _ = store.(app.Backuper)
+	keys, _ = store.(app.KeyStore)
+	// cache is wrapped by batch for hits to be read from cache
+	if cfg.Cache != nil {
+		if store, err = cache_store.NewCacheLinkStore(ctx, store, *cfg.Cache); err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("init cache store failed: %w", err)
+		}
+	}
+	if cfg.Batch != nil {
+		if store, err = batch_store.NewBatchLinkStore(ctx, store, *cfg.Batch); err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("init batch store failed: %w", err)
+		}
+		if hits != nil {
+			if hits, err = batch_store.NewBatchHitStore(ctx, store, hits); err != nil {
+				return nil, nil, nil, nil, fmt.Errorf("init batch hit store failed: %w", err)
+			}
+		}
+	}
+	return store, hits, backuper, keys, nil
+}
+
+// checkRetention checks that store keeps expired links for reaper retention at least:
+// redis removes them natively, so they must outlive retention to be reaped (and restored) as in other stores
+func checkRetention(cfg *config.StoreConfig, retention time.Duration) error {
+	if cfg.Redis != nil && cfg.Postgres == nil {
+		if delay := redis_store.ExpireDelay(*cfg.Redis); delay < retention {
+			return fmt.Errorf("redis expire delay [%v] is less than reaper retention [%v]", delay, retention)
+		}
+	}
+	return nil
+}
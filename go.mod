@@ -4,14 +4,25 @@ module github.com/nj-eka/shurl
 go 1.16
 
 require (
+	github.com/alicebob/miniredis/v2 v2.16.0
 	github.com/asdine/storm/v3 v3.2.1
 	github.com/deepmap/oapi-codegen v1.8.2
+	github.com/fergusstrange/embedded-postgres v1.12.0
 	github.com/getkin/kin-openapi v0.61.0
 	github.com/go-chi/chi v1.5.4
 	github.com/go-chi/chi/v5 v5.0.0
+	github.com/go-redis/redis/v8 v8.11.4
+	github.com/jackc/pgconn v1.10.0
+	github.com/jackc/pgerrcode v0.0.0-20201024163028-a0d42d470451
+	github.com/jackc/pgx/v4 v4.13.0
 	github.com/joho/godotenv v1.3.0
+	github.com/kr/text v0.2.0 // indirect
+	github.com/lib/pq v1.10.9 // indirect
 	github.com/sirupsen/logrus v1.8.1
 	github.com/speps/go-hashids v2.0.0+incompatible
 	github.com/spf13/viper v1.9.0
+	github.com/stretchr/testify v1.10.0 // indirect
 	go.etcd.io/bbolt v1.3.6
+	golang.org/x/crypto v0.0.0-20210817164053-32db794688a5
+	modernc.org/sqlite v1.14.6
 )
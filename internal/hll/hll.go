@@ -0,0 +1,123 @@
+// Package hll implements HyperLogLog cardinality estimator (Flajolet et al.) with linear counting for small cardinalities
+package hll
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+)
+
+var ErrInvalidData = errors.New("invalid sketch data")
+
+// Precision defines number of registers m = 2^Precision, standard error is about 1.04/sqrt(m) = 1.6%
+const Precision = 12
+
+const (
+	m = 1 << Precision
+
+	formatDense  byte = 0
+	formatSparse byte = 1
+)
+
+// Sketch is HyperLogLog registers set, zero value is not usable, use New
+type Sketch struct {
+	registers []uint8
+}
+
+func New() *Sketch {
+	return &Sketch{registers: make([]uint8, m)}
+}
+
+// Add adds element by its 64 bit hash (hash is additionally mixed, so fnv and alike are fine)
+func (s *Sketch) Add(hash uint64) {
+	x := mix(hash)
+	idx := x >> (64 - Precision)
+	rank := uint8(bits.LeadingZeros64(x<<Precision|1<<(Precision-1))) + 1
+	if rank > s.registers[idx] {
+		s.registers[idx] = rank
+	}
+}
+
+// Merge makes sketch to be estimator of union of both sets
+func (s *Sketch) Merge(other *Sketch) {
+	for i, r := range other.registers {
+		if r > s.registers[i] {
+			s.registers[i] = r
+		}
+	}
+}
+
+func (s *Sketch) Count() uint64 {
+	sum, zeros := 0.0, 0
+	for _, r := range s.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(float64(m)/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+// MarshalBinary encodes registers as [index (2 bytes), value] pairs when most of them are empty, as is otherwise
+func (s *Sketch) MarshalBinary() ([]byte, error) {
+	used := 0
+	for _, r := range s.registers {
+		if r != 0 {
+			used++
+		}
+	}
+	if 3*used >= m {
+		return append([]byte{formatDense}, s.registers...), nil
+	}
+	data := make([]byte, 1, 1+3*used)
+	data[0] = formatSparse
+	for i, r := range s.registers {
+		if r != 0 {
+			data = append(data, byte(i>>8), byte(i), r)
+		}
+	}
+	return data, nil
+}
+
+func (s *Sketch) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return ErrInvalidData
+	}
+	registers := make([]uint8, m)
+	switch data[0] {
+	case formatDense:
+		if len(data) != 1+m {
+			return ErrInvalidData
+		}
+		copy(registers, data[1:])
+	case formatSparse:
+		if (len(data)-1)%3 != 0 {
+			return ErrInvalidData
+		}
+		for i := 1; i < len(data); i += 3 {
+			idx := int(data[i])<<8 | int(data[i+1])
+			if idx >= m {
+				return ErrInvalidData
+			}
+			registers[idx] = data[i+2]
+		}
+	default:
+		return ErrInvalidData
+	}
+	s.registers = registers
+	return nil
+}
+
+// mix is murmur3 finalizer spreading input bits over the whole hash
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
@@ -0,0 +1,82 @@
+package hll
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSketch_Count(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+	}{
+		{"empty", 0},
+		{"one", 1},
+		{"hundred", 100},
+		{"ten thousands", 10000},
+		{"million", 1000000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			for i := 0; i < tt.n; i++ {
+				s.Add(uint64(i))
+				s.Add(uint64(i)) // duplicates don't count
+			}
+			got := s.Count()
+			if math.Abs(float64(got)-float64(tt.n)) > 0.05*float64(tt.n) {
+				t.Errorf("Count() = %v, want %v (5%%)", got, tt.n)
+			}
+		})
+	}
+}
+
+func TestSketch_Merge(t *testing.T) {
+	s1, s2 := New(), New()
+	for i := 0; i < 3000; i++ {
+		s1.Add(uint64(i))
+	}
+	for i := 2000; i < 5000; i++ {
+		s2.Add(uint64(i))
+	}
+	s1.Merge(s2)
+	if got := s1.Count(); math.Abs(float64(got)-5000) > 250 {
+		t.Errorf("Merge() count = %v, want %v", got, 5000)
+	}
+}
+
+func TestSketch_MarshalBinary(t *testing.T) {
+	tests := []struct {
+		name       string
+		n          int
+		wantFormat byte
+	}{
+		{"sparse", 100, formatSparse},
+		{"dense", 100000, formatDense},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			for i := 0; i < tt.n; i++ {
+				s.Add(uint64(i))
+			}
+			data, err := s.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data[0] != tt.wantFormat {
+				t.Errorf("MarshalBinary() format = %v, want %v", data[0], tt.wantFormat)
+			}
+			s2 := New()
+			if err := s2.UnmarshalBinary(data); err != nil {
+				t.Fatal(err)
+			}
+			if s.Count() != s2.Count() {
+				t.Errorf("UnmarshalBinary() count = %v, want %v", s2.Count(), s.Count())
+			}
+		})
+	}
+	if err := New().UnmarshalBinary([]byte{formatSparse, 0xff, 0xff, 1}); err != ErrInvalidData {
+		t.Errorf("UnmarshalBinary() err = %v, want %v", err, ErrInvalidData)
+	}
+}
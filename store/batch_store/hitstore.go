@@ -0,0 +1,123 @@
+package batch_store
+
+import (
+	"context"
+	"fmt"
+	"github.com/nj-eka/shurl/app"
+	cu "github.com/nj-eka/shurl/internal/contexts"
+	"github.com/nj-eka/shurl/internal/errs"
+	"github.com/nj-eka/shurl/internal/logging"
+	"sync"
+	"time"
+)
+
+var _ app.HitStore = &batchHitStore{}
+
+// batchHitStore is write-behind decorator of hit store: hits are buffered in memory
+// and added to underlying store in flushes of batch link store (at once if it is app.HitBatcher)
+type batchHitStore struct {
+	app.HitStore
+	links *batchLinkStore
+	mu    sync.Mutex
+	hits  []*app.Hit
+}
+
+// NewBatchHitStore returns hit store flushed along with given batch link store
+func NewBatchHitStore(ctx context.Context, store app.LinkStore, hits app.HitStore) (app.HitStore, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("batch.InitHits"), errs.SetDefaultErrsKind(errs.KindStore))
+	bls, ok := store.(*batchLinkStore)
+	if !ok {
+		return nil, errs.E(ctx, fmt.Errorf("batch hit store requires batch link store, got [%T]", store))
+	}
+	bh := &batchHitStore{HitStore: hits, links: bls}
+	bls.mu.Lock()
+	bls.hits = bh
+	bls.mu.Unlock()
+	return bh, nil
+}
+
+// AddHit buffers hit, flush is requested as soon as buffered hits reach batch size
+func (bh *batchHitStore) AddHit(ctx context.Context, hit *app.Hit) errs.Error {
+	h := *hit
+	bh.mu.Lock()
+	bh.hits = append(bh.hits, &h)
+	full := len(bh.hits) >= bh.links.size
+	bh.mu.Unlock()
+	if full {
+		bh.links.requestFlush()
+	}
+	return nil
+}
+
+// flush adds buffered hits to underlying store, hits are kept buffered on failure
+func (bh *batchHitStore) flush(ctx context.Context) errs.Error {
+	bh.mu.Lock()
+	hits := bh.hits
+	bh.hits = nil
+	bh.mu.Unlock()
+	if len(hits) == 0 {
+		return nil
+	}
+	var err errs.Error
+	if batcher, ok := bh.HitStore.(app.HitBatcher); ok {
+		err = batcher.AddHits(ctx, hits)
+	} else {
+		for i, hit := range hits {
+			if err = bh.HitStore.AddHit(ctx, hit); err != nil {
+				hits = hits[i:]
+				break
+			}
+		}
+	}
+	if err != nil {
+		bh.mu.Lock()
+		bh.hits = append(hits, bh.hits...)
+		bh.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// flushed flushes buffered hits before they are read
+func (bh *batchHitStore) flushed(ctx context.Context) {
+	if err := bh.flush(ctx); err != nil {
+		logging.LogError(ctx, err)
+	}
+}
+
+func (bh *batchHitStore) ListHits(ctx context.Context, linkId int, from, to time.Time, limit int) ([]*app.Hit, errs.Error) {
+	bh.flushed(ctx)
+	return bh.HitStore.ListHits(ctx, linkId, from, to, limit)
+}
+
+func (bh *batchHitStore) Stats(ctx context.Context, linkId int, g app.Granularity, from, to time.Time) ([]*app.StatsBucket, errs.Error) {
+	bh.flushed(ctx)
+	return bh.HitStore.Stats(ctx, linkId, g, from, to)
+}
+
+func (bh *batchHitStore) Uniques(ctx context.Context, linkId int, from, to time.Time) (int, errs.Error) {
+	bh.flushed(ctx)
+	return bh.HitStore.Uniques(ctx, linkId, from, to)
+}
+
+// Purge flushes buffered hits first for hits of link not to be left behind
+func (bh *batchHitStore) Purge(ctx context.Context, linkId int) (int, errs.Error) {
+	bh.flushed(ctx)
+	return bh.HitStore.Purge(ctx, linkId)
+}
+
+// Close flushes buffered hits, detaches from batch link store and closes underlying store
+func (bh *batchHitStore) Close(ctx context.Context) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("batch.CloseHits"), errs.SetDefaultErrsKind(errs.KindStore))
+	bh.links.mu.Lock()
+	bh.links.hits = nil
+	bh.links.mu.Unlock()
+	if err := bh.flush(ctx); err != nil {
+		logging.LogError(ctx, errs.SeverityCritical, err)
+		if err2 := bh.HitStore.Close(ctx); err2 != nil {
+			logging.LogError(ctx, err2)
+		}
+		return errs.E(ctx, errs.SeverityCritical, fmt.Errorf("flushing hit events on close failed: %w", err))
+	}
+	return bh.HitStore.Close(ctx)
+}
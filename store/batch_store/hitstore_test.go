@@ -0,0 +1,75 @@
+package batch_store
+
+import (
+	"context"
+	"github.com/nj-eka/shurl/app"
+	"github.com/nj-eka/shurl/config"
+	"github.com/nj-eka/shurl/store/bolt_store"
+	"testing"
+	"time"
+)
+
+func Test_batchHitStore(t *testing.T) {
+	ctx := context.Background()
+	id, _, err := store.Create(ctx, &app.Link{TargetUrl: "https://go.dev/hits"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits, err := bolt_store.NewBoltHitStore(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewBatchHitStore(ctx, store, hits); err == nil {
+		t.Error("NewBatchHitStore() of not batch link store gotErr = <nil>")
+	}
+	bs, err := NewBatchLinkStore(ctx, nopCloser{store}, config.BatchStoreConfig{Interval: time.Hour, Size: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = bs.Close(ctx)
+	}()
+	bh, err := NewBatchHitStore(ctx, bs, hits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	stored := func() int {
+		got, err := hits.ListHits(ctx, id, now.Add(-time.Hour), now.Add(time.Hour), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(got)
+	}
+	addHits := func(n int) {
+		for i := 0; i < n; i++ {
+			if err := bh.AddHit(ctx, &app.Hit{LinkId: id, Time: now, ClientIP: "127.0.0.1"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	addHits(2)
+	if got := stored(); got != 0 {
+		t.Errorf("AddHit() stored %d hits before flush, want 0", got)
+	}
+	if got, err := bh.ListHits(ctx, id, now.Add(-time.Hour), now.Add(time.Hour), 0); err != nil || len(got) != 2 {
+		t.Errorf("ListHits() got %d hits, %v, want 2 flushed before read", len(got), err)
+	}
+	addHits(3)
+	for deadline := time.Now().Add(time.Second); stored() != 5 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	if got := stored(); got != 5 {
+		t.Errorf("AddHit() stored %d hits after flush by size, want 5", got)
+	}
+	addHits(1)
+	if err := bh.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := stored(); got != 6 {
+		t.Errorf("Close() stored %d hits, want 6", got)
+	}
+	if got, err := hits.Stats(ctx, id, app.Granularities[0], now.Add(-time.Hour), now.Add(time.Hour)); err != nil || len(got) != 1 || got[0].Hits != 6 {
+		t.Errorf("Stats() got = %v, %v, want single bucket of 6 hits", got, err)
+	}
+}
@@ -0,0 +1,195 @@
+package batch_store
+
+import (
+	"context"
+	"fmt"
+	"github.com/nj-eka/shurl/app"
+	"github.com/nj-eka/shurl/config"
+	cu "github.com/nj-eka/shurl/internal/contexts"
+	"github.com/nj-eka/shurl/internal/errs"
+	"github.com/nj-eka/shurl/internal/logging"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultInterval = time.Second
+	DefaultSize     = 1000
+)
+
+var _ app.LinkStore = &batchLinkStore{}
+
+// batchLinkStore is write-behind decorator of link store:
+// hits are accumulated in memory and added to underlying store in batches (see LinkStore.AddHits)
+// along with hit events buffered by batch hit store (see NewBatchHitStore)
+type batchLinkStore struct {
+	app.LinkStore
+	size int
+	// flushing guards reading link with its pending hits from being interleaved with flush
+	flushing  sync.RWMutex
+	mu        sync.Mutex
+	deltas    map[int]int
+	pending   int
+	hits      *batchHitStore
+	flushReq  chan struct{}
+	done      chan struct{}
+	completed chan struct{}
+}
+
+func NewBatchLinkStore(ctx context.Context, store app.LinkStore, cfg config.BatchStoreConfig) (app.LinkStore, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("batch.Init"), errs.SetDefaultErrsKind(errs.KindStore))
+	if cfg.Interval < 0 || cfg.Size < 0 {
+		return nil, errs.E(ctx, errs.KindInvalidValue, fmt.Errorf("invalid batch config: interval [%v] size [%d]", cfg.Interval, cfg.Size))
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.Size == 0 {
+		cfg.Size = DefaultSize
+	}
+	b := &batchLinkStore{
+		LinkStore: store,
+		size:      cfg.Size,
+		deltas:    make(map[int]int),
+		flushReq:  make(chan struct{}, 1),
+		done:      make(chan struct{}),
+		completed: make(chan struct{}),
+	}
+	go b.run(cu.BuildContext(ctx, cu.SetContextOperation("batch.Flush")), cfg.Interval)
+	return b, nil
+}
+
+func (b *batchLinkStore) run(ctx context.Context, interval time.Duration) {
+	defer close(b.completed)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-ticker.C:
+		case <-b.flushReq:
+		}
+		if err := b.flush(ctx); err != nil {
+			logging.LogError(ctx, err)
+		}
+	}
+}
+
+// flush adds accumulated hits to underlying store and buffered hit events to hit store
+func (b *batchLinkStore) flush(ctx context.Context) errs.Error {
+	err := b.flushDeltas(ctx)
+	b.mu.Lock()
+	hits := b.hits
+	b.mu.Unlock()
+	if hits != nil {
+		if herr := hits.flush(ctx); herr != nil {
+			if err == nil {
+				return herr
+			}
+			logging.LogError(ctx, herr)
+		}
+	}
+	return err
+}
+
+// flushDeltas adds accumulated hits to underlying store, hits are kept pending on failure
+func (b *batchLinkStore) flushDeltas(ctx context.Context) errs.Error {
+	b.flushing.Lock()
+	defer b.flushing.Unlock()
+	b.mu.Lock()
+	deltas := b.deltas
+	b.deltas, b.pending = make(map[int]int, len(deltas)), 0
+	b.mu.Unlock()
+	if len(deltas) == 0 {
+		return nil
+	}
+	if err := b.LinkStore.AddHits(ctx, deltas); err != nil {
+		b.mu.Lock()
+		for id, delta := range deltas {
+			b.deltas[id] += delta
+			b.pending += delta
+		}
+		b.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Get returns link with pending hits counted
+func (b *batchLinkStore) Get(ctx context.Context, id int) (*app.Link, errs.Error) {
+	b.flushing.RLock()
+	defer b.flushing.RUnlock()
+	link, err := b.LinkStore.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	link.Hits += b.deltas[id]
+	b.mu.Unlock()
+	return link, nil
+}
+
+// List flushes pending hits first for links to be sorted by actual hits
+func (b *batchLinkStore) List(ctx context.Context, q app.ListQuery) ([]*app.Link, errs.Error) {
+	if err := b.flush(ctx); err != nil {
+		logging.LogError(ctx, err)
+	}
+	return b.LinkStore.List(ctx, q)
+}
+
+// Hit reads link from underlying store and adds hit to pending ones,
+// hits of links limited by max hits are passed to underlying store to be checked against the limit atomically
+func (b *batchLinkStore) Hit(ctx context.Context, id int) (*app.Link, errs.Error) {
+	b.flushing.RLock()
+	defer b.flushing.RUnlock()
+	link, err := b.LinkStore.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if link.MaxHits > 0 {
+		return b.LinkStore.Hit(ctx, id)
+	}
+	b.mu.Lock()
+	b.deltas[id]++
+	b.pending++
+	link.Hits += b.deltas[id]
+	full := b.pending >= b.size
+	b.mu.Unlock()
+	if full {
+		b.requestFlush()
+	}
+	return link, nil
+}
+
+func (b *batchLinkStore) requestFlush() {
+	select {
+	case b.flushReq <- struct{}{}:
+	default: // flush is already requested
+	}
+}
+
+func (b *batchLinkStore) AddHits(ctx context.Context, deltas map[int]int) errs.Error {
+	b.mu.Lock()
+	for id, delta := range deltas {
+		b.deltas[id] += delta
+		b.pending += delta
+	}
+	b.mu.Unlock()
+	return nil
+}
+
+// Close flushes pending hits and closes underlying store
+func (b *batchLinkStore) Close(ctx context.Context) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("batch.Close"), errs.SetDefaultErrsKind(errs.KindStore))
+	close(b.done)
+	<-b.completed
+	if err := b.flush(ctx); err != nil {
+		logging.LogError(ctx, errs.SeverityCritical, err)
+		if err2 := b.LinkStore.Close(ctx); err2 != nil {
+			logging.LogError(ctx, err2)
+		}
+		return errs.E(ctx, errs.SeverityCritical, fmt.Errorf("flushing hits on close failed: %w", err))
+	}
+	return b.LinkStore.Close(ctx)
+}
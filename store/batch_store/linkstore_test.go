@@ -0,0 +1,205 @@
+package batch_store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nj-eka/shurl/app"
+	"github.com/nj-eka/shurl/app/hashid_tokenizer"
+	"github.com/nj-eka/shurl/config"
+	"github.com/nj-eka/shurl/internal/errs"
+	"github.com/nj-eka/shurl/store/bolt_store"
+	"log"
+	"os"
+	"testing"
+	"time"
+)
+
+var store app.LinkStore
+
+func Init() {
+	ctx := context.Background()
+	_ = os.Remove("links.db")
+	var err error
+	store, err = bolt_store.NewBoltLinkStore(ctx, config.BoltStoreConfig{FilePath: "links.db", Timeout: 10 * time.Second})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func TestMain(m *testing.M) {
+	Init()
+	code := m.Run()
+	_ = store.Close(context.TODO())
+	_ = os.Remove("links.db")
+	os.Exit(code)
+}
+
+// nopCloser keeps shared store opened after batch store is closed
+type nopCloser struct {
+	app.LinkStore
+}
+
+func (nopCloser) Close(context.Context) errs.Error { return nil }
+
+func Test_batchLinkStore_Hit(t *testing.T) {
+	ctx := context.Background()
+	id, _, err := store.Create(ctx, &app.Link{TargetUrl: "https://stackoverflow.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		cfg      config.BatchStoreConfig
+		hits     int
+		wantHits int
+	}{
+		{"flushed on close", config.BatchStoreConfig{Interval: time.Hour, Size: 1000}, 10, 10},
+		{"flushed by size", config.BatchStoreConfig{Interval: time.Hour, Size: 3}, 10, 20},
+		{"flushed by interval", config.BatchStoreConfig{Interval: time.Millisecond, Size: 1000}, 10, 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bs, err := NewBatchLinkStore(ctx, nopCloser{store}, tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 1; i <= tt.hits; i++ {
+				link, err := bs.Hit(ctx, id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := tt.wantHits - tt.hits + i; link.Hits != want {
+					t.Errorf("Hit() got hits = %v, want %v", link.Hits, want)
+				}
+			}
+			if link, err := bs.Get(ctx, id); err != nil || link.Hits != tt.wantHits {
+				t.Errorf("Get() got = %v, %v, want hits %v", link, err, tt.wantHits)
+			}
+			if err := bs.Close(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if link, err := store.Get(ctx, id); err != nil || link.Hits != tt.wantHits {
+				t.Errorf("Get() after close got = %v, %v, want hits %v", link, err, tt.wantHits)
+			}
+		})
+	}
+}
+
+func benchmarkHit(b *testing.B, s app.LinkStore) {
+	ctx := context.Background()
+	ids := make([]int, 0, 10)
+	for i := 0; i < cap(ids); i++ {
+		id, _, err := s.Create(ctx, &app.Link{TargetUrl: fmt.Sprintf("https://example.com/%d", i)})
+		if err != nil {
+			b.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			if _, err := s.Hit(ctx, ids[i%len(ids)]); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkHit_bolt(b *testing.B) {
+	benchmarkHit(b, store)
+}
+
+func BenchmarkHit_batch(b *testing.B) {
+	bs, err := NewBatchLinkStore(context.Background(), nopCloser{store}, config.BatchStoreConfig{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() {
+		_ = bs.Close(context.Background())
+	}()
+	benchmarkHit(b, bs)
+}
+
+// benchmarkHitLink measures whole redirect path: link hit and hit event record
+func benchmarkHitLink(b *testing.B, s app.LinkStore, hits app.HitStore) {
+	ctx := context.Background()
+	tokenizer, err := hashid_tokenizer.NewHashidTokenizer(&config.HashidTokenizerConfig{MinLength: 5, Alphabet: "0123456789abcdefghijklmnopqrstuvwxyz"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	a := app.NewApp(s, tokenizer, app.WithHitStore(hits))
+	keys := make([]string, 0, 10)
+	for i := 0; i < cap(keys); i++ {
+		key, _, err := a.CreateToken(ctx, &app.Link{TargetUrl: fmt.Sprintf("https://example.com/app/%d", i)})
+		if err != nil {
+			b.Fatal(err)
+		}
+		keys = append(keys, key)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			if _, err := a.HitLink(ctx, keys[i%len(keys)], &app.Hit{ClientIP: "127.0.0.1", UserAgent: "bench"}); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkHitLink_bolt(b *testing.B) {
+	hits, err := bolt_store.NewBoltHitStore(context.Background(), store)
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkHitLink(b, store, hits)
+}
+
+func BenchmarkHitLink_batch(b *testing.B) {
+	ctx := context.Background()
+	hits, err := bolt_store.NewBoltHitStore(ctx, store)
+	if err != nil {
+		b.Fatal(err)
+	}
+	bs, err := NewBatchLinkStore(ctx, nopCloser{store}, config.BatchStoreConfig{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() {
+		_ = bs.Close(ctx)
+	}()
+	bh, err := NewBatchHitStore(ctx, bs, hits)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() {
+		_ = bh.Close(ctx)
+	}()
+	benchmarkHitLink(b, bs, bh)
+}
+
+func Test_batchLinkStore_MaxHits(t *testing.T) {
+	ctx := context.Background()
+	id, _, err := store.Create(ctx, &app.Link{TargetUrl: "https://go.dev/once", MaxHits: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err := NewBatchLinkStore(ctx, nopCloser{store}, config.BatchStoreConfig{Interval: time.Hour, Size: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = bs.Close(ctx)
+	}()
+	if link, err := bs.Hit(ctx, id); err != nil || link.Hits != 1 {
+		t.Errorf("Hit() got = %v, %v, want hits 1", link, err)
+	}
+	if link, err := store.Get(ctx, id); err != nil || link.Hits != 1 {
+		t.Errorf("Get() of underlying store got = %v, %v, want hits 1 (not batched)", link, err)
+	}
+	if _, err := bs.Hit(ctx, id); !errors.Is(err, app.ErrHitsExhausted) {
+		t.Errorf("Hit() of exhausted link gotErr = %v, want %v", err, app.ErrHitsExhausted)
+	}
+}
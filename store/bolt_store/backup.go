@@ -0,0 +1,167 @@
+package bolt_store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/nj-eka/shurl/app"
+	"github.com/nj-eka/shurl/config"
+	cu "github.com/nj-eka/shurl/internal/contexts"
+	"github.com/nj-eka/shurl/internal/errs"
+	bolt "go.etcd.io/bbolt"
+	"io"
+	"os"
+	fp "path/filepath"
+	"time"
+)
+
+var _ app.Backuper = &boltLinkStore{}
+
+// defaultLockTimeout limits waiting for db file lock taken by another process (e.g. running server)
+const defaultLockTimeout = time.Second
+
+// Backup writes snapshot of db file (links and hits) within read transaction so writers aren't blocked
+func (b *boltLinkStore) Backup(ctx context.Context, w io.Writer) (int64, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.Backup"), errs.SetDefaultErrsKind(errs.KindStore))
+	return backup(ctx, b.db.Bolt, w)
+}
+
+// BackupFile writes snapshot of db file which isn't opened by another process
+func BackupFile(ctx context.Context, cfg config.BoltStoreConfig, w io.Writer) (int64, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.BackupFile"), errs.SetDefaultErrsKind(errs.KindStore))
+	db, err := openReadOnly(cfg.FilePath, cfg.Timeout)
+	if err != nil {
+		return 0, errs.E(ctx, fmt.Errorf("opening bolt db [%s] failed (is it used by server?): %w", cfg.FilePath, err))
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	return backup(ctx, db, w)
+}
+
+func backup(ctx context.Context, db *bolt.DB, w io.Writer) (n int64, err errs.Error) {
+	if ie := db.View(func(tx *bolt.Tx) error {
+		var ie error
+		n, ie = tx.WriteTo(w)
+		return ie
+	}); ie != nil {
+		return n, errs.E(ctx, fmt.Errorf("writing snapshot of bolt db [%s] failed: %w", db.Path(), ie))
+	}
+	return n, nil
+}
+
+// ValidateSnapshot checks snapshot file consistency and its links, it returns number of links
+func ValidateSnapshot(ctx context.Context, path string) (int, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.Validate"), errs.SetDefaultErrsKind(errs.KindStore))
+	db, err := openReadOnly(path, defaultLockTimeout)
+	if err != nil {
+		return 0, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("opening snapshot [%s] failed: %v: %w", path, err, app.ErrInvalidSnapshot))
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	count := 0
+	if err = db.View(func(tx *bolt.Tx) error {
+		var checkErr error
+		for err := range tx.Check() { // channel is to be drained
+			if checkErr == nil {
+				checkErr = err
+			}
+		}
+		if checkErr != nil {
+			return checkErr
+		}
+		bucket := tx.Bucket([]byte("Link"))
+		if bucket == nil {
+			return nil // no links were saved
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			if v == nil {
+				return nil // storm metadata and index buckets
+			}
+			var link Link
+			if err := json.Unmarshal(v, &link); err != nil {
+				return fmt.Errorf("decoding link [%x] failed: %w", k, err)
+			}
+			if link.Id <= 0 || link.TargetUrl == "" {
+				return fmt.Errorf("link [%x] is malformed: %v", k, link)
+			}
+			count++
+			return nil
+		})
+	}); err != nil {
+		return 0, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("snapshot [%s]: %v: %w", path, err, app.ErrInvalidSnapshot))
+	}
+	return count, nil
+}
+
+// Restore validates snapshot and replaces db file with it (previous one is kept with .bak suffix),
+// db file must not be opened by another process, it returns number of restored links
+func Restore(ctx context.Context, cfg config.BoltStoreConfig, snapshotPath string) (int, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.Restore"), errs.SetDefaultErrsKind(errs.KindStore))
+	count, err := ValidateSnapshot(ctx, snapshotPath)
+	if err != nil {
+		return 0, err
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = defaultLockTimeout
+	}
+	if _, ie := os.Stat(cfg.FilePath); ie == nil {
+		// holding lock of live db till it's replaced keeps server from starting on it meanwhile
+		db, ie := bolt.Open(cfg.FilePath, 0660, &bolt.Options{Timeout: timeout})
+		if ie != nil {
+			return 0, errs.E(ctx, fmt.Errorf("locking bolt db [%s] failed (is it used by server?): %w", cfg.FilePath, ie))
+		}
+		defer func() {
+			_ = db.Close()
+		}()
+		if ie = copyFile(cfg.FilePath, cfg.FilePath+".bak"); ie != nil {
+			return 0, errs.E(ctx, fmt.Errorf("saving bolt db [%s] copy failed: %w", cfg.FilePath, ie))
+		}
+	} else if !os.IsNotExist(ie) {
+		return 0, errs.E(ctx, fmt.Errorf("checking bolt db [%s] failed: %w", cfg.FilePath, ie))
+	}
+	tmpPath := fp.Join(fp.Dir(cfg.FilePath), "."+fp.Base(cfg.FilePath)+".restore")
+	if ie := copyFile(snapshotPath, tmpPath); ie != nil {
+		_ = os.Remove(tmpPath)
+		return 0, errs.E(ctx, fmt.Errorf("copying snapshot [%s] failed: %w", snapshotPath, ie))
+	}
+	if ie := os.Rename(tmpPath, cfg.FilePath); ie != nil {
+		_ = os.Remove(tmpPath)
+		return 0, errs.E(ctx, fmt.Errorf("replacing bolt db [%s] failed: %w", cfg.FilePath, ie))
+	}
+	return count, nil
+}
+
+func openReadOnly(path string, timeout time.Duration) (*bolt.DB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err // bolt would create missing file
+	}
+	if timeout == 0 {
+		timeout = defaultLockTimeout
+	}
+	return bolt.Open(path, 0440, &bolt.Options{ReadOnly: true, Timeout: timeout})
+}
+
+// copyFile copies src to dst (synced to disk)
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0660)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
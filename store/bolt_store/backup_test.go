@@ -0,0 +1,80 @@
+package bolt_store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nj-eka/shurl/app"
+	"github.com/nj-eka/shurl/config"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func Test_boltLinkStore_Backup(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.BoltStoreConfig{FilePath: "backup.db", Timeout: 100 * time.Millisecond}
+	defer func() {
+		for _, path := range []string{"backup.db", "backup.db.bak", "snapshot.db", "invalid.db"} {
+			_ = os.Remove(path)
+		}
+	}()
+	src, err := NewBoltLinkStore(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, _, err := src.Create(ctx, &app.Link{TargetUrl: fmt.Sprintf("https://go.dev/%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if _, err := src.(app.Backuper).Backup(ctx, &buf); err != nil {
+		t.Fatalf("Backup() gotErr = %v", err)
+	}
+	// written after snapshot, so it's lost by restore
+	if _, _, err := src.Create(ctx, &app.Link{TargetUrl: "https://go.dev/lost"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Restore(ctx, cfg, "snapshot.db"); err == nil {
+		t.Errorf("Restore() of missing snapshot gotErr = nil")
+	}
+	if err := ioutil.WriteFile("snapshot.db", buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Restore(ctx, cfg, "snapshot.db"); err == nil {
+		t.Errorf("Restore() of db used by store gotErr = nil")
+	}
+	_ = src.Close(ctx)
+
+	if err := ioutil.WriteFile("invalid.db", []byte("not a bolt db"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Restore(ctx, cfg, "invalid.db"); !errors.Is(err, app.ErrInvalidSnapshot) {
+		t.Errorf("Restore() of invalid snapshot gotErr = %v, want %v", err, app.ErrInvalidSnapshot)
+	}
+	gotCount, err := Restore(ctx, cfg, "snapshot.db")
+	if err != nil || gotCount != 3 {
+		t.Fatalf("Restore() got = %v, %v, want %v", gotCount, err, 3)
+	}
+	if _, err := os.Stat("backup.db.bak"); err != nil {
+		t.Errorf("Restore() previous db copy: %v", err)
+	}
+	dst, err := NewBoltLinkStore(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = dst.Close(ctx)
+	}()
+	links, err := dst.List(ctx, app.ListQuery{SortBy: app.SortById})
+	if err != nil || len(links) != 3 {
+		t.Errorf("List() after Restore() got = %v, %v, want %v links", links, err, 3)
+	}
+	var fileBuf bytes.Buffer
+	if _, err := BackupFile(ctx, cfg, &fileBuf); err == nil {
+		t.Errorf("BackupFile() of db used by store gotErr = nil")
+	}
+}
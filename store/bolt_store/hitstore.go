@@ -0,0 +1,270 @@
+package bolt_store
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/nj-eka/shurl/app"
+	cu "github.com/nj-eka/shurl/internal/contexts"
+	"github.com/nj-eka/shurl/internal/errs"
+	"github.com/nj-eka/shurl/internal/hll"
+	bolt "go.etcd.io/bbolt"
+	"time"
+)
+
+var _ app.HitStore = &boltHitStore{}
+var _ app.HitBatcher = &boltHitStore{}
+
+// hits are kept in raw bolt bucket with keys [link id | time | seq] (big endian uint64 each),
+// so that link hits of any time range can be read with single cursor seek
+var hitsBucket = []byte("Hits")
+
+// hit counters of time buckets are kept in raw bolt bucket with keys [link id | granularity | bucket start]
+// and big endian uint64 values
+var statsBucket = []byte("Stats")
+
+// unique visitors sketches are kept in raw bolt bucket with the same keys as hit counters,
+// sketch of all time is kept with allTime granularity and zero start
+var uniquesBucket = []byte("Uniques")
+
+const allTime app.Granularity = "all"
+
+type boltHitStore struct {
+	db *bolt.DB
+}
+
+// NewBoltHitStore returns hit store sharing bolt db with given link store (which keeps ownership of db)
+func NewBoltHitStore(ctx context.Context, store app.LinkStore) (app.HitStore, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.InitHits"), errs.SetDefaultErrsKind(errs.KindStore))
+	bls, ok := store.(*boltLinkStore)
+	if !ok {
+		return nil, errs.E(ctx, fmt.Errorf("bolt hit store requires bolt link store, got [%T]", store))
+	}
+	if err := bls.db.Bolt.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{hitsBucket, statsBucket, uniquesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, errs.E(ctx, fmt.Errorf("creating hits buckets failed: %w", err))
+	}
+	return &boltHitStore{db: bls.db.Bolt}, nil
+}
+
+func (b *boltHitStore) AddHit(ctx context.Context, hit *app.Hit) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.AddHit"), errs.SetDefaultErrsKind(errs.KindStore))
+	value, err := json.Marshal(hit)
+	if err != nil {
+		return errs.E(ctx, fmt.Errorf("encoding hit [%v] failed: %w", hit, err))
+	}
+	// batch to coalesce concurrent redirects into fewer write transactions
+	if err = b.db.Batch(func(tx *bolt.Tx) error {
+		return putHit(tx, hit, value)
+	}); err != nil {
+		return errs.E(ctx, fmt.Errorf("adding hit of link with id [%d] failed: %w", hit.LinkId, err))
+	}
+	return nil
+}
+
+// AddHits records hits in single write transaction
+func (b *boltHitStore) AddHits(ctx context.Context, hits []*app.Hit) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.AddHits"), errs.SetDefaultErrsKind(errs.KindStore))
+	values := make([][]byte, len(hits))
+	for i, hit := range hits {
+		var err error
+		if values[i], err = json.Marshal(hit); err != nil {
+			return errs.E(ctx, fmt.Errorf("encoding hit [%v] failed: %w", hit, err))
+		}
+	}
+	if err := b.db.Update(func(tx *bolt.Tx) error {
+		for i, hit := range hits {
+			if err := putHit(tx, hit, values[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return errs.E(ctx, fmt.Errorf("adding [%d] hits failed: %w", len(hits), err))
+	}
+	return nil
+}
+
+// putHit puts encoded hit, increments its time buckets counters and adds its fingerprint to unique visitors sketches
+func putHit(tx *bolt.Tx, hit *app.Hit, value []byte) error {
+	bucket := tx.Bucket(hitsBucket)
+	seq, err := bucket.NextSequence()
+	if err != nil {
+		return err
+	}
+	if err = bucket.Put(hitKey(hit.LinkId, hit.Time, seq), value); err != nil {
+		return err
+	}
+	stats := tx.Bucket(statsBucket)
+	for _, g := range app.Granularities {
+		key := statsKey(hit.LinkId, g, g.Truncate(hit.Time))
+		var count uint64
+		if v := stats.Get(key); v != nil {
+			count = binary.BigEndian.Uint64(v)
+		}
+		value := make([]byte, 8)
+		binary.BigEndian.PutUint64(value, count+1)
+		if err = stats.Put(key, value); err != nil {
+			return err
+		}
+	}
+	uniques := tx.Bucket(uniquesBucket)
+	fp := hit.Fingerprint()
+	for _, key := range [][]byte{
+		statsKey(hit.LinkId, app.UniquesGranularity, app.UniquesGranularity.Truncate(hit.Time)),
+		statsKey(hit.LinkId, allTime, time.Time{}),
+	} {
+		sketch := hll.New()
+		if v := uniques.Get(key); v != nil {
+			if err = sketch.UnmarshalBinary(v); err != nil {
+				return err
+			}
+		}
+		sketch.Add(fp)
+		value, err := sketch.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if err = uniques.Put(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *boltHitStore) ListHits(ctx context.Context, linkId int, from, to time.Time, limit int) ([]*app.Hit, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.ListHits"), errs.SetDefaultErrsKind(errs.KindStore))
+	hits := make([]*app.Hit, 0)
+	if err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(hitsBucket).Cursor()
+		upper := hitKey(linkId, to, 0)
+		for k, v := c.Seek(hitKey(linkId, from, 0)); k != nil && bytes.Compare(k, upper) < 0; k, v = c.Next() {
+			hit := &app.Hit{}
+			if err := json.Unmarshal(v, hit); err != nil {
+				return err
+			}
+			hits = append(hits, hit)
+			if limit > 0 && len(hits) == limit {
+				break
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, errs.E(ctx, fmt.Errorf("listing hits of link with id [%d] failed: %w", linkId, err))
+	}
+	return hits, nil
+}
+
+func (b *boltHitStore) Stats(ctx context.Context, linkId int, g app.Granularity, from, to time.Time) ([]*app.StatsBucket, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.Stats"), errs.SetDefaultErrsKind(errs.KindStore))
+	buckets := make([]*app.StatsBucket, 0)
+	if err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(statsBucket).Cursor()
+		upper := statsKey(linkId, g, to)
+		for k, v := c.Seek(statsKey(linkId, g, from)); k != nil && bytes.Compare(k, upper) < 0; k, v = c.Next() {
+			buckets = append(buckets, &app.StatsBucket{
+				Start: time.Unix(0, int64(binary.BigEndian.Uint64(k[9:]))).UTC(),
+				Hits:  int(binary.BigEndian.Uint64(v)),
+			})
+		}
+		return nil
+	}); err != nil {
+		return nil, errs.E(ctx, fmt.Errorf("getting stats of link with id [%d] failed: %w", linkId, err))
+	}
+	return buckets, nil
+}
+
+func (b *boltHitStore) Uniques(ctx context.Context, linkId int, from, to time.Time) (int, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.Uniques"), errs.SetDefaultErrsKind(errs.KindStore))
+	sketch := hll.New()
+	if err := b.db.View(func(tx *bolt.Tx) error {
+		uniques := tx.Bucket(uniquesBucket)
+		if from.IsZero() && to.IsZero() {
+			if v := uniques.Get(statsKey(linkId, allTime, time.Time{})); v != nil {
+				return sketch.UnmarshalBinary(v)
+			}
+			return nil
+		}
+		c := uniques.Cursor()
+		upper := statsKey(linkId, app.UniquesGranularity, to)
+		for k, v := c.Seek(statsKey(linkId, app.UniquesGranularity, from)); k != nil && bytes.Compare(k, upper) < 0; k, v = c.Next() {
+			bucketSketch := hll.New()
+			if err := bucketSketch.UnmarshalBinary(v); err != nil {
+				return err
+			}
+			sketch.Merge(bucketSketch)
+		}
+		return nil
+	}); err != nil {
+		return 0, errs.E(ctx, fmt.Errorf("estimating unique visitors of link with id [%d] failed: %w", linkId, err))
+	}
+	return int(sketch.Count()), nil
+}
+
+func (b *boltHitStore) Purge(ctx context.Context, linkId int) (int, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.Purge"), errs.SetDefaultErrsKind(errs.KindStore))
+	prefix := make([]byte, 8)
+	binary.BigEndian.PutUint64(prefix, uint64(linkId))
+	count := 0
+	if err := b.db.Update(func(tx *bolt.Tx) error {
+		// all keys of link start with its id
+		for _, name := range [][]byte{hitsBucket, statsBucket, uniquesBucket} {
+			bucket := tx.Bucket(name)
+			var keys [][]byte
+			c := bucket.Cursor()
+			for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+				keys = append(keys, k)
+			}
+			// keys are deleted after iteration as deleting under cursor makes it skip keys
+			for _, k := range keys {
+				if err := bucket.Delete(k); err != nil {
+					return err
+				}
+			}
+			count += len(keys)
+		}
+		return nil
+	}); err != nil {
+		return 0, errs.E(ctx, fmt.Errorf("purging hits of link with id [%d] failed: %w", linkId, err))
+	}
+	return count, nil
+}
+
+func (b *boltHitStore) Close(ctx context.Context) errs.Error {
+	return nil // db is closed by link store
+}
+
+func hitKey(linkId int, t time.Time, seq uint64) []byte {
+	key := make([]byte, 24)
+	binary.BigEndian.PutUint64(key, uint64(linkId))
+	binary.BigEndian.PutUint64(key[8:], uint64(unixNano(t)))
+	binary.BigEndian.PutUint64(key[16:], seq)
+	return key
+}
+
+func statsKey(linkId int, g app.Granularity, start time.Time) []byte {
+	key := make([]byte, 17)
+	binary.BigEndian.PutUint64(key, uint64(linkId))
+	key[8] = g[0]
+	binary.BigEndian.PutUint64(key[9:], uint64(unixNano(start)))
+	return key
+}
+
+// unixNano clamps time to non-negative nanoseconds range so that it keeps byte order of keys
+func unixNano(t time.Time) int64 {
+	if t.IsZero() || t.Before(time.Unix(0, 0)) {
+		return 0
+	}
+	if t.Year() > 2262 {
+		return 1<<63 - 1
+	}
+	return t.UnixNano()
+}
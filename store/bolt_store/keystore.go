@@ -0,0 +1,70 @@
+package bolt_store
+
+import (
+	"context"
+	"fmt"
+	"github.com/asdine/storm/v3"
+	"github.com/nj-eka/shurl/app"
+	cu "github.com/nj-eka/shurl/internal/contexts"
+	"github.com/nj-eka/shurl/internal/errs"
+)
+
+var _ app.KeyStore = &boltLinkStore{}
+
+func (b *boltLinkStore) AddKey(ctx context.Context, key *app.ApiKey) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.AddKey"), errs.SetDefaultErrsKind(errs.KindStore))
+	k := ApiKey{Id: key.Id, Owner: key.Owner, Hash: key.Hash, Admin: key.Admin, CreatedAt: key.CreatedAt}
+	var ie error
+	tx, ie := b.db.Begin(true)
+	if ie == nil {
+		defer func() {
+			_ = tx.Rollback()
+		}()
+		if ie = tx.One("Id", key.Id, &ApiKey{}); ie == nil {
+			return errs.E(ctx, errs.SeverityWarning, fmt.Errorf("api key [%s]: %w", key.Id, app.ErrConflict))
+		} else if ie == storm.ErrNotFound {
+			if ie = tx.Save(&k); ie == nil {
+				if ie = tx.Commit(); ie == nil {
+					return nil
+				}
+			}
+		}
+	}
+	return errs.E(ctx, fmt.Errorf("adding api key [%s] failed: %w", key.Id, ie))
+}
+
+func (b *boltLinkStore) GetKey(ctx context.Context, id string) (*app.ApiKey, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.GetKey"), errs.SetDefaultErrsKind(errs.KindStore))
+	var k ApiKey
+	if err := b.db.One("Id", id, &k); err != nil {
+		if err == storm.ErrNotFound {
+			return nil, errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return nil, errs.E(ctx, fmt.Errorf("getting api key [%s] failed: %w", id, err))
+	}
+	return k.toApp(), nil
+}
+
+func (b *boltLinkStore) ListKeys(ctx context.Context) ([]*app.ApiKey, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.ListKeys"), errs.SetDefaultErrsKind(errs.KindStore))
+	var keys []ApiKey
+	if err := b.db.All(&keys); err != nil {
+		return nil, errs.E(ctx, fmt.Errorf("listing api keys failed: %w", err))
+	}
+	result := make([]*app.ApiKey, 0, len(keys))
+	for i := range keys {
+		result = append(result, keys[i].toApp())
+	}
+	return result, nil
+}
+
+func (b *boltLinkStore) DeleteKey(ctx context.Context, id string) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.DeleteKey"), errs.SetDefaultErrsKind(errs.KindStore))
+	if err := b.db.DeleteStruct(&ApiKey{Id: id}); err != nil {
+		if err == storm.ErrNotFound {
+			return errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return errs.E(ctx, fmt.Errorf("deleting api key [%s] failed: %w", id, err))
+	}
+	return nil
+}
@@ -1,12 +1,103 @@
 package bolt_store
 
-import "time"
+import (
+	"github.com/nj-eka/shurl/app"
+	"time"
+)
 
 type Link struct {
-	Id        int    `storm:"id,increment"`
-	TargetUrl string `storm:"unique"`
+	Id        int `storm:"id,increment"`
+	TargetUrl string
+	Owner     string
+	// OwnerUrl is unique key of link deduplication: url is unique among links of the same owner
+	OwnerUrl     string `storm:"unique"`
+	Alias        string `storm:"unique"`
+	PasswordHash string
+	CreatedAt    time.Time
+	DeletedAt    *time.Time
+	ExpiredAt    *time.Time
+	ActiveFrom   *time.Time
+	Hits         int
+	MaxHits      int
+	RedirectType int
+	// QueryPassthrough, PathPassthrough, Utm and Rules are kept as app.Link ones
+	QueryPassthrough app.QueryPassthrough
+	PathPassthrough  bool
+	Utm              *app.Utm
+	Rules            []app.Rule
+}
+
+func (l *Link) toApp() *app.Link {
+	return &app.Link{
+		Id:               l.Id,
+		Alias:            l.Alias,
+		TargetUrl:        l.TargetUrl,
+		Owner:            l.Owner,
+		PasswordHash:     l.PasswordHash,
+		CreatedAt:        l.CreatedAt,
+		ExpiredAt:        l.ExpiredAt,
+		ActiveFrom:       l.ActiveFrom,
+		DeletedAt:        l.DeletedAt,
+		Hits:             l.Hits,
+		MaxHits:          l.MaxHits,
+		RedirectType:     l.RedirectType,
+		QueryPassthrough: l.QueryPassthrough,
+		PathPassthrough:  l.PathPassthrough,
+		Utm:              l.Utm,
+		Rules:            l.Rules,
+	}
+}
+
+func fromApp(link *app.Link) *Link {
+	return &Link{
+		Id:               link.Id,
+		Alias:            link.Alias,
+		TargetUrl:        link.TargetUrl,
+		Owner:            link.Owner,
+		OwnerUrl:         ownerUrl(link.Owner, link.TargetUrl),
+		PasswordHash:     link.PasswordHash,
+		CreatedAt:        link.CreatedAt,
+		ExpiredAt:        link.ExpiredAt,
+		ActiveFrom:       link.ActiveFrom,
+		DeletedAt:        link.DeletedAt,
+		Hits:             link.Hits,
+		MaxHits:          link.MaxHits,
+		RedirectType:     link.RedirectType,
+		QueryPassthrough: link.QueryPassthrough,
+		PathPassthrough:  link.PathPassthrough,
+		Utm:              link.Utm,
+		Rules:            link.Rules,
+	}
+}
+
+func ownerUrl(owner, url string) string {
+	return owner + "\n" + url
+}
+
+// ApiKey is app.ApiKey kept in bolt db
+type ApiKey struct {
+	Id        string `storm:"id"`
+	Owner     string
+	Hash      string
+	Admin     bool
 	CreatedAt time.Time
-	DeletedAt *time.Time
-	ExpiredAt *time.Time
-	Hits      int
+}
+
+func (k *ApiKey) toApp() *app.ApiKey {
+	return &app.ApiKey{Id: k.Id, Owner: k.Owner, Hash: k.Hash, Admin: k.Admin, CreatedAt: k.CreatedAt}
+}
+
+// listMatcher is storm query matcher filtering links by app.ListQuery
+type listMatcher struct {
+	q *app.ListQuery
+}
+
+func (m listMatcher) Match(i interface{}) (bool, error) {
+	switch link := i.(type) {
+	case Link: // storm passes indirect values to matchers combined with q.And
+		return m.q.Match(link.toApp()), nil
+	case *Link:
+		return m.q.Match(link.toApp()), nil
+	}
+	return false, nil
 }
@@ -2,6 +2,7 @@ package bolt_store
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
 	"github.com/asdine/storm/v3"
 	"github.com/nj-eka/shurl/app"
@@ -25,10 +26,14 @@ func NewBoltLinkStore(ctx context.Context, cfg config.BoltStoreConfig) (app.Link
 	if err != nil {
 		return nil, errs.E(ctx, errs.KindStore, fmt.Errorf("opening bolt db [%s] failed: %w", cfg.FilePath, err))
 	}
+	if err = migrate(db); err != nil {
+		_ = db.Close()
+		return nil, errs.E(ctx, errs.KindStore, fmt.Errorf("migrating bolt db [%s] failed: %w", cfg.FilePath, err))
+	}
 	return &boltLinkStore{db: db}, nil
 }
 
-func (b *boltLinkStore) Create(ctx context.Context, targetUrl string, expiredAt *time.Time) (int, bool, errs.Error) {
+func (b *boltLinkStore) Create(ctx context.Context, link *app.Link) (int, bool, errs.Error) {
 	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.Create"), errs.SetDefaultErrsKind(errs.KindStore))
 	var ie error
 	id, added := -1, false
@@ -37,23 +42,43 @@ func (b *boltLinkStore) Create(ctx context.Context, targetUrl string, expiredAt
 		defer func() {
 			_ = tx.Rollback()
 		}()
-		link := Link{}
-		if ie = tx.One("TargetUrl", targetUrl, &link); ie == nil {
-			ie = tx.UpdateField(&Link{Id: link.Id}, "ExpiredAt", expiredAt)
+		bl := Link{}
+		if ie = tx.One("OwnerUrl", ownerUrl(link.Owner, link.TargetUrl), &bl); ie == nil {
+			if link.Alias != "" && link.Alias != bl.Alias {
+				if bl.Alias != "" {
+					ie = app.ErrAliasExists
+				} else {
+					ie = tx.UpdateField(&Link{Id: bl.Id}, "Alias", link.Alias)
+				}
+			}
 		} else if ie == storm.ErrNotFound {
-			link.TargetUrl = targetUrl
-			link.CreatedAt = time.Now().UTC()
-			link.ExpiredAt = expiredAt
-			ie = tx.Save(&link)
+			bl.TargetUrl = link.TargetUrl
+			bl.Owner = link.Owner
+			bl.OwnerUrl = ownerUrl(link.Owner, link.TargetUrl)
+			bl.Alias = link.Alias
+			bl.PasswordHash = link.PasswordHash
+			bl.MaxHits = link.MaxHits
+			bl.CreatedAt = time.Now().UTC()
+			bl.ExpiredAt = link.ExpiredAt
+			bl.ActiveFrom = link.ActiveFrom
+			bl.RedirectType = link.RedirectType
+			bl.QueryPassthrough = link.QueryPassthrough
+			bl.PathPassthrough = link.PathPassthrough
+			bl.Utm = link.Utm
+			bl.Rules = link.Rules
+			ie = tx.Save(&bl)
 			added = true
 		}
 		if ie == nil {
 			if ie = tx.Commit(); ie == nil {
-				return link.Id, added, nil
+				return bl.Id, added, nil
 			}
 		}
+		if ie == storm.ErrAlreadyExists || ie == app.ErrAliasExists {
+			return id, false, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("adding link with alias [%s] failed: %w", link.Alias, app.ErrAliasExists))
+		}
 	}
-	return id, added, errs.E(ctx, fmt.Errorf("adding link [%s] failed: %w", strutils.Truncate(targetUrl, 24, "..."), ie))
+	return id, added, errs.E(ctx, fmt.Errorf("adding link [%s] failed: %w", strutils.Truncate(link.TargetUrl, 24, "..."), ie))
 }
 
 func (b *boltLinkStore) Get(ctx context.Context, id int) (*app.Link, errs.Error) {
@@ -65,14 +90,32 @@ func (b *boltLinkStore) Get(ctx context.Context, id int) (*app.Link, errs.Error)
 		}
 		return nil, errs.E(ctx, fmt.Errorf("getting link with id [%d] failed: %w", id, err))
 	}
-	return &app.Link{
-		Id:        link.Id,
-		TargetUrl: link.TargetUrl,
-		CreatedAt: link.CreatedAt,
-		ExpiredAt: link.ExpiredAt,
-		DeletedAt: link.DeletedAt,
-		Hits:      link.Hits,
-	}, nil
+	return link.toApp(), nil
+}
+
+func (b *boltLinkStore) Lookup(ctx context.Context, alias string) (int, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.Lookup"), errs.SetDefaultErrsKind(errs.KindStore))
+	link := Link{}
+	if err := b.db.One("Alias", alias, &link); err != nil {
+		if err == storm.ErrNotFound {
+			return -1, errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return -1, errs.E(ctx, fmt.Errorf("looking up link with alias [%s] failed: %w", alias, err))
+	}
+	return link.Id, nil
+}
+
+func (b *boltLinkStore) List(ctx context.Context, q app.ListQuery) ([]*app.Link, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.List"), errs.SetDefaultErrsKind(errs.KindStore))
+	var links []Link
+	if err := b.db.Select(listMatcher{q: &q}).Find(&links); err != nil && err != storm.ErrNotFound {
+		return nil, errs.E(ctx, fmt.Errorf("listing links failed: %w", err))
+	}
+	result := make([]*app.Link, 0, len(links))
+	for i := range links {
+		result = append(result, links[i].toApp())
+	}
+	return q.Sort(result), nil
 }
 
 func (b *boltLinkStore) Hit(ctx context.Context, id int) (*app.Link, errs.Error) {
@@ -85,17 +128,13 @@ func (b *boltLinkStore) Hit(ctx context.Context, id int) (*app.Link, errs.Error)
 		}()
 		link := Link{}
 		if ie = tx.One("Id", id, &link); ie == nil {
+			if link.MaxHits > 0 && link.Hits >= link.MaxHits {
+				return nil, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("hitting link with id [%d] failed: %w", id, app.ErrHitsExhausted))
+			}
 			link.Hits++
 			if ie = tx.UpdateField(&Link{Id: id}, "Hits", link.Hits); ie == nil {
 				if ie = tx.Commit(); ie == nil {
-					return &app.Link{
-						Id:        link.Id,
-						TargetUrl: link.TargetUrl,
-						CreatedAt: link.CreatedAt,
-						ExpiredAt: link.ExpiredAt,
-						DeletedAt: link.DeletedAt,
-						Hits:      link.Hits,
-					}, nil
+					return link.toApp(), nil
 				}
 			}
 		}
@@ -106,6 +145,96 @@ func (b *boltLinkStore) Hit(ctx context.Context, id int) (*app.Link, errs.Error)
 	return nil, errs.E(ctx, fmt.Errorf("hitting link with id [%d] failed: %w", id, ie))
 }
 
+func (b *boltLinkStore) AddHits(ctx context.Context, deltas map[int]int) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.AddHits"), errs.SetDefaultErrsKind(errs.KindStore))
+	var ie error
+	tx, ie := b.db.Begin(true)
+	if ie == nil {
+		defer func() {
+			_ = tx.Rollback()
+		}()
+		for id, delta := range deltas {
+			link := Link{}
+			if ie = tx.One("Id", id, &link); ie == nil {
+				ie = tx.UpdateField(&Link{Id: id}, "Hits", link.Hits+delta)
+			} else if ie == storm.ErrNotFound {
+				ie = nil
+			}
+			if ie != nil {
+				break
+			}
+		}
+		if ie == nil {
+			if ie = tx.Commit(); ie == nil {
+				return nil
+			}
+		}
+	}
+	return errs.E(ctx, fmt.Errorf("adding hits of [%d] links failed: %w", len(deltas), ie))
+}
+
+func (b *boltLinkStore) Update(ctx context.Context, link *app.Link) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.Update"), errs.SetDefaultErrsKind(errs.KindStore))
+	var ie error
+	tx, ie := b.db.Begin(true)
+	if ie == nil {
+		defer func() {
+			_ = tx.Rollback()
+		}()
+		bl := Link{}
+		if ie = tx.One("Id", link.Id, &bl); ie == nil {
+			if link.TargetUrl != bl.TargetUrl {
+				if ie = tx.UpdateField(&Link{Id: link.Id}, "OwnerUrl", ownerUrl(bl.Owner, link.TargetUrl)); ie == nil {
+					ie = tx.UpdateField(&Link{Id: link.Id}, "TargetUrl", link.TargetUrl)
+				}
+			}
+			if ie == nil {
+				ie = tx.UpdateField(&Link{Id: link.Id}, "ExpiredAt", link.ExpiredAt)
+			}
+			if ie == nil {
+				ie = tx.UpdateField(&Link{Id: link.Id}, "Rules", link.Rules)
+			}
+		}
+		if ie == nil {
+			if ie = tx.Commit(); ie == nil {
+				return nil
+			}
+		}
+		switch ie {
+		case storm.ErrNotFound:
+			return errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		case storm.ErrAlreadyExists:
+			return errs.E(ctx, errs.SeverityWarning, fmt.Errorf("updating link with id [%d] failed: %w", link.Id, app.ErrConflict))
+		}
+	}
+	return errs.E(ctx, fmt.Errorf("updating link with id [%d] failed: %w", link.Id, ie))
+}
+
+func (b *boltLinkStore) Import(ctx context.Context, link *app.Link) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.Import"), errs.SetDefaultErrsKind(errs.KindStore))
+	err := b.db.Bolt.Update(func(tx *bolt.Tx) error {
+		if err := b.db.WithTransaction(tx).Save(fromApp(link)); err != nil {
+			return err
+		}
+		// storm doesn't advance id counter on saving with given id
+		meta := tx.Bucket([]byte("Link")).Bucket([]byte("__storm_metadata"))
+		raw := meta.Get([]byte("Idcounter"))
+		if raw == nil || int64(binary.BigEndian.Uint64(raw)) < int64(link.Id) {
+			counter := make([]byte, 8)
+			binary.BigEndian.PutUint64(counter, uint64(link.Id))
+			return meta.Put([]byte("Idcounter"), counter)
+		}
+		return nil
+	})
+	if err != nil {
+		if err == storm.ErrAlreadyExists {
+			return errs.E(ctx, errs.SeverityWarning, fmt.Errorf("importing link with id [%d] failed: %w", link.Id, app.ErrConflict))
+		}
+		return errs.E(ctx, fmt.Errorf("importing link with id [%d] failed: %w", link.Id, err))
+	}
+	return nil
+}
+
 func (b *boltLinkStore) SetDeleted(ctx context.Context, id int) errs.Error {
 	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.SetDel"), errs.SetDefaultErrsKind(errs.KindStore))
 	deletedAt := time.Now().UTC()
@@ -118,6 +247,17 @@ func (b *boltLinkStore) SetDeleted(ctx context.Context, id int) errs.Error {
 	return nil
 }
 
+func (b *boltLinkStore) Restore(ctx context.Context, id int) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.Restore"), errs.SetDefaultErrsKind(errs.KindStore))
+	if err := b.db.UpdateField(&Link{Id: id}, "DeletedAt", (*time.Time)(nil)); err != nil {
+		if err == storm.ErrNotFound {
+			return errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return errs.E(ctx, fmt.Errorf("restoring link with id [%d] failed: %w", id, err))
+	}
+	return nil
+}
+
 func (b *boltLinkStore) Delete(ctx context.Context, id int) errs.Error {
 	ctx = cu.BuildContext(ctx, cu.AddContextOperation("bolt.Delete"), errs.SetDefaultErrsKind(errs.KindStore))
 	if err := b.db.DeleteStruct(&Link{Id: id}); err != nil {
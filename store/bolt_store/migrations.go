@@ -0,0 +1,31 @@
+package bolt_store
+
+import (
+	"github.com/asdine/storm/v3"
+	bolt "go.etcd.io/bbolt"
+)
+
+// urlIndex is unique index of link urls replaced by OwnerUrl one when links got owners
+var urlIndex = []byte("__storm_index_TargetUrl")
+
+// migrate brings links saved by previous versions up to date, it's done in one transaction and only once
+func migrate(db *storm.DB) error {
+	return db.Bolt.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("Link"))
+		if bucket == nil || bucket.Bucket(urlIndex) == nil {
+			return nil
+		}
+		node := db.WithTransaction(tx)
+		var links []Link
+		if err := node.All(&links); err != nil {
+			return err
+		}
+		for i := range links {
+			links[i].OwnerUrl = ownerUrl(links[i].Owner, links[i].TargetUrl)
+			if err := node.Save(&links[i]); err != nil {
+				return err
+			}
+		}
+		return bucket.DeleteBucket(urlIndex)
+	})
+}
@@ -0,0 +1,233 @@
+package cache_store
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"github.com/nj-eka/shurl/app"
+	"github.com/nj-eka/shurl/config"
+	cu "github.com/nj-eka/shurl/internal/contexts"
+	"github.com/nj-eka/shurl/internal/errs"
+	"github.com/nj-eka/shurl/internal/logging"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultSize = 10000
+	DefaultTTL  = time.Minute
+)
+
+// Counter is implemented by link store returned by NewCacheLinkStore
+type Counter interface {
+	// Counters returns numbers of cache hits and misses
+	Counters() (hits, misses uint64)
+}
+
+var _ app.LinkStore = &cacheLinkStore{}
+var _ Counter = &cacheLinkStore{}
+
+type entry struct {
+	link     app.Link
+	cachedAt time.Time
+}
+
+// copyLink returns copy of link not sharing its mutable Rules and Utm (cached link is neither changed by callers nor changes them)
+func copyLink(link *app.Link) app.Link {
+	cp := *link
+	if link.Rules != nil {
+		cp.Rules = append([]app.Rule(nil), link.Rules...)
+	}
+	if link.Utm != nil {
+		utm := *link.Utm
+		cp.Utm = &utm
+	}
+	return cp
+}
+
+// cacheLinkStore is read-through decorator of link store with LRU cache of links (by id) limited in size and time to live
+type cacheLinkStore struct {
+	app.LinkStore
+	size   int
+	ttl    time.Duration
+	mu     sync.Mutex
+	items  map[int]*list.Element
+	lru    *list.List // front is the most recently used
+	hits   uint64
+	misses uint64
+
+	// fills are tokens of reads of links to be cached, invalidation drops them (see beginFill)
+	fills   map[int]uint64
+	fillSeq uint64
+}
+
+func NewCacheLinkStore(ctx context.Context, store app.LinkStore, cfg config.CacheStoreConfig) (app.LinkStore, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("cache.Init"), errs.SetDefaultErrsKind(errs.KindStore))
+	if cfg.Size < 0 || cfg.TTL < 0 {
+		return nil, errs.E(ctx, errs.KindInvalidValue, fmt.Errorf("invalid cache config: size [%d] ttl [%v]", cfg.Size, cfg.TTL))
+	}
+	if cfg.Size == 0 {
+		cfg.Size = DefaultSize
+	}
+	if cfg.TTL == 0 {
+		cfg.TTL = DefaultTTL
+	}
+	return &cacheLinkStore{
+		LinkStore: store,
+		size:      cfg.Size,
+		ttl:       cfg.TTL,
+		items:     make(map[int]*list.Element, cfg.Size),
+		lru:       list.New(),
+		fills:     make(map[int]uint64),
+	}, nil
+}
+
+func (c *cacheLinkStore) Counters() (hits, misses uint64) {
+	return atomic.LoadUint64(&c.hits), atomic.LoadUint64(&c.misses)
+}
+
+// Create invalidates existing link as it can be updated
+func (c *cacheLinkStore) Create(ctx context.Context, link *app.Link) (int, bool, errs.Error) {
+	id, added, err := c.LinkStore.Create(ctx, link)
+	if err == nil && !added {
+		c.invalidate(id)
+	}
+	return id, added, err
+}
+
+func (c *cacheLinkStore) Get(ctx context.Context, id int) (*app.Link, errs.Error) {
+	if link := c.get(id); link != nil {
+		atomic.AddUint64(&c.hits, 1)
+		return link, nil
+	}
+	atomic.AddUint64(&c.misses, 1)
+	token := c.beginFill(id)
+	link, err := c.LinkStore.Get(ctx, id)
+	if err != nil {
+		c.endFill(id, token, nil)
+		return nil, err
+	}
+	c.endFill(id, token, link)
+	return link, nil
+}
+
+func (c *cacheLinkStore) Hit(ctx context.Context, id int) (*app.Link, errs.Error) {
+	token := c.beginFill(id)
+	link, err := c.LinkStore.Hit(ctx, id)
+	if err != nil {
+		c.endFill(id, token, nil)
+		c.invalidate(id)
+		return nil, err
+	}
+	c.endFill(id, token, link)
+	return link, nil
+}
+
+func (c *cacheLinkStore) AddHits(ctx context.Context, deltas map[int]int) errs.Error {
+	defer func() {
+		for id := range deltas {
+			c.invalidate(id)
+		}
+	}()
+	return c.LinkStore.AddHits(ctx, deltas)
+}
+
+func (c *cacheLinkStore) Update(ctx context.Context, link *app.Link) errs.Error {
+	defer c.invalidate(link.Id)
+	return c.LinkStore.Update(ctx, link)
+}
+
+func (c *cacheLinkStore) Import(ctx context.Context, link *app.Link) errs.Error {
+	defer c.invalidate(link.Id)
+	return c.LinkStore.Import(ctx, link)
+}
+
+func (c *cacheLinkStore) SetDeleted(ctx context.Context, id int) errs.Error {
+	defer c.invalidate(id)
+	return c.LinkStore.SetDeleted(ctx, id)
+}
+
+func (c *cacheLinkStore) Restore(ctx context.Context, id int) errs.Error {
+	defer c.invalidate(id)
+	return c.LinkStore.Restore(ctx, id)
+}
+
+func (c *cacheLinkStore) Delete(ctx context.Context, id int) errs.Error {
+	defer c.invalidate(id)
+	return c.LinkStore.Delete(ctx, id)
+}
+
+func (c *cacheLinkStore) Close(ctx context.Context) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("cache.Close"))
+	hits, misses := c.Counters()
+	logging.Msg(ctx).Infof("link cache hits: %d misses: %d", hits, misses)
+	return c.LinkStore.Close(ctx)
+}
+
+// get returns copy of cached link or nil if there is no one or it's outdated
+func (c *cacheLinkStore) get(id int) *app.Link {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[id]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*entry)
+	if time.Since(e.cachedAt) > c.ttl {
+		c.lru.Remove(el)
+		delete(c.items, id)
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	link := copyLink(&e.link)
+	return &link
+}
+
+// beginFill registers read of link to be cached (before it is read from underlying store), it returns token of the read
+func (c *cacheLinkStore) beginFill(id int) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fillSeq++
+	c.fills[id] = c.fillSeq
+	return c.fillSeq
+}
+
+// endFill caches link read by fill unless link is invalidated (or read again) since fill began,
+// so that link changed while it was read is not cached outdated
+func (c *cacheLinkStore) endFill(id int, token uint64, link *app.Link) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fills[id] != token {
+		return
+	}
+	delete(c.fills, id)
+	if link != nil {
+		c.put(link)
+	}
+}
+
+// put caches link, mu is to be held
+func (c *cacheLinkStore) put(link *app.Link) {
+	if el, ok := c.items[link.Id]; ok {
+		el.Value = &entry{link: copyLink(link), cachedAt: time.Now()}
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.items[link.Id] = c.lru.PushFront(&entry{link: copyLink(link), cachedAt: time.Now()})
+	if c.lru.Len() > c.size {
+		el := c.lru.Back()
+		c.lru.Remove(el)
+		delete(c.items, el.Value.(*entry).link.Id)
+	}
+}
+
+func (c *cacheLinkStore) invalidate(id int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.fills, id)
+	if el, ok := c.items[id]; ok {
+		c.lru.Remove(el)
+		delete(c.items, id)
+	}
+}
@@ -0,0 +1,173 @@
+package cache_store
+
+import (
+	"context"
+	"errors"
+	"github.com/nj-eka/shurl/app"
+	"github.com/nj-eka/shurl/config"
+	"github.com/nj-eka/shurl/internal/errs"
+	"github.com/nj-eka/shurl/store/bolt_store"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+var store app.LinkStore
+
+func Init() {
+	ctx := context.Background()
+	_ = os.Remove("links.db")
+	var err error
+	store, err = bolt_store.NewBoltLinkStore(ctx, config.BoltStoreConfig{FilePath: "links.db", Timeout: 10 * time.Second})
+	if err != nil {
+		log.Fatal(err)
+	}
+	store, err = NewCacheLinkStore(ctx, store, config.CacheStoreConfig{Size: 2, TTL: 100 * time.Millisecond})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func TestMain(m *testing.M) {
+	Init()
+	code := m.Run()
+	_ = store.Close(context.TODO())
+	_ = os.Remove("links.db")
+	os.Exit(code)
+}
+
+func Test_cacheLinkStore(t *testing.T) {
+	ctx := context.Background()
+	for _, url := range []string{"https://stackoverflow.com", "https://go.dev", "https://golang.org"} {
+		if _, _, err := store.Create(ctx, &app.Link{TargetUrl: url}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expiredAt := time.Now().UTC().AddDate(0, 0, 1)
+	tests := []struct {
+		name       string
+		do         func() error
+		id         int
+		wantHits   int
+		wantErrIs  error
+		wantCached bool
+	}{
+		{"get first miss", nil, 1, 0, nil, false},
+		{"get first hit", nil, 1, 0, nil, true},
+		{"get second miss", nil, 2, 0, nil, false},
+		{"get third miss", nil, 3, 0, nil, false},
+		{"get first evicted", nil, 1, 0, nil, false},
+		{"get third hit", nil, 3, 0, nil, true},
+		{"get third updated by hit", func() error { _, err := store.Hit(ctx, 3); return err }, 3, 1, nil, true},
+		{"get third invalidated by add hits", func() error { return store.AddHits(ctx, map[int]int{3: 2}) }, 3, 3, nil, false},
+		{"get third invalidated by create", func() error {
+			_, _, err := store.Create(ctx, &app.Link{TargetUrl: "https://golang.org", ExpiredAt: &expiredAt})
+			return err
+		}, 3, 3, nil, false},
+		{"get third expired", func() error { time.Sleep(150 * time.Millisecond); return nil }, 3, 3, nil, false},
+		{"get third invalidated by delete", func() error { return store.Delete(ctx, 3) }, 3, 0, app.ErrNotFound, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.do != nil {
+				if err := tt.do(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			hits, misses := store.(Counter).Counters()
+			gotLink, gotErr := store.Get(ctx, tt.id)
+			if tt.wantErrIs != nil || gotErr != nil {
+				if !errors.Is(gotErr, tt.wantErrIs) {
+					t.Errorf("Get() gotErr = %v, want %v", gotErr, tt.wantErrIs)
+				}
+			} else if gotLink.Id != tt.id || gotLink.Hits != tt.wantHits {
+				t.Errorf("Get() got = %v, want id %v hits %v", gotLink, tt.id, tt.wantHits)
+			}
+			gotHits, gotMisses := store.(Counter).Counters()
+			if gotCached := gotHits > hits; gotCached != tt.wantCached || gotHits+gotMisses != hits+misses+1 {
+				t.Errorf("Get() got cached = %v, want %v", gotCached, tt.wantCached)
+			}
+		})
+	}
+}
+
+func Test_cacheLinkStore_Copies(t *testing.T) {
+	ctx := context.Background()
+	id, _, err := store.Create(ctx, &app.Link{
+		TargetUrl: "https://go.dev/cached",
+		Utm:       &app.Utm{Source: "cache"},
+		Rules:     []app.Rule{{Platform: app.PlatformIOS, TargetUrl: "https://apps.apple.com/app/go"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ { // the first get caches link, the second one returns cached copy
+		link, err := store.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if link.Utm == nil || link.Utm.Source != "cache" || len(link.Rules) != 1 || link.Rules[0].TargetUrl != "https://apps.apple.com/app/go" {
+			t.Fatalf("Get() [%d] got = %v, want link as created", i, link)
+		}
+		link.Utm.Source = "changed"
+		link.Rules[0].TargetUrl = "https://go.dev/changed"
+		link.Rules = nil
+	}
+	if link, err := store.Get(ctx, id); err != nil || link.Utm.Source != "cache" || link.Rules[0].TargetUrl != "https://apps.apple.com/app/go" {
+		t.Errorf("Get() got = %v, %v, want cached link unchanged by callers", link, err)
+	}
+}
+
+// slowStore keeps single link, its first Get reads link and waits for gate to be closed before returning it
+type slowStore struct {
+	app.LinkStore
+	mu   sync.Mutex
+	link app.Link
+	read chan struct{}
+	gate chan struct{}
+	once sync.Once
+}
+
+func (s *slowStore) Get(_ context.Context, _ int) (*app.Link, errs.Error) {
+	s.mu.Lock()
+	link := s.link
+	s.mu.Unlock()
+	s.once.Do(func() {
+		close(s.read)
+		<-s.gate
+	})
+	return &link, nil
+}
+
+func (s *slowStore) Update(_ context.Context, link *app.Link) errs.Error {
+	s.mu.Lock()
+	s.link = *link
+	s.mu.Unlock()
+	return nil
+}
+
+func Test_cacheLinkStore_UpdateWhileMissed(t *testing.T) {
+	ctx := context.Background()
+	slow := &slowStore{link: app.Link{Id: 1, TargetUrl: "https://go.dev/old"}, read: make(chan struct{}), gate: make(chan struct{})}
+	cs, err := NewCacheLinkStore(ctx, slow, config.CacheStoreConfig{Size: 2, TTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = cs.Get(ctx, 1)
+	}()
+	<-slow.read
+	// link is updated after missed Get read it but before read link is cached
+	if err := cs.Update(ctx, &app.Link{Id: 1, TargetUrl: "https://go.dev/new"}); err != nil {
+		t.Fatal(err)
+	}
+	close(slow.gate)
+	<-done
+	if link, err := cs.Get(ctx, 1); err != nil || link.TargetUrl != "https://go.dev/new" {
+		t.Errorf("Get() after update got = %v, %v, want updated link", link, err)
+	}
+}
@@ -0,0 +1,184 @@
+package mem_store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/nj-eka/shurl/app"
+	"github.com/nj-eka/shurl/config"
+	cu "github.com/nj-eka/shurl/internal/contexts"
+	"github.com/nj-eka/shurl/internal/errs"
+	"github.com/nj-eka/shurl/internal/hll"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var _ app.HitStore = &memHitStore{}
+
+type memHitStore struct {
+	path  string
+	mu    sync.RWMutex
+	hits  map[string][]*app.Hit // link id -> hits ordered by time (string keys for json marshaling)
+	stats map[statsKey]int      // is not saved, it is restored from hits on loading
+	// unique visitors sketches of UniquesGranularity time buckets and of all time (with zero key start),
+	// they are restored from hits on loading as well
+	uniques map[statsKey]*hll.Sketch
+}
+
+type statsKey struct {
+	linkId int
+	g      app.Granularity
+	start  int64
+}
+
+// NewMemHitStore returns hit store kept in memory and saved to cfg.HitsFilePath (if any) on closing
+func NewMemHitStore(ctx context.Context, cfg config.MemStoreConfig) (app.HitStore, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("mem.InitHits"), errs.SetDefaultErrsKind(errs.KindStore))
+	mhs := &memHitStore{path: cfg.HitsFilePath, hits: make(map[string][]*app.Hit), stats: make(map[statsKey]int), uniques: make(map[statsKey]*hll.Sketch)}
+	if mhs.path != "" {
+		if file, err := os.Open(mhs.path); err != nil {
+			if !os.IsNotExist(err) {
+				return nil, errs.E(ctx, fmt.Errorf("opening hits file [%s] failed: %w", mhs.path, err))
+			}
+		} else {
+			defer file.Close()
+			if err := json.NewDecoder(file).Decode(&mhs.hits); err != nil {
+				return nil, errs.E(ctx, fmt.Errorf("decoding hits file [%s] failed: %w", mhs.path, err))
+			}
+			for _, hits := range mhs.hits {
+				for _, hit := range hits {
+					mhs.count(hit)
+				}
+			}
+		}
+	}
+	return mhs, nil
+}
+
+func (mhs *memHitStore) AddHit(ctx context.Context, hit *app.Hit) errs.Error {
+	h := *hit
+	// kept hits are the same as saved ones
+	h.AcceptLanguage, h.Path, h.Query = "", "", nil
+	sid := strconv.Itoa(h.LinkId)
+	mhs.mu.Lock()
+	defer mhs.mu.Unlock()
+	hits := mhs.hits[sid]
+	// hits mostly come in time order, so it is usually append
+	i := sort.Search(len(hits), func(i int) bool { return hits[i].Time.After(h.Time) })
+	hits = append(hits, nil)
+	copy(hits[i+1:], hits[i:])
+	hits[i] = &h
+	mhs.hits[sid] = hits
+	mhs.count(&h)
+	return nil
+}
+
+func (mhs *memHitStore) count(hit *app.Hit) {
+	for _, g := range app.Granularities {
+		mhs.stats[statsKey{linkId: hit.LinkId, g: g, start: g.Truncate(hit.Time).UnixNano()}]++
+	}
+	fp := hit.Fingerprint()
+	for _, key := range []statsKey{
+		{linkId: hit.LinkId, g: app.UniquesGranularity, start: app.UniquesGranularity.Truncate(hit.Time).UnixNano()},
+		{linkId: hit.LinkId},
+	} {
+		sketch, ok := mhs.uniques[key]
+		if !ok {
+			sketch = hll.New()
+			mhs.uniques[key] = sketch
+		}
+		sketch.Add(fp)
+	}
+}
+
+func (mhs *memHitStore) ListHits(ctx context.Context, linkId int, from, to time.Time, limit int) ([]*app.Hit, errs.Error) {
+	mhs.mu.RLock()
+	defer mhs.mu.RUnlock()
+	hits := mhs.hits[strconv.Itoa(linkId)]
+	i := sort.Search(len(hits), func(i int) bool { return !hits[i].Time.Before(from) })
+	result := make([]*app.Hit, 0)
+	for ; i < len(hits) && hits[i].Time.Before(to); i++ {
+		h := *hits[i]
+		result = append(result, &h)
+		if limit > 0 && len(result) == limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+func (mhs *memHitStore) Stats(ctx context.Context, linkId int, g app.Granularity, from, to time.Time) ([]*app.StatsBucket, errs.Error) {
+	mhs.mu.RLock()
+	defer mhs.mu.RUnlock()
+	buckets := make([]*app.StatsBucket, 0)
+	if g.Duration() == 0 {
+		return buckets, nil
+	}
+	for t := g.Truncate(from); t.Before(to); t = t.Add(g.Duration()) {
+		if hits, ok := mhs.stats[statsKey{linkId: linkId, g: g, start: t.UnixNano()}]; ok {
+			buckets = append(buckets, &app.StatsBucket{Start: t, Hits: hits})
+		}
+	}
+	return buckets, nil
+}
+
+func (mhs *memHitStore) Uniques(ctx context.Context, linkId int, from, to time.Time) (int, errs.Error) {
+	mhs.mu.RLock()
+	defer mhs.mu.RUnlock()
+	sketch := hll.New()
+	if from.IsZero() && to.IsZero() {
+		if total, ok := mhs.uniques[statsKey{linkId: linkId}]; ok {
+			sketch.Merge(total)
+		}
+		return int(sketch.Count()), nil
+	}
+	g := app.UniquesGranularity
+	for t := g.Truncate(from); t.Before(to); t = t.Add(g.Duration()) {
+		if bucketSketch, ok := mhs.uniques[statsKey{linkId: linkId, g: g, start: t.UnixNano()}]; ok {
+			sketch.Merge(bucketSketch)
+		}
+	}
+	return int(sketch.Count()), nil
+}
+
+func (mhs *memHitStore) Purge(ctx context.Context, linkId int) (int, errs.Error) {
+	mhs.mu.Lock()
+	defer mhs.mu.Unlock()
+	sid := strconv.Itoa(linkId)
+	count := len(mhs.hits[sid])
+	delete(mhs.hits, sid)
+	for key := range mhs.stats {
+		if key.linkId == linkId {
+			delete(mhs.stats, key)
+			count++
+		}
+	}
+	for key := range mhs.uniques {
+		if key.linkId == linkId {
+			delete(mhs.uniques, key)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (mhs *memHitStore) Close(ctx context.Context) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("mem.CloseHits"), errs.SetDefaultErrsKind(errs.KindStore))
+	if mhs.path == "" {
+		return nil
+	}
+	mhs.mu.RLock()
+	defer mhs.mu.RUnlock()
+	file, err := os.OpenFile(mhs.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return errs.E(ctx, errs.SeverityCritical, fmt.Errorf("opening hits file [%s] failed: %w", mhs.path, err))
+	}
+	defer file.Close()
+	if err := json.NewEncoder(file).Encode(mhs.hits); err != nil {
+		return errs.E(ctx, errs.SeverityCritical, fmt.Errorf("saving hits to file [%s] failed: %w", mhs.path, err))
+	}
+	return nil
+}
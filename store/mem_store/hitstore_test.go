@@ -0,0 +1,183 @@
+package mem_store
+
+import (
+	"context"
+	"fmt"
+	"github.com/nj-eka/shurl/app"
+	"github.com/nj-eka/shurl/config"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_memHitStore(t *testing.T) {
+	ctx := context.Background()
+	hits, err := NewMemHitStore(ctx, config.MemStoreConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = hits.Close(ctx)
+	}()
+	start := time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+	for i, linkId := range []int{1, 2, 1, 2, 1} {
+		if err := hits.AddHit(ctx, &app.Hit{LinkId: linkId, Time: start.Add(time.Duration(i) * time.Minute), ClientIP: "127.0.0.1", UserAgent: fmt.Sprint(i / 2)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	type args struct {
+		linkId int
+		from   time.Time
+		to     time.Time
+		limit  int
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantTimes []time.Time
+	}{
+		{"list all of link 1", args{1, time.Time{}, start.Add(time.Hour), 0}, []time.Time{start, start.Add(2 * time.Minute), start.Add(4 * time.Minute)}},
+		{"list range of link 1", args{1, start.Add(time.Minute), start.Add(4 * time.Minute), 0}, []time.Time{start.Add(2 * time.Minute)}},
+		{"list limited of link 2", args{2, time.Time{}, start.Add(time.Hour), 1}, []time.Time{start.Add(time.Minute)}},
+		{"list not existed", args{3, time.Time{}, start.Add(time.Hour), 0}, []time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotHits, gotErr := hits.ListHits(ctx, tt.args.linkId, tt.args.from, tt.args.to, tt.args.limit)
+			if gotErr != nil {
+				t.Errorf("ListHits() gotErr = %v", gotErr)
+				return
+			}
+			if len(gotHits) != len(tt.wantTimes) {
+				t.Errorf("ListHits() got = %v, want %v", gotHits, tt.wantTimes)
+				return
+			}
+			for i, hit := range gotHits {
+				if hit.LinkId != tt.args.linkId || !hit.Time.Equal(tt.wantTimes[i]) {
+					t.Errorf("ListHits() got = %v, want time %v", hit, tt.wantTimes[i])
+				}
+			}
+		})
+	}
+	statsTests := []struct {
+		name      string
+		linkId    int
+		g         app.Granularity
+		from, to  time.Time
+		wantStats []app.StatsBucket
+	}{
+		{"hourly stats of link 1", 1, app.GranularityHour, start.Add(-time.Hour), start.Add(time.Hour), []app.StatsBucket{{Start: start, Hits: 3}}},
+		{"daily stats of link 2", 2, app.GranularityDay, start.AddDate(0, 0, -1), start.AddDate(0, 0, 1), []app.StatsBucket{{Start: start.Truncate(24 * time.Hour), Hits: 2}}},
+		{"hourly stats out of range", 1, app.GranularityHour, start.Add(time.Hour), start.Add(2 * time.Hour), []app.StatsBucket{}},
+	}
+	for _, tt := range statsTests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStats, gotErr := hits.Stats(ctx, tt.linkId, tt.g, tt.from, tt.to)
+			if gotErr != nil {
+				t.Errorf("Stats() gotErr = %v", gotErr)
+				return
+			}
+			if len(gotStats) != len(tt.wantStats) {
+				t.Errorf("Stats() got = %v, want %v", gotStats, tt.wantStats)
+				return
+			}
+			for i, bucket := range gotStats {
+				if !bucket.Start.Equal(tt.wantStats[i].Start) || bucket.Hits != tt.wantStats[i].Hits {
+					t.Errorf("Stats() got = %v, want %v", bucket, tt.wantStats[i])
+				}
+			}
+		})
+	}
+	uniquesTests := []struct {
+		name     string
+		linkId   int
+		from, to time.Time
+		want     int
+	}{
+		{"all time uniques of link 1", 1, time.Time{}, time.Time{}, 3},
+		{"all time uniques of link 2", 2, time.Time{}, time.Time{}, 2},
+		{"daily uniques of link 1", 1, start.Truncate(24 * time.Hour), start.AddDate(0, 0, 1), 3},
+		{"uniques out of range", 1, start.AddDate(0, 0, 1), start.AddDate(0, 0, 2), 0},
+		{"uniques of not existed", 3, time.Time{}, time.Time{}, 0},
+	}
+	for _, tt := range uniquesTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotErr := hits.Uniques(ctx, tt.linkId, tt.from, tt.to)
+			if gotErr != nil {
+				t.Errorf("Uniques() gotErr = %v", gotErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Uniques() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_memHitStore_NotRecorded(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.MemStoreConfig{HitsFilePath: filepath.Join(t.TempDir(), "hits.json")}
+	hits, err := NewMemHitStore(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2021, 11, 1, 12, 0, 0, 0, time.UTC)
+	hit := &app.Hit{LinkId: 1, Time: at, ClientIP: "127.0.0.1", AcceptLanguage: "en", Path: "/private", Query: url.Values{"token": {"s3cret"}}}
+	if err := hits.AddHit(ctx, hit); err != nil {
+		t.Fatal(err)
+	}
+	if err := hits.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if saved, err := os.ReadFile(cfg.HitsFilePath); err != nil {
+		t.Fatal(err)
+	} else if strings.Contains(string(saved), "s3cret") || strings.Contains(string(saved), "/private") {
+		t.Errorf("hits file got = %s, want no path and query", saved)
+	}
+	if hits, err = NewMemHitStore(ctx, cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = hits.Close(ctx)
+	}()
+	gotHits, gotErr := hits.ListHits(ctx, 1, time.Time{}, at.Add(time.Hour), 0)
+	if gotErr != nil || len(gotHits) != 1 {
+		t.Fatalf("ListHits() got = %v, %v, want 1 hit", gotHits, gotErr)
+	}
+	if got := gotHits[0]; got.ClientIP != hit.ClientIP || got.AcceptLanguage != "" || got.Path != "" || got.Query != nil {
+		t.Errorf("ListHits() got = %+v, want hit without accept language, path and query", got)
+	}
+}
+
+func Test_memHitStore_Purge(t *testing.T) {
+	ctx := context.Background()
+	hits, err := NewMemHitStore(ctx, config.MemStoreConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = hits.Close(ctx)
+	}()
+	at := time.Date(2021, 11, 1, 12, 0, 0, 0, time.UTC)
+	for _, linkId := range []int{1, 1, 2} {
+		if err := hits.AddHit(ctx, &app.Hit{LinkId: linkId, Time: at, ClientIP: "127.0.0.1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 2 hits, hourly and daily counters, daily and all time sketches
+	if got, err := hits.Purge(ctx, 1); err != nil || got != 6 {
+		t.Errorf("Purge() got = %v, %v, want %v", got, err, 6)
+	}
+	if got, err := hits.Purge(ctx, 1); err != nil || got != 0 {
+		t.Errorf("Purge() of purged got = %v, %v, want %v", got, err, 0)
+	}
+	if got, err := hits.ListHits(ctx, 2, time.Time{}, at.Add(time.Hour), 0); err != nil || len(got) != 1 {
+		t.Errorf("ListHits() of another link got = %v, %v, want 1 hit", got, err)
+	}
+	if got, err := hits.Uniques(ctx, 1, time.Time{}, time.Time{}); err != nil || got != 0 {
+		t.Errorf("Uniques() of purged got = %v, %v, want %v", got, err, 0)
+	}
+}
@@ -1,14 +1,84 @@
 package mem_store
 
 import (
+	"github.com/nj-eka/shurl/app"
 	"time"
 )
 
 type Link struct {
-	Id        int        `json:"id"`
-	TargetUrl string     `json:"url"`
-	CreatedAt time.Time  `json:"ct"`
-	DeletedAt *time.Time `json:"dt"`
-	ExpiredAt *time.Time `json:"et"`
-	Hits      int        `json:"hs"`
+	Id               int                  `json:"id"`
+	TargetUrl        string               `json:"url"`
+	Owner            string               `json:"ow,omitempty"`
+	Alias            string               `json:"al,omitempty"`
+	PasswordHash     string               `json:"pw,omitempty"`
+	CreatedAt        time.Time            `json:"ct"`
+	DeletedAt        *time.Time           `json:"dt"`
+	ExpiredAt        *time.Time           `json:"et"`
+	ActiveFrom       *time.Time           `json:"af,omitempty"`
+	Hits             int                  `json:"hs"`
+	MaxHits          int                  `json:"mh,omitempty"`
+	RedirectType     int                  `json:"rt,omitempty"`
+	QueryPassthrough app.QueryPassthrough `json:"qp,omitempty"`
+	PathPassthrough  bool                 `json:"pp,omitempty"`
+	Utm              *app.Utm             `json:"utm,omitempty"`
+	Rules            []app.Rule           `json:"rs,omitempty"`
+}
+
+func (l *Link) toApp() *app.Link {
+	return &app.Link{
+		Id:               l.Id,
+		Alias:            l.Alias,
+		TargetUrl:        l.TargetUrl,
+		Owner:            l.Owner,
+		PasswordHash:     l.PasswordHash,
+		CreatedAt:        l.CreatedAt,
+		ExpiredAt:        l.ExpiredAt,
+		ActiveFrom:       l.ActiveFrom,
+		DeletedAt:        l.DeletedAt,
+		Hits:             l.Hits,
+		MaxHits:          l.MaxHits,
+		RedirectType:     l.RedirectType,
+		QueryPassthrough: l.QueryPassthrough,
+		PathPassthrough:  l.PathPassthrough,
+		Utm:              l.Utm,
+		Rules:            l.Rules,
+	}
+}
+
+func fromApp(link *app.Link) *Link {
+	return &Link{
+		Id:               link.Id,
+		Alias:            link.Alias,
+		TargetUrl:        link.TargetUrl,
+		Owner:            link.Owner,
+		PasswordHash:     link.PasswordHash,
+		CreatedAt:        link.CreatedAt,
+		ExpiredAt:        link.ExpiredAt,
+		ActiveFrom:       link.ActiveFrom,
+		DeletedAt:        link.DeletedAt,
+		Hits:             link.Hits,
+		MaxHits:          link.MaxHits,
+		RedirectType:     link.RedirectType,
+		QueryPassthrough: link.QueryPassthrough,
+		PathPassthrough:  link.PathPassthrough,
+		Utm:              link.Utm,
+		Rules:            link.Rules,
+	}
+}
+
+// ownerUrl is key of urls index: url is unique among links of the same owner
+func ownerUrl(owner, url string) string {
+	return owner + "\n" + url
+}
+
+type ApiKey struct {
+	Id        string    `json:"id"`
+	Owner     string    `json:"ow"`
+	Hash      string    `json:"hash"`
+	Admin     bool      `json:"adm,omitempty"`
+	CreatedAt time.Time `json:"ct"`
+}
+
+func (k *ApiKey) toApp() *app.ApiKey {
+	return &app.ApiKey{Id: k.Id, Owner: k.Owner, Hash: k.Hash, Admin: k.Admin, CreatedAt: k.CreatedAt}
 }
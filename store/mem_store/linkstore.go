@@ -8,14 +8,29 @@ import (
 	cu "github.com/nj-eka/shurl/internal/contexts"
 	"github.com/nj-eka/shurl/internal/errs"
 	"github.com/nj-eka/shurl/utils/strutils"
-	"time"
 )
 
+var _ app.KeyStore = &memLinkStore{}
+
 func NewMemStore(ctx context.Context, cfg config.MemStoreConfig) (app.LinkStore, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("mem.Init"), errs.SetDefaultErrsKind(errs.KindStore))
+	switch cfg.Fsync {
+	case "":
+		cfg.Fsync = FsyncInterval
+	case FsyncAlways, FsyncInterval, FsyncNever:
+	default:
+		return nil, errs.E(ctx, errs.KindInvalidValue, fmt.Errorf("invalid fsync policy [%s]", cfg.Fsync))
+	}
+	if cfg.FsyncInterval <= 0 {
+		cfg.FsyncInterval = DefaultFsyncInterval
+	}
+	if cfg.CompactInterval <= 0 {
+		cfg.CompactInterval = DefaultCompactInterval
+	}
 	done := make(chan struct{})
-	mlm, err := newMapManager(done, cfg.FilePath)
+	mlm, err := newMapManager(ctx, done, cfg)
 	if err != nil {
-		return nil, errs.E(ctx, errs.KindStore, fmt.Errorf("init mem store with path [%s] failed: %w", cfg.FilePath, err))
+		return nil, errs.E(ctx, fmt.Errorf("init mem store with path [%s] failed: %w", cfg.FilePath, err))
 	}
 	return &memLinkStore{done: done, mlm: mlm}, nil
 }
@@ -34,14 +49,17 @@ func (mls *memLinkStore) Close(ctx context.Context) errs.Error {
 	return nil
 }
 
-func (mls *memLinkStore) Create(ctx context.Context, targetUrl string, expiredAt *time.Time) (int, bool, errs.Error) {
+func (mls *memLinkStore) Create(ctx context.Context, link *app.Link) (int, bool, errs.Error) {
 	ctx = cu.BuildContext(ctx, cu.AddContextOperation("mem.Create"), errs.SetDefaultErrsKind(errs.KindStore))
-	id, added, err := mls.mlm.addLink(targetUrl, expiredAt)
+	id, added, err := mls.mlm.addLink(fromApp(link))
 	if err != nil {
 		if err == ErrNotFound {
 			return id, added, errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
 		}
-		return id, added, errs.E(ctx, fmt.Errorf("adding link [%s] failed: %w", strutils.Truncate(targetUrl, 24, "..."), err))
+		if err == ErrAliasExists {
+			return id, added, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("adding link with alias [%s] failed: %w", link.Alias, app.ErrAliasExists))
+		}
+		return id, added, errs.E(ctx, fmt.Errorf("adding link [%s] failed: %w", strutils.Truncate(link.TargetUrl, 24, "..."), err))
 	}
 	return id, added, nil
 }
@@ -54,15 +72,29 @@ func (mls *memLinkStore) Get(ctx context.Context, id int) (*app.Link, errs.Error
 		}
 		return nil, errs.E(ctx, fmt.Errorf("getting link with id [%d] failed: %w", id, err))
 	} else {
-		return &app.Link{
-			Id:        link.Id,
-			TargetUrl: link.TargetUrl,
-			CreatedAt: link.CreatedAt,
-			ExpiredAt: link.ExpiredAt,
-			DeletedAt: link.DeletedAt,
-			Hits:      link.Hits,
-		}, nil
+		return link.toApp(), nil
+	}
+}
+
+func (mls *memLinkStore) Lookup(ctx context.Context, alias string) (int, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("mem.Lookup"), errs.SetDefaultErrsKind(errs.KindStore))
+	if id, err := mls.mlm.lookupAlias(alias); err != nil {
+		if err == ErrNotFound {
+			return -1, errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return -1, errs.E(ctx, fmt.Errorf("looking up link with alias [%s] failed: %w", alias, err))
+	} else {
+		return id, nil
+	}
+}
+
+func (mls *memLinkStore) List(ctx context.Context, q app.ListQuery) ([]*app.Link, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("mem.List"), errs.SetDefaultErrsKind(errs.KindStore))
+	links, err := mls.mlm.getLinks(&q)
+	if err != nil {
+		return nil, errs.E(ctx, fmt.Errorf("listing links failed: %w", err))
 	}
+	return q.Sort(links), nil
 }
 
 func (mls *memLinkStore) Hit(ctx context.Context, id int) (*app.Link, errs.Error) {
@@ -71,19 +103,48 @@ func (mls *memLinkStore) Hit(ctx context.Context, id int) (*app.Link, errs.Error
 		if err == ErrNotFound {
 			return nil, errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
 		}
+		if err == ErrExhausted {
+			return nil, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("hitting link with id [%d] failed: %w", id, app.ErrHitsExhausted))
+		}
 		return nil, errs.E(ctx, fmt.Errorf("hitting link with id [%d] failed: %w", id, err))
 	} else {
-		return &app.Link{
-			Id:        link.Id,
-			TargetUrl: link.TargetUrl,
-			CreatedAt: link.CreatedAt,
-			ExpiredAt: link.ExpiredAt,
-			DeletedAt: link.DeletedAt,
-			Hits:      link.Hits,
-		}, nil
+		return link.toApp(), nil
 	}
 }
 
+func (mls *memLinkStore) AddHits(ctx context.Context, deltas map[int]int) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("mem.AddHits"), errs.SetDefaultErrsKind(errs.KindStore))
+	if err := mls.mlm.addHits(deltas); err != nil {
+		return errs.E(ctx, fmt.Errorf("adding hits of [%d] links failed: %w", len(deltas), err))
+	}
+	return nil
+}
+
+func (mls *memLinkStore) Update(ctx context.Context, link *app.Link) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("mem.Update"), errs.SetDefaultErrsKind(errs.KindStore))
+	if err := mls.mlm.updateLink(link.Id, link.TargetUrl, link.ExpiredAt, link.Rules); err != nil {
+		switch err {
+		case ErrNotFound:
+			return errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		case ErrConflict:
+			return errs.E(ctx, errs.SeverityWarning, fmt.Errorf("updating link with id [%d] failed: %w", link.Id, app.ErrConflict))
+		}
+		return errs.E(ctx, fmt.Errorf("updating link with id [%d] failed: %w", link.Id, err))
+	}
+	return nil
+}
+
+func (mls *memLinkStore) Import(ctx context.Context, link *app.Link) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("mem.Import"), errs.SetDefaultErrsKind(errs.KindStore))
+	if err := mls.mlm.importLink(fromApp(link)); err != nil {
+		if err == ErrConflict {
+			return errs.E(ctx, errs.SeverityWarning, fmt.Errorf("importing link with id [%d] failed: %w", link.Id, app.ErrConflict))
+		}
+		return errs.E(ctx, fmt.Errorf("importing link with id [%d] failed: %w", link.Id, err))
+	}
+	return nil
+}
+
 func (mls *memLinkStore) SetDeleted(ctx context.Context, id int) errs.Error {
 	ctx = cu.BuildContext(ctx, cu.AddContextOperation("mem.SetDeleted"), errs.SetDefaultErrsKind(errs.KindStore))
 	if err := mls.mlm.setLinkDeleted(id); err != nil {
@@ -95,6 +156,17 @@ func (mls *memLinkStore) SetDeleted(ctx context.Context, id int) errs.Error {
 	return nil
 }
 
+func (mls *memLinkStore) Restore(ctx context.Context, id int) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("mem.Restore"), errs.SetDefaultErrsKind(errs.KindStore))
+	if err := mls.mlm.restoreLink(id); err != nil {
+		if err == ErrNotFound {
+			return errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return errs.E(ctx, fmt.Errorf("restoring link with id [%d] failed: %w", id, err))
+	}
+	return nil
+}
+
 func (mls *memLinkStore) Delete(ctx context.Context, id int) errs.Error {
 	ctx = cu.BuildContext(ctx, cu.AddContextOperation("mem.Delete"), errs.SetDefaultErrsKind(errs.KindStore))
 	if err := mls.mlm.removeLink(id); err != nil {
@@ -105,3 +177,47 @@ func (mls *memLinkStore) Delete(ctx context.Context, id int) errs.Error {
 	}
 	return nil
 }
+
+func (mls *memLinkStore) AddKey(ctx context.Context, key *app.ApiKey) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("mem.AddKey"), errs.SetDefaultErrsKind(errs.KindStore))
+	k := &ApiKey{Id: key.Id, Owner: key.Owner, Hash: key.Hash, Admin: key.Admin, CreatedAt: key.CreatedAt}
+	if err := mls.mlm.addKey(k); err != nil {
+		if err == ErrConflict {
+			return errs.E(ctx, errs.SeverityWarning, fmt.Errorf("api key [%s]: %w", key.Id, app.ErrConflict))
+		}
+		return errs.E(ctx, fmt.Errorf("adding api key [%s] failed: %w", key.Id, err))
+	}
+	return nil
+}
+
+func (mls *memLinkStore) GetKey(ctx context.Context, id string) (*app.ApiKey, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("mem.GetKey"), errs.SetDefaultErrsKind(errs.KindStore))
+	key, err := mls.mlm.getKey(id)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return nil, errs.E(ctx, fmt.Errorf("getting api key [%s] failed: %w", id, err))
+	}
+	return key, nil
+}
+
+func (mls *memLinkStore) ListKeys(ctx context.Context) ([]*app.ApiKey, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("mem.ListKeys"), errs.SetDefaultErrsKind(errs.KindStore))
+	keys, err := mls.mlm.getKeys()
+	if err != nil {
+		return nil, errs.E(ctx, fmt.Errorf("listing api keys failed: %w", err))
+	}
+	return keys, nil
+}
+
+func (mls *memLinkStore) DeleteKey(ctx context.Context, id string) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("mem.DeleteKey"), errs.SetDefaultErrsKind(errs.KindStore))
+	if err := mls.mlm.removeKey(id); err != nil {
+		if err == ErrNotFound {
+			return errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return errs.E(ctx, fmt.Errorf("deleting api key [%s] failed: %w", id, err))
+	}
+	return nil
+}
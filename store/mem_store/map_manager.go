@@ -1,8 +1,14 @@
 package mem_store
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"github.com/nj-eka/shurl/app"
+	"github.com/nj-eka/shurl/config"
+	"github.com/nj-eka/shurl/internal/errs"
+	"github.com/nj-eka/shurl/internal/logging"
 	"os"
 	"strconv"
 	"sync"
@@ -12,17 +18,25 @@ import (
 var ErrClosed = errors.New("closed")
 var ErrNotFound = errors.New("not found")
 var ErrInvalidValue = errors.New("invalid value")
+var ErrAliasExists = errors.New("alias already exists")
+var ErrConflict = errors.New("url or alias belongs to another link")
+var ErrExhausted = errors.New("hits exhausted")
 
 type mapLinkManager struct {
+	ctx          context.Context
 	path         string
 	stop         <-chan struct{}
 	completed    chan struct{}
 	err          error
 	mapLinks     map[string]*Link // int -> string for json marshaling
 	mapIndexUrls map[string]string
+	mapAliases   map[string]string
+	mapKeys      map[string]*ApiKey
 	chOps        chan request
 	wg           sync.WaitGroup
 	next         int
+	wal          *wal // nil if links aren't saved
+	cfg          config.MemStoreConfig
 }
 
 type request map[string]interface{}
@@ -32,46 +46,93 @@ type response struct {
 	err   error
 }
 
+// snapshot is content of snapshot file, files of previous versions keep links only (plain map of links),
+// Next is the last allocated id (ids of removed links are never reused, so it can't be derived from links)
+type snapshot struct {
+	Links map[string]*Link   `json:"links"`
+	Keys  map[string]*ApiKey `json:"keys,omitempty"`
+	Next  int                `json:"next,omitempty"`
+}
+
 type addedResult struct {
 	id    int
 	added bool
 }
 
-func newMapManager(stop <-chan struct{}, path string) (*mapLinkManager, error) {
-	mapLinks := make(map[string]*Link)
-	mapIndexUrls := make(map[string]string)
-	next := 0
-	if path != "" {
-		if file, err := os.OpenFile(path, os.O_RDONLY, 0); err != nil {
+// newMapManager loads links from snapshot (cfg.FilePath) and replays write-ahead log (cfg.FilePath + ".wal") over them
+func newMapManager(ctx context.Context, stop <-chan struct{}, cfg config.MemStoreConfig) (*mapLinkManager, error) {
+	snap := snapshot{Links: make(map[string]*Link), Keys: make(map[string]*ApiKey)}
+	if cfg.FilePath != "" {
+		if file, err := os.OpenFile(cfg.FilePath, os.O_RDONLY, 0); err != nil {
 			if !os.IsNotExist(err) {
 				return nil, err
 			}
 		} else {
 			defer file.Close()
-			if err := json.NewDecoder(file).Decode(&mapLinks); err != nil {
+			var content map[string]json.RawMessage
+			if err := json.NewDecoder(file).Decode(&content); err != nil {
 				return nil, err
 			}
-			mapIndexUrls = make(map[string]string, len(mapLinks))
-			for cid, link := range mapLinks {
-				mapIndexUrls[link.TargetUrl] = cid
-				if next < link.Id {
-					next = link.Id
+			if data, ok := content["links"]; ok {
+				if err := json.Unmarshal(data, &snap.Links); err != nil {
+					return nil, err
+				}
+				if data, ok = content["keys"]; ok {
+					if err := json.Unmarshal(data, &snap.Keys); err != nil {
+						return nil, err
+					}
+				}
+				if data, ok = content["next"]; ok {
+					if err := json.Unmarshal(data, &snap.Next); err != nil {
+						return nil, err
+					}
+				}
+			} else {
+				for sid, data := range content {
+					link := &Link{}
+					if err := json.Unmarshal(data, link); err != nil {
+						return nil, err
+					}
+					snap.Links[sid] = link
 				}
 			}
 		}
 	}
+	mapLinks := snap.Links
 	ms := mapLinkManager{
-		path:         path,
+		ctx:          ctx,
+		path:         cfg.FilePath,
 		stop:         stop,
-		mapLinks:     mapLinks,
-		mapIndexUrls: mapIndexUrls,
-		next:         next,
+		mapLinks:     make(map[string]*Link, len(mapLinks)),
+		mapIndexUrls: make(map[string]string, len(mapLinks)),
+		mapAliases:   make(map[string]string),
+		mapKeys:      make(map[string]*ApiKey),
+		cfg:          cfg,
 		// buffer length doesn't matter here in fact cuz blocking will be in any case, whether it is writing or reading
 		// operations are serialized / linearized as an alternative to mutex, but with the possibility of unified logging of operations
 		chOps:     make(chan request),
 		completed: make(chan struct{}),
 		wg:        sync.WaitGroup{},
 	}
+	for _, link := range mapLinks {
+		ms.put(link)
+	}
+	if ms.next < snap.Next {
+		ms.next = snap.Next
+	}
+	for id, key := range snap.Keys {
+		ms.mapKeys[id] = key
+	}
+	if cfg.FilePath != "" {
+		w, records, err := openWal(cfg.FilePath+".wal", cfg.Fsync)
+		if err != nil {
+			return nil, fmt.Errorf("opening wal failed: %w", err)
+		}
+		for _, rec := range records {
+			ms.apply(rec)
+		}
+		ms.wal = w
+	}
 	ms.startProcessOperations()
 	return &ms, nil
 }
@@ -83,36 +144,51 @@ func (mlm *mapLinkManager) startProcessOperations() {
 		go func() {
 			mlm.wg.Wait() // wait for last operation being completed
 			close(mlm.chOps)
-			mlm.save()
 		}()
 	}()
+	if mlm.wal != nil {
+		go mlm.maintainWal(mlm.stop)
+	}
 	go func() {
 		defer close(mlm.completed)
+		defer mlm.save() // links are saved before completion
 		for request := range mlm.chOps {
 			op := request["op"]
 			switch {
 			case op == "addLink":
 				resCh := request["rc"].(chan response)
-				url := request["url"].(string)
-				var link *Link
-				var sid string
-				var ok bool
-				if sid, ok = mlm.mapIndexUrls[url]; !ok {
-					mlm.next++
-					sid = strconv.Itoa(mlm.next)
-					link = &Link{
-						Id:        mlm.next,
-						TargetUrl: url,
-						CreatedAt: time.Now().UTC(),
-						DeletedAt: nil,
-						ExpiredAt: request["expiredAt"].(*time.Time),
-						Hits:      0,
+				proto := request["link"].(*Link)
+				alias := proto.Alias
+				var rec *walRecord
+				sid, ok := mlm.mapIndexUrls[ownerUrl(proto.Owner, proto.TargetUrl)]
+				if !ok {
+					if _, taken := mlm.mapAliases[alias]; alias != "" && taken {
+						resCh <- response{err: ErrAliasExists}
+						continue
+					}
+					sid = strconv.Itoa(mlm.next + 1)
+					added := *proto
+					added.Id = mlm.next + 1
+					added.CreatedAt = time.Now().UTC()
+					added.DeletedAt = nil
+					added.Hits = 0
+					rec = &walRecord{Op: "addLink", Link: &added, Next: added.Id}
+				} else if link := mlm.mapLinks[sid]; alias != "" && alias != link.Alias {
+					if _, taken := mlm.mapAliases[alias]; taken || link.Alias != "" {
+						resCh <- response{err: ErrAliasExists}
+						continue
 					}
-					mlm.mapLinks[sid] = link
-					mlm.mapIndexUrls[url] = sid
+					updated := *link
+					updated.Alias = alias
+					rec = &walRecord{Op: "addLink", Link: &updated}
 				}
-				link = mlm.mapLinks[sid]
-				resCh <- response{value: &addedResult{id: link.Id, added: !ok}}
+				if rec != nil {
+					if err := mlm.commit(rec); err != nil {
+						resCh <- response{err: err}
+						continue
+					}
+				}
+				resCh <- response{value: &addedResult{id: mlm.mapLinks[sid].Id, added: !ok}}
 			case op == "getLink":
 				sid := strconv.Itoa(request["id"].(int))
 				resCh := request["rc"].(chan response)
@@ -121,35 +197,151 @@ func (mlm *mapLinkManager) startProcessOperations() {
 				} else {
 					resCh <- response{err: ErrNotFound}
 				}
+			case op == "lookupAlias":
+				resCh := request["rc"].(chan response)
+				if sid, ok := mlm.mapAliases[request["alias"].(string)]; ok {
+					resCh <- response{value: mlm.mapLinks[sid].Id}
+				} else {
+					resCh <- response{err: ErrNotFound}
+				}
+			case op == "getLinks":
+				q := request["query"].(*app.ListQuery)
+				resCh := request["rc"].(chan response)
+				links := make([]*app.Link, 0)
+				for _, link := range mlm.mapLinks {
+					// copies are made here to be safe from concurrent modifications
+					if al := link.toApp(); q.Match(al) {
+						links = append(links, al)
+					}
+				}
+				resCh <- response{value: links}
 			case op == "hitLink":
 				sid := strconv.Itoa(request["id"].(int))
 				resCh := request["rc"].(chan response)
 				if link, ok := mlm.mapLinks[sid]; ok {
-					link.Hits++
+					if link.MaxHits > 0 && link.Hits >= link.MaxHits {
+						resCh <- response{err: ErrExhausted}
+						continue
+					}
+					if err := mlm.commit(&walRecord{Op: "hitLink", Hits: map[string]int{sid: link.Hits + 1}}); err != nil {
+						resCh <- response{err: err}
+						continue
+					}
 					resCh <- response{value: link}
 				} else {
 					resCh <- response{err: ErrNotFound}
 				}
+			case op == "addHits":
+				resCh := request["rc"].(chan response)
+				hits := make(map[string]int)
+				for id, delta := range request["deltas"].(map[int]int) {
+					sid := strconv.Itoa(id)
+					if link, ok := mlm.mapLinks[sid]; ok {
+						hits[sid] = link.Hits + delta
+					}
+				}
+				if len(hits) > 0 {
+					resCh <- response{err: mlm.commit(&walRecord{Op: "addHits", Hits: hits})}
+					continue
+				}
+				resCh <- response{}
+			case op == "updateLink":
+				id := request["id"].(int)
+				url := request["url"].(string)
+				resCh := request["rc"].(chan response)
+				sid := strconv.Itoa(id)
+				link, ok := mlm.mapLinks[sid]
+				if !ok {
+					resCh <- response{err: ErrNotFound}
+					continue
+				}
+				if cid, taken := mlm.mapIndexUrls[ownerUrl(link.Owner, url)]; taken && cid != sid {
+					resCh <- response{err: ErrConflict}
+					continue
+				}
+				updated := *link
+				updated.TargetUrl = url
+				updated.ExpiredAt = request["expiredAt"].(*time.Time)
+				updated.Rules = request["rules"].([]app.Rule)
+				resCh <- response{err: mlm.commit(&walRecord{Op: "updateLink", Link: &updated})}
+			case op == "importLink":
+				resCh := request["rc"].(chan response)
+				link := request["link"].(*Link)
+				sid := strconv.Itoa(link.Id)
+				if cid, ok := mlm.mapIndexUrls[ownerUrl(link.Owner, link.TargetUrl)]; ok && cid != sid {
+					resCh <- response{err: ErrConflict}
+					continue
+				}
+				if cid, ok := mlm.mapAliases[link.Alias]; ok && link.Alias != "" && cid != sid {
+					resCh <- response{err: ErrConflict}
+					continue
+				}
+				resCh <- response{err: mlm.commit(&walRecord{Op: "importLink", Link: link})}
 			case op == "setLinkDeleted":
-				sid := strconv.Itoa(request["id"].(int))
+				id := request["id"].(int)
 				resCh := request["rc"].(chan response)
-				if link, ok := mlm.mapLinks[sid]; ok {
+				if link, ok := mlm.mapLinks[strconv.Itoa(id)]; ok {
 					dt := time.Now().UTC()
-					link.DeletedAt = &dt
+					if err := mlm.commit(&walRecord{Op: "setLinkDeleted", Id: id, At: &dt}); err != nil {
+						resCh <- response{err: err}
+						continue
+					}
 					resCh <- response{value: link}
 				} else {
 					resCh <- response{err: ErrNotFound}
 				}
+			case op == "restoreLink":
+				id := request["id"].(int)
+				resCh := request["rc"].(chan response)
+				if _, ok := mlm.mapLinks[strconv.Itoa(id)]; ok {
+					resCh <- response{err: mlm.commit(&walRecord{Op: "restoreLink", Id: id})}
+				} else {
+					resCh <- response{err: ErrNotFound}
+				}
 			case op == "removeLink":
-				sid := strconv.Itoa(request["id"].(int))
+				id := request["id"].(int)
 				resCh := request["rc"].(chan response)
-				if link, ok := mlm.mapLinks[sid]; ok {
-					delete(mlm.mapIndexUrls, link.TargetUrl)
-					delete(mlm.mapLinks, sid)
-					resCh <- response{}
+				if _, ok := mlm.mapLinks[strconv.Itoa(id)]; ok {
+					resCh <- response{err: mlm.commit(&walRecord{Op: "removeLink", Id: id})}
 				} else {
 					resCh <- response{err: ErrNotFound}
 				}
+			case op == "addKey":
+				resCh := request["rc"].(chan response)
+				key := request["key"].(*ApiKey)
+				if _, ok := mlm.mapKeys[key.Id]; ok {
+					resCh <- response{err: ErrConflict}
+					continue
+				}
+				resCh <- response{err: mlm.commit(&walRecord{Op: "addKey", Key: key})}
+			case op == "getKey":
+				resCh := request["rc"].(chan response)
+				if key, ok := mlm.mapKeys[request["id"].(string)]; ok {
+					resCh <- response{value: key.toApp()}
+				} else {
+					resCh <- response{err: ErrNotFound}
+				}
+			case op == "getKeys":
+				resCh := request["rc"].(chan response)
+				keys := make([]*app.ApiKey, 0, len(mlm.mapKeys))
+				for _, key := range mlm.mapKeys {
+					keys = append(keys, key.toApp())
+				}
+				resCh <- response{value: keys}
+			case op == "removeKey":
+				id := request["id"].(string)
+				resCh := request["rc"].(chan response)
+				if _, ok := mlm.mapKeys[id]; ok {
+					resCh <- response{err: mlm.commit(&walRecord{Op: "removeKey", Kid: id})}
+				} else {
+					resCh <- response{err: ErrNotFound}
+				}
+			case op == "syncWal":
+				resCh := request["rc"].(chan response)
+				resCh <- response{err: mlm.wal.sync()}
+			case op == "compact":
+				resCh := request["rc"].(chan response)
+				resCh <- response{err: mlm.compact()}
 			default:
 				// don't panic
 				continue
@@ -158,7 +350,99 @@ func (mlm *mapLinkManager) startProcessOperations() {
 	}()
 }
 
-func (mlm *mapLinkManager) addLink(url string, expiredAt *time.Time) (int, bool, error) {
+// commit writes record to wal (if any) ahead of applying it
+func (mlm *mapLinkManager) commit(rec *walRecord) error {
+	if mlm.wal != nil {
+		if err := mlm.wal.append(rec); err != nil {
+			return fmt.Errorf("writing wal failed: %w", err)
+		}
+	}
+	mlm.apply(rec)
+	return nil
+}
+
+// apply makes changes of record, it's used for both ops and wal replaying
+func (mlm *mapLinkManager) apply(rec *walRecord) {
+	switch rec.Op {
+	case "addLink", "updateLink", "importLink":
+		mlm.put(rec.Link)
+		if mlm.next < rec.Next {
+			mlm.next = rec.Next
+		}
+	case "hitLink", "addHits":
+		for sid, hits := range rec.Hits {
+			if link, ok := mlm.mapLinks[sid]; ok {
+				link.Hits = hits
+			}
+		}
+	case "setLinkDeleted", "restoreLink":
+		if link, ok := mlm.mapLinks[strconv.Itoa(rec.Id)]; ok {
+			link.DeletedAt = rec.At
+		}
+	case "removeLink":
+		// next is left as is, so id of removed link is not allocated again
+		sid := strconv.Itoa(rec.Id)
+		if link, ok := mlm.mapLinks[sid]; ok {
+			delete(mlm.mapIndexUrls, ownerUrl(link.Owner, link.TargetUrl))
+			if link.Alias != "" {
+				delete(mlm.mapAliases, link.Alias)
+			}
+			delete(mlm.mapLinks, sid)
+		}
+	case "addKey":
+		mlm.mapKeys[rec.Key.Id] = rec.Key
+	case "removeKey":
+		delete(mlm.mapKeys, rec.Kid)
+	}
+}
+
+// put sets link replacing the one with the same id
+func (mlm *mapLinkManager) put(link *Link) {
+	sid := strconv.Itoa(link.Id)
+	if prev, ok := mlm.mapLinks[sid]; ok {
+		delete(mlm.mapIndexUrls, ownerUrl(prev.Owner, prev.TargetUrl))
+		if prev.Alias != "" {
+			delete(mlm.mapAliases, prev.Alias)
+		}
+	}
+	mlm.mapLinks[sid] = link
+	mlm.mapIndexUrls[ownerUrl(link.Owner, link.TargetUrl)] = sid
+	if link.Alias != "" {
+		mlm.mapAliases[link.Alias] = sid
+	}
+	if mlm.next < link.Id {
+		mlm.next = link.Id
+	}
+}
+
+// maintainWal syncs wal (according to fsync policy) and compacts it into snapshot periodically till stop
+func (mlm *mapLinkManager) maintainWal(stop <-chan struct{}) {
+	var syncCh <-chan time.Time
+	if mlm.cfg.Fsync == FsyncInterval {
+		syncTicker := time.NewTicker(mlm.cfg.FsyncInterval)
+		defer syncTicker.Stop()
+		syncCh = syncTicker.C
+	}
+	compactTicker := time.NewTicker(mlm.cfg.CompactInterval)
+	defer compactTicker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-syncCh:
+			if err := mlm.do("syncWal"); err != nil && err != ErrClosed {
+				logging.LogError(mlm.ctx, errs.KindStore, fmt.Errorf("syncing wal failed: %w", err))
+			}
+		case <-compactTicker.C:
+			if err := mlm.do("compact"); err != nil && err != ErrClosed {
+				logging.LogError(mlm.ctx, errs.KindStore, fmt.Errorf("compacting wal failed: %w", err))
+			}
+		}
+	}
+}
+
+// addLink adds link made of proto (id, times and hits are set by manager) unless there is one with the same url of the same owner
+func (mlm *mapLinkManager) addLink(proto *Link) (int, bool, error) {
 	mlm.wg.Add(1)
 	defer mlm.wg.Done()
 	if mlm.stop == nil {
@@ -166,8 +450,7 @@ func (mlm *mapLinkManager) addLink(url string, expiredAt *time.Time) (int, bool,
 	}
 	request := make(request)
 	request["op"] = "addLink"
-	request["url"] = url
-	request["expiredAt"] = expiredAt
+	request["link"] = proto
 	resCh := make(chan response)
 	defer close(resCh)
 	request["rc"] = resCh
@@ -203,21 +486,45 @@ func (mlm *mapLinkManager) getLink(id int) (*Link, error) {
 	return res.value.(*Link), res.err
 }
 
-//func (mlm *mapLinkManager) getLinks() ([]Link, error) {
-//	mlm.wg.Add(1)
-//	defer mlm.wg.Done()
-//	if mlm.stop == nil {
-//		return nil, ErrClosed
-//	}
-//	request := make(map[string]interface{})
-//	request["op"] = "getLinks"
-//	resCh := make(chan response)
-//	defer close(resCh)
-//	request["rc"] = resCh
-//	mlm.chOps <- request
-//	res := <-resCh
-//	return res.value.([]Link), res.err
-//}
+func (mlm *mapLinkManager) lookupAlias(alias string) (int, error) {
+	mlm.wg.Add(1)
+	defer mlm.wg.Done()
+	if mlm.stop == nil {
+		return -1, ErrClosed
+	}
+	request := make(request)
+	request["op"] = "lookupAlias"
+	request["alias"] = alias
+	resCh := make(chan response)
+	defer close(resCh)
+	request["rc"] = resCh
+	mlm.chOps <- request
+	res := <-resCh
+	if res.err != nil {
+		return -1, res.err
+	}
+	return res.value.(int), nil
+}
+
+func (mlm *mapLinkManager) getLinks(q *app.ListQuery) ([]*app.Link, error) {
+	mlm.wg.Add(1)
+	defer mlm.wg.Done()
+	if mlm.stop == nil {
+		return nil, ErrClosed
+	}
+	request := make(request)
+	request["op"] = "getLinks"
+	request["query"] = q
+	resCh := make(chan response)
+	defer close(resCh)
+	request["rc"] = resCh
+	mlm.chOps <- request
+	res := <-resCh
+	if res.err != nil {
+		return nil, res.err
+	}
+	return res.value.([]*app.Link), nil
+}
 
 func (mlm *mapLinkManager) hitLink(id int) (*Link, error) {
 	mlm.wg.Add(1)
@@ -239,6 +546,57 @@ func (mlm *mapLinkManager) hitLink(id int) (*Link, error) {
 	return res.value.(*Link), res.err
 }
 
+func (mlm *mapLinkManager) addHits(deltas map[int]int) error {
+	mlm.wg.Add(1)
+	defer mlm.wg.Done()
+	if mlm.stop == nil {
+		return ErrClosed
+	}
+	request := make(request)
+	request["op"] = "addHits"
+	request["deltas"] = deltas
+	resCh := make(chan response)
+	defer close(resCh)
+	request["rc"] = resCh
+	mlm.chOps <- request
+	return (<-resCh).err
+}
+
+func (mlm *mapLinkManager) updateLink(id int, url string, expiredAt *time.Time, rules []app.Rule) error {
+	mlm.wg.Add(1)
+	defer mlm.wg.Done()
+	if mlm.stop == nil {
+		return ErrClosed
+	}
+	request := make(request)
+	request["op"] = "updateLink"
+	request["id"] = id
+	request["url"] = url
+	request["expiredAt"] = expiredAt
+	request["rules"] = rules
+	resCh := make(chan response)
+	defer close(resCh)
+	request["rc"] = resCh
+	mlm.chOps <- request
+	return (<-resCh).err
+}
+
+func (mlm *mapLinkManager) importLink(link *Link) error {
+	mlm.wg.Add(1)
+	defer mlm.wg.Done()
+	if mlm.stop == nil {
+		return ErrClosed
+	}
+	request := make(request)
+	request["op"] = "importLink"
+	request["link"] = link
+	resCh := make(chan response)
+	defer close(resCh)
+	request["rc"] = resCh
+	mlm.chOps <- request
+	return (<-resCh).err
+}
+
 func (mlm *mapLinkManager) setLinkDeleted(id int) error {
 	mlm.wg.Add(1)
 	defer mlm.wg.Done()
@@ -255,6 +613,22 @@ func (mlm *mapLinkManager) setLinkDeleted(id int) error {
 	return (<-resCh).err
 }
 
+func (mlm *mapLinkManager) restoreLink(id int) error {
+	mlm.wg.Add(1)
+	defer mlm.wg.Done()
+	if mlm.stop == nil {
+		return ErrClosed
+	}
+	request := make(request)
+	request["op"] = "restoreLink"
+	request["id"] = id
+	resCh := make(chan response)
+	defer close(resCh)
+	request["rc"] = resCh
+	mlm.chOps <- request
+	return (<-resCh).err
+}
+
 func (mlm *mapLinkManager) removeLink(id int) error {
 	mlm.wg.Add(1)
 	defer mlm.wg.Done()
@@ -271,6 +645,93 @@ func (mlm *mapLinkManager) removeLink(id int) error {
 	return (<-resCh).err
 }
 
+func (mlm *mapLinkManager) addKey(key *ApiKey) error {
+	mlm.wg.Add(1)
+	defer mlm.wg.Done()
+	if mlm.stop == nil {
+		return ErrClosed
+	}
+	request := make(request)
+	request["op"] = "addKey"
+	request["key"] = key
+	resCh := make(chan response)
+	defer close(resCh)
+	request["rc"] = resCh
+	mlm.chOps <- request
+	return (<-resCh).err
+}
+
+func (mlm *mapLinkManager) getKey(id string) (*app.ApiKey, error) {
+	mlm.wg.Add(1)
+	defer mlm.wg.Done()
+	if mlm.stop == nil {
+		return nil, ErrClosed
+	}
+	request := make(request)
+	request["op"] = "getKey"
+	request["id"] = id
+	resCh := make(chan response)
+	defer close(resCh)
+	request["rc"] = resCh
+	mlm.chOps <- request
+	res := <-resCh
+	if res.err != nil {
+		return nil, res.err
+	}
+	return res.value.(*app.ApiKey), nil
+}
+
+func (mlm *mapLinkManager) getKeys() ([]*app.ApiKey, error) {
+	mlm.wg.Add(1)
+	defer mlm.wg.Done()
+	if mlm.stop == nil {
+		return nil, ErrClosed
+	}
+	request := make(request)
+	request["op"] = "getKeys"
+	resCh := make(chan response)
+	defer close(resCh)
+	request["rc"] = resCh
+	mlm.chOps <- request
+	res := <-resCh
+	if res.err != nil {
+		return nil, res.err
+	}
+	return res.value.([]*app.ApiKey), nil
+}
+
+func (mlm *mapLinkManager) removeKey(id string) error {
+	mlm.wg.Add(1)
+	defer mlm.wg.Done()
+	if mlm.stop == nil {
+		return ErrClosed
+	}
+	request := make(request)
+	request["op"] = "removeKey"
+	request["id"] = id
+	resCh := make(chan response)
+	defer close(resCh)
+	request["rc"] = resCh
+	mlm.chOps <- request
+	return (<-resCh).err
+}
+
+// do makes op with no arguments and result
+func (mlm *mapLinkManager) do(op string) error {
+	mlm.wg.Add(1)
+	defer mlm.wg.Done()
+	if mlm.stop == nil {
+		return ErrClosed
+	}
+	request := make(request)
+	request["op"] = op
+	resCh := make(chan response)
+	defer close(resCh)
+	request["rc"] = resCh
+	mlm.chOps <- request
+	return (<-resCh).err
+}
+
 func (mlm *mapLinkManager) Done() <-chan struct{} {
 	return mlm.completed
 }
@@ -282,12 +743,32 @@ func (mlm *mapLinkManager) Err() error {
 func (mlm *mapLinkManager) save() {
 	mlm.wg.Wait()
 	if mlm.path != "" {
-		if file, err := os.OpenFile(mlm.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644); err != nil {
+		mlm.err = mlm.compact()
+		if err := mlm.wal.close(); mlm.err == nil {
 			mlm.err = err
-		} else {
-			if err := json.NewEncoder(file).Encode(mlm.mapLinks); err != nil {
-				mlm.err = err
-			}
 		}
 	}
 }
+
+// compact saves links and keys to snapshot file (atomically replaced) and empties wal
+func (mlm *mapLinkManager) compact() error {
+	tmpPath := mlm.path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if err = json.NewEncoder(file).Encode(snapshot{Links: mlm.mapLinks, Keys: mlm.mapKeys, Next: mlm.next}); err == nil {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, mlm.path)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return mlm.wal.reset()
+}
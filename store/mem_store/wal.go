@@ -0,0 +1,123 @@
+package mem_store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// fsync policies of write-ahead log
+const (
+	FsyncAlways   = "always"   // each op is synced before it's responded
+	FsyncInterval = "interval" // log is synced every fsync interval
+	FsyncNever    = "never"    // syncing is left to OS (ops survive process crash but not OS one)
+)
+
+const (
+	DefaultFsyncInterval   = time.Second
+	DefaultCompactInterval = 10 * time.Minute
+)
+
+// walRecord is mutating op with its result state, so replaying of records is idempotent
+// (records already reflected in snapshot can be replayed once again if compaction is interrupted)
+type walRecord struct {
+	Op   string         `json:"op"`
+	Link *Link          `json:"link,omitempty"` // addLink, updateLink, importLink
+	Next int            `json:"next,omitempty"` // addLink: id allocated to added link
+	Id   int            `json:"id,omitempty"`   // setLinkDeleted, restoreLink, removeLink
+	Hits map[string]int `json:"hits,omitempty"` // hitLink, addHits: link id -> hits
+	At   *time.Time     `json:"at,omitempty"`   // setLinkDeleted (restoreLink - none)
+	Key  *ApiKey        `json:"key,omitempty"`  // addKey
+	Kid  string         `json:"kid,omitempty"`  // removeKey
+}
+
+// wal is append-only file of json lines (records) of ops made since the last snapshot
+type wal struct {
+	file  *os.File
+	fsync string
+	dirty bool
+}
+
+// openWal opens (or creates) log and reads its records,
+// torn record at the end (left by crash in the middle of writing) is cut off
+func openWal(path string, fsync string) (*wal, []*walRecord, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	var records []*walRecord
+	var offset int64
+	r := bufio.NewReader(file)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			break // the last line without new line is torn
+		}
+		if err != nil {
+			_ = file.Close()
+			return nil, nil, err
+		}
+		rec := &walRecord{}
+		if err = json.Unmarshal(bytes.TrimSpace(line), rec); err != nil {
+			_ = file.Close()
+			return nil, nil, fmt.Errorf("decoding wal record at offset [%d] failed: %w", offset, err)
+		}
+		records = append(records, rec)
+		offset += int64(len(line))
+	}
+	if err = file.Truncate(offset); err == nil {
+		_, err = file.Seek(offset, io.SeekStart)
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, nil, err
+	}
+	return &wal{file: file, fsync: fsync}, records, nil
+}
+
+func (w *wal) append(rec *walRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err = w.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if w.fsync == FsyncAlways {
+		return w.file.Sync()
+	}
+	w.dirty = true
+	return nil
+}
+
+func (w *wal) sync() error {
+	if !w.dirty {
+		return nil
+	}
+	w.dirty = false
+	return w.file.Sync()
+}
+
+// reset empties log once its records are saved to snapshot
+func (w *wal) reset() error {
+	if err := w.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	w.dirty = false
+	return w.file.Sync()
+}
+
+func (w *wal) close() error {
+	err := w.sync()
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,185 @@
+package mem_store
+
+import (
+	"context"
+	"errors"
+	"github.com/nj-eka/shurl/app"
+	"github.com/nj-eka/shurl/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_memLinkStore_Wal(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.MemStoreConfig{FilePath: filepath.Join(t.TempDir(), "wlinks.json"), Fsync: FsyncAlways}
+	// crashed store is the one never closed
+	crashed, err := NewMemStore(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, url := range []string{"https://go.dev", "https://golang.org", "https://pkg.go.dev"} {
+		if _, _, err := crashed.Create(ctx, &app.Link{TargetUrl: url}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := crashed.(*memLinkStore).mlm.do("compact"); err != nil {
+		t.Fatalf("compact gotErr = %v", err)
+	}
+	if _, _, err := crashed.Create(ctx, &app.Link{TargetUrl: "https://go.dev/blog", Alias: "blog"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := crashed.Hit(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := crashed.AddHits(ctx, map[int]int{1: 2, 4: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := crashed.SetDeleted(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := crashed.SetDeleted(ctx, 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := crashed.Restore(ctx, 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := crashed.Delete(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"k1", "k2"} {
+		if err := crashed.(app.KeyStore).AddKey(ctx, &app.ApiKey{Id: id, Owner: "alice", Hash: "hash"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := crashed.(app.KeyStore).DeleteKey(ctx, "k2"); err != nil {
+		t.Fatal(err)
+	}
+	// torn record of op interrupted by crash
+	f, ferr := os.OpenFile(cfg.FilePath+".wal", os.O_WRONLY|os.O_APPEND, 0)
+	if ferr != nil {
+		t.Fatal(ferr)
+	}
+	_, _ = f.WriteString(`{"op":"removeLink","id":`)
+	_ = f.Close()
+
+	recovered, err := NewMemStore(ctx, cfg)
+	if err != nil {
+		t.Fatalf("NewMemStore() gotErr = %v", err)
+	}
+	check := func(s app.LinkStore) {
+		for id, wantHits := range map[int]int{1: 3, 4: 5} {
+			if link, err := s.Get(ctx, id); err != nil || link.Hits != wantHits || link.DeletedAt != nil {
+				t.Errorf("Get(%d) got = %v, %v, want hits %v", id, link, err, wantHits)
+			}
+		}
+		if link, err := s.Get(ctx, 2); err != nil || link.DeletedAt == nil {
+			t.Errorf("Get(2) got = %v, %v, want deleted", link, err)
+		}
+		if _, err := s.Get(ctx, 3); !errors.Is(err, app.ErrNotFound) {
+			t.Errorf("Get(3) gotErr = %v, want %v", err, app.ErrNotFound)
+		}
+		if id, err := s.Lookup(ctx, "blog"); err != nil || id != 4 {
+			t.Errorf("Lookup() got = %v, %v, want %v", id, err, 4)
+		}
+		if id, added, err := s.Create(ctx, &app.Link{TargetUrl: "https://go.dev"}); err != nil || id != 1 || added {
+			t.Errorf("Create() of existing got = %v, %v, %v, want %v", id, added, err, 1)
+		}
+		if key, err := s.(app.KeyStore).GetKey(ctx, "k1"); err != nil || key.Owner != "alice" {
+			t.Errorf("GetKey(k1) got = %v, %v, want key of alice", key, err)
+		}
+		if _, err := s.(app.KeyStore).GetKey(ctx, "k2"); !errors.Is(err, app.ErrNotFound) {
+			t.Errorf("GetKey(k2) gotErr = %v, want %v", err, app.ErrNotFound)
+		}
+	}
+	check(recovered)
+	if err := recovered.Close(ctx); err != nil {
+		t.Fatalf("Close() gotErr = %v", err)
+	}
+	if info, err := os.Stat(cfg.FilePath + ".wal"); err != nil || info.Size() != 0 {
+		t.Errorf("wal after Close() got = %v, %v, want empty", info, err)
+	}
+	reopened, err := NewMemStore(ctx, cfg)
+	if err != nil {
+		t.Fatalf("NewMemStore() gotErr = %v", err)
+	}
+	check(reopened)
+	if id, added, err := reopened.Create(ctx, &app.Link{TargetUrl: "https://go.dev/doc"}); err != nil || id != 5 || !added {
+		t.Errorf("Create() got = %v, %v, %v, want %v", id, added, err, 5)
+	}
+	_ = reopened.Close(ctx)
+}
+
+func Test_memLinkStore_LegacySnapshot(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.MemStoreConfig{FilePath: filepath.Join(t.TempDir(), "llinks.json"), Fsync: FsyncAlways}
+	// snapshot of links only saved before api keys appeared
+	legacy := `{"1":{"id":1,"url":"https://go.dev","ct":"2021-01-02T03:04:05Z","dt":null,"et":null,"hs":3}}`
+	if err := os.WriteFile(cfg.FilePath, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewMemStore(ctx, cfg)
+	if err != nil {
+		t.Fatalf("NewMemStore() gotErr = %v", err)
+	}
+	if link, err := s.Get(ctx, 1); err != nil || link.TargetUrl != "https://go.dev" || link.Hits != 3 {
+		t.Errorf("Get(1) got = %v, %v", link, err)
+	}
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("Close() gotErr = %v", err)
+	}
+	s, err = NewMemStore(ctx, cfg)
+	if err != nil {
+		t.Fatalf("NewMemStore() of resaved snapshot gotErr = %v", err)
+	}
+	if link, err := s.Get(ctx, 1); err != nil || link.Hits != 3 {
+		t.Errorf("Get(1) of resaved snapshot got = %v, %v", link, err)
+	}
+	_ = s.Close(ctx)
+}
+
+func Test_memLinkStore_IdsNotReused(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.MemStoreConfig{FilePath: filepath.Join(t.TempDir(), "ilinks.json"), Fsync: FsyncAlways}
+	reaper := func(s app.LinkStore, id int) {
+		r, err := app.NewReaper(s, nil, config.ReaperConfig{Retention: time.Minute})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SetDeleted(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+		if _, deleted, err := r.Reap(ctx, time.Now().UTC().Add(time.Hour)); err != nil || deleted != 1 {
+			t.Fatalf("Reap() got = %v, %v, want 1 deleted", deleted, err)
+		}
+	}
+	s, err := NewMemStore(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, url := range []string{"https://go.dev", "https://golang.org"} {
+		if _, _, err := s.Create(ctx, &app.Link{TargetUrl: url}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// highest id is reaped and store crashes (wal is replayed), then it is reaped once again and store is closed (snapshot is loaded)
+	reaper(s, 2)
+	if s, err = NewMemStore(ctx, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if id, added, err := s.Create(ctx, &app.Link{TargetUrl: "https://pkg.go.dev"}); err != nil || !added || id != 3 {
+		t.Errorf("Create() after wal replay got = %v, %v, %v, want %v", id, added, err, 3)
+	}
+	reaper(s, 3)
+	if err := s.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = NewMemStore(ctx, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if id, added, err := s.Create(ctx, &app.Link{TargetUrl: "https://go.dev/blog"}); err != nil || !added || id != 4 {
+		t.Errorf("Create() after snapshot load got = %v, %v, %v, want %v", id, added, err, 4)
+	}
+	_ = s.Close(ctx)
+}
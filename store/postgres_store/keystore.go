@@ -0,0 +1,82 @@
+package postgres_store
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/nj-eka/shurl/app"
+	cu "github.com/nj-eka/shurl/internal/contexts"
+	"github.com/nj-eka/shurl/internal/errs"
+)
+
+var _ app.KeyStore = &postgresLinkStore{}
+
+const keyColumns = "id, owner, hash, admin, created_at"
+
+func scanKey(row pgx.Row) (*app.ApiKey, error) {
+	var key app.ApiKey
+	if err := row.Scan(&key.Id, &key.Owner, &key.Hash, &key.Admin, &key.CreatedAt); err != nil {
+		return nil, err
+	}
+	key.CreatedAt = key.CreatedAt.UTC()
+	return &key, nil
+}
+
+func (p *postgresLinkStore) AddKey(ctx context.Context, key *app.ApiKey) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("postgres.AddKey"), errs.SetDefaultErrsKind(errs.KindStore))
+	if _, err := p.pool.Exec(
+		ctx,
+		"INSERT INTO api_keys ("+keyColumns+") VALUES ($1, $2, $3, $4, $5)",
+		key.Id, key.Owner, key.Hash, key.Admin, key.CreatedAt,
+	); err != nil {
+		if isUniqueViolation(err) {
+			return errs.E(ctx, errs.SeverityWarning, fmt.Errorf("api key [%s]: %w", key.Id, app.ErrConflict))
+		}
+		return errs.E(ctx, fmt.Errorf("adding api key [%s] failed: %w", key.Id, err))
+	}
+	return nil
+}
+
+func (p *postgresLinkStore) GetKey(ctx context.Context, id string) (*app.ApiKey, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("postgres.GetKey"), errs.SetDefaultErrsKind(errs.KindStore))
+	key, err := scanKey(p.pool.QueryRow(ctx, "SELECT "+keyColumns+" FROM api_keys WHERE id = $1", id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return nil, errs.E(ctx, fmt.Errorf("getting api key [%s] failed: %w", id, err))
+	}
+	return key, nil
+}
+
+func (p *postgresLinkStore) ListKeys(ctx context.Context) ([]*app.ApiKey, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("postgres.ListKeys"), errs.SetDefaultErrsKind(errs.KindStore))
+	rows, err := p.pool.Query(ctx, "SELECT "+keyColumns+" FROM api_keys ORDER BY created_at")
+	if err != nil {
+		return nil, errs.E(ctx, fmt.Errorf("listing api keys failed: %w", err))
+	}
+	defer rows.Close()
+	result := make([]*app.ApiKey, 0)
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			return nil, errs.E(ctx, fmt.Errorf("listing api keys failed: %w", err))
+		}
+		result = append(result, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errs.E(ctx, fmt.Errorf("listing api keys failed: %w", err))
+	}
+	return result, nil
+}
+
+func (p *postgresLinkStore) DeleteKey(ctx context.Context, id string) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("postgres.DeleteKey"), errs.SetDefaultErrsKind(errs.KindStore))
+	if err := p.exec(ctx, "DELETE FROM api_keys WHERE id = $1", id); err != nil {
+		if err == pgx.ErrNoRows {
+			return errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return errs.E(ctx, fmt.Errorf("deleting api key [%s] failed: %w", id, err))
+	}
+	return nil
+}
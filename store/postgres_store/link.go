@@ -0,0 +1,121 @@
+package postgres_store
+
+import (
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/nj-eka/shurl/app"
+	"strings"
+	"time"
+)
+
+const linkColumns = "id, target_url, alias, created_at, expired_at, deleted_at, hits, owner, password_hash, max_hits, active_from, redirect_type, query_passthrough, path_passthrough, utm, rules"
+
+// scanLink scans row of linkColumns
+func scanLink(row pgx.Row) (*app.Link, error) {
+	var link app.Link
+	var alias *string
+	var queryPassthrough, utm, rules string
+	if err := row.Scan(&link.Id, &link.TargetUrl, &alias, &link.CreatedAt, &link.ExpiredAt, &link.DeletedAt, &link.Hits, &link.Owner, &link.PasswordHash, &link.MaxHits, &link.ActiveFrom, &link.RedirectType, &queryPassthrough,
+		&link.PathPassthrough, &utm, &rules); err != nil {
+		return nil, err
+	}
+	if alias != nil {
+		link.Alias = *alias
+	}
+	link.CreatedAt = link.CreatedAt.UTC()
+	link.ExpiredAt = utc(link.ExpiredAt)
+	link.DeletedAt = utc(link.DeletedAt)
+	link.ActiveFrom = utc(link.ActiveFrom)
+	link.QueryPassthrough = app.QueryPassthrough(queryPassthrough)
+	var err error
+	if link.Utm, err = app.DecodeUtm(utm); err != nil {
+		return nil, fmt.Errorf("invalid link [%d] utm [%s]: %w", link.Id, utm, err)
+	}
+	if link.Rules, err = app.DecodeRules(rules); err != nil {
+		return nil, fmt.Errorf("invalid link [%d] rules [%s]: %w", link.Id, rules, err)
+	}
+	return &link, nil
+}
+
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// empty alias is kept as NULL to be out of unique constraint
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// listQuery builds select of links matched by app.ListQuery in query order
+func listQuery(q *app.ListQuery) (string, []interface{}) {
+	var where []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	now := q.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if q.Owner != nil {
+		where = append(where, "owner = "+arg(*q.Owner))
+	}
+	if q.Deleted != nil {
+		if *q.Deleted {
+			where = append(where, "deleted_at IS NOT NULL AND deleted_at < "+arg(now))
+		} else {
+			where = append(where, "(deleted_at IS NULL OR deleted_at >= "+arg(now)+")")
+		}
+	}
+	if q.Expired != nil {
+		if *q.Expired {
+			where = append(where, "expired_at IS NOT NULL AND expired_at < "+arg(now))
+		} else {
+			where = append(where, "(expired_at IS NULL OR expired_at >= "+arg(now)+")")
+		}
+	}
+	column, order, cmp := "", "ASC", ">"
+	if q.Desc {
+		order, cmp = "DESC", "<"
+	}
+	switch q.SortBy {
+	case app.SortByCreatedAt:
+		column = "created_at"
+	case app.SortByHits:
+		column = "hits"
+	}
+	if q.After != nil {
+		if column == "" {
+			where = append(where, fmt.Sprintf("id %s %s", cmp, arg(q.After.Id)))
+		} else {
+			var after interface{} = q.After.Hits
+			if q.SortBy == app.SortByCreatedAt {
+				after = q.After.CreatedAt
+			}
+			// row comparison keeps id as tiebreaker
+			where = append(where, fmt.Sprintf("(%s, id) %s (%s, %s)", column, cmp, arg(after), arg(q.After.Id)))
+		}
+	}
+	var sb strings.Builder
+	sb.WriteString("SELECT " + linkColumns + " FROM links")
+	if len(where) > 0 {
+		sb.WriteString(" WHERE " + strings.Join(where, " AND "))
+	}
+	sb.WriteString(" ORDER BY ")
+	if column != "" {
+		sb.WriteString(column + " " + order + ", ")
+	}
+	sb.WriteString("id " + order)
+	if q.Limit > 0 {
+		sb.WriteString(" LIMIT " + arg(q.Limit))
+	}
+	return sb.String(), args
+}
@@ -0,0 +1,269 @@
+package postgres_store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/nj-eka/shurl/app"
+	"github.com/nj-eka/shurl/config"
+	cu "github.com/nj-eka/shurl/internal/contexts"
+	"github.com/nj-eka/shurl/internal/errs"
+	"github.com/nj-eka/shurl/utils/strutils"
+	"time"
+)
+
+var _ app.LinkStore = &postgresLinkStore{}
+
+type postgresLinkStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresLinkStore(ctx context.Context, cfg config.PostgresStoreConfig) (app.LinkStore, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("postgres.Init"))
+	pcfg, err := pgxpool.ParseConfig(cfg.DSN)
+	if err != nil {
+		return nil, errs.E(ctx, errs.KindStore, errs.KindInvalidValue, fmt.Errorf("parsing postgres dsn failed: %w", err))
+	}
+	if cfg.MaxConns > 0 {
+		pcfg.MaxConns = cfg.MaxConns
+	}
+	if cfg.Timeout > 0 {
+		pcfg.ConnConfig.ConnectTimeout = cfg.Timeout
+	}
+	pool, err := pgxpool.ConnectConfig(ctx, pcfg)
+	if err != nil {
+		return nil, errs.E(ctx, errs.KindStore, fmt.Errorf("connecting to postgres [%s:%d] failed: %w", pcfg.ConnConfig.Host, pcfg.ConnConfig.Port, err))
+	}
+	if err = migrate(ctx, pool); err != nil {
+		pool.Close()
+		return nil, errs.E(ctx, errs.KindStore, fmt.Errorf("migrating postgres db failed: %w", err))
+	}
+	return &postgresLinkStore{pool: pool}, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pe *pgconn.PgError
+	return errors.As(err, &pe) && pe.Code == pgerrcode.UniqueViolation
+}
+
+func (p *postgresLinkStore) Create(ctx context.Context, link *app.Link) (int, bool, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("postgres.Create"), errs.SetDefaultErrsKind(errs.KindStore))
+	id, added := -1, false
+	ie := p.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var alias *string
+		// update goes first not to waste id sequence on conflicts of usual case
+		err := tx.QueryRow(
+			ctx,
+			"UPDATE links SET alias = COALESCE(alias, $3) WHERE owner = $1 AND target_url = $2 RETURNING id, alias",
+			link.Owner, link.TargetUrl, nullString(link.Alias),
+		).Scan(&id, &alias)
+		if err == pgx.ErrNoRows {
+			// link could be inserted concurrently since update
+			err = tx.QueryRow(
+				ctx,
+				`INSERT INTO links (owner, target_url, alias, created_at, expired_at, password_hash, max_hits, active_from, redirect_type,
+				query_passthrough, path_passthrough, utm, rules) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+				ON CONFLICT (owner, target_url) DO UPDATE SET alias = COALESCE(links.alias, EXCLUDED.alias)
+				RETURNING id, alias, xmax = 0`,
+				link.Owner, link.TargetUrl, nullString(link.Alias), time.Now().UTC(), link.ExpiredAt, link.PasswordHash, link.MaxHits, link.ActiveFrom, link.RedirectType,
+				string(link.QueryPassthrough), link.PathPassthrough, link.Utm.Encode(), app.EncodeRules(link.Rules),
+			).Scan(&id, &alias, &added)
+		}
+		if err != nil {
+			return err
+		}
+		if link.Alias != "" && (alias == nil || *alias != link.Alias) {
+			return app.ErrAliasExists
+		}
+		return nil
+	})
+	if ie != nil {
+		if ie == app.ErrAliasExists || isUniqueViolation(ie) {
+			return -1, false, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("adding link with alias [%s] failed: %w", link.Alias, app.ErrAliasExists))
+		}
+		return -1, false, errs.E(ctx, fmt.Errorf("adding link [%s] failed: %w", strutils.Truncate(link.TargetUrl, 24, "..."), ie))
+	}
+	return id, added, nil
+}
+
+func (p *postgresLinkStore) Get(ctx context.Context, id int) (*app.Link, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("postgres.Get"), errs.SetDefaultErrsKind(errs.KindStore))
+	link, err := scanLink(p.pool.QueryRow(ctx, "SELECT "+linkColumns+" FROM links WHERE id = $1", id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return nil, errs.E(ctx, fmt.Errorf("getting link with id [%d] failed: %w", id, err))
+	}
+	return link, nil
+}
+
+func (p *postgresLinkStore) Lookup(ctx context.Context, alias string) (int, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("postgres.Lookup"), errs.SetDefaultErrsKind(errs.KindStore))
+	var id int
+	if err := p.pool.QueryRow(ctx, "SELECT id FROM links WHERE alias = $1", alias).Scan(&id); err != nil {
+		if err == pgx.ErrNoRows {
+			return -1, errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return -1, errs.E(ctx, fmt.Errorf("looking up link with alias [%s] failed: %w", alias, err))
+	}
+	return id, nil
+}
+
+func (p *postgresLinkStore) List(ctx context.Context, q app.ListQuery) ([]*app.Link, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("postgres.List"), errs.SetDefaultErrsKind(errs.KindStore))
+	query, args := listQuery(&q)
+	rows, err := p.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errs.E(ctx, fmt.Errorf("listing links failed: %w", err))
+	}
+	defer rows.Close()
+	result := make([]*app.Link, 0)
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, errs.E(ctx, fmt.Errorf("listing links failed: %w", err))
+		}
+		result = append(result, link)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errs.E(ctx, fmt.Errorf("listing links failed: %w", err))
+	}
+	return result, nil
+}
+
+func (p *postgresLinkStore) Hit(ctx context.Context, id int) (*app.Link, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("postgres.Hit"), errs.SetDefaultErrsKind(errs.KindStore))
+	link, err := scanLink(p.pool.QueryRow(ctx, "UPDATE links SET hits = hits + 1 WHERE id = $1 AND (max_hits = 0 OR hits < max_hits) RETURNING "+linkColumns, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			// link is either absent or exhausted
+			var exists int
+			if err = p.pool.QueryRow(ctx, "SELECT 1 FROM links WHERE id = $1", id).Scan(&exists); err == nil {
+				return nil, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("hitting link with id [%d] failed: %w", id, app.ErrHitsExhausted))
+			}
+		}
+		if err == pgx.ErrNoRows {
+			return nil, errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return nil, errs.E(ctx, fmt.Errorf("hitting link with id [%d] failed: %w", id, err))
+	}
+	return link, nil
+}
+
+func (p *postgresLinkStore) AddHits(ctx context.Context, deltas map[int]int) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("postgres.AddHits"), errs.SetDefaultErrsKind(errs.KindStore))
+	ids, hits := make([]int32, 0, len(deltas)), make([]int32, 0, len(deltas))
+	for id, delta := range deltas {
+		ids, hits = append(ids, int32(id)), append(hits, int32(delta))
+	}
+	if _, err := p.pool.Exec(
+		ctx,
+		"UPDATE links SET hits = links.hits + d.delta FROM UNNEST($1::INTEGER[], $2::INTEGER[]) AS d (id, delta) WHERE links.id = d.id",
+		ids, hits,
+	); err != nil {
+		return errs.E(ctx, fmt.Errorf("adding hits of [%d] links failed: %w", len(deltas), err))
+	}
+	return nil
+}
+
+func (p *postgresLinkStore) Import(ctx context.Context, link *app.Link) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("postgres.Import"), errs.SetDefaultErrsKind(errs.KindStore))
+	err := p.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(
+			ctx,
+			`INSERT INTO links (`+linkColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+			ON CONFLICT (id) DO UPDATE SET target_url = EXCLUDED.target_url, owner = EXCLUDED.owner, alias = EXCLUDED.alias, created_at = EXCLUDED.created_at,
+			expired_at = EXCLUDED.expired_at, deleted_at = EXCLUDED.deleted_at, hits = EXCLUDED.hits, password_hash = EXCLUDED.password_hash,
+			max_hits = EXCLUDED.max_hits, active_from = EXCLUDED.active_from, redirect_type = EXCLUDED.redirect_type,
+			query_passthrough = EXCLUDED.query_passthrough, path_passthrough = EXCLUDED.path_passthrough, utm = EXCLUDED.utm,
+			rules = EXCLUDED.rules`,
+			link.Id, link.TargetUrl, nullString(link.Alias), link.CreatedAt, link.ExpiredAt, link.DeletedAt, link.Hits, link.Owner, link.PasswordHash, link.MaxHits, link.ActiveFrom, link.RedirectType,
+			string(link.QueryPassthrough), link.PathPassthrough, link.Utm.Encode(), app.EncodeRules(link.Rules),
+		); err != nil {
+			return err
+		}
+		// sequence isn't advanced by explicitly given ids
+		_, err := tx.Exec(
+			ctx,
+			"SELECT setval('links_id_seq', GREATEST($1, (SELECT CASE WHEN is_called THEN last_value ELSE 0 END FROM links_id_seq)))",
+			link.Id,
+		)
+		return err
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return errs.E(ctx, errs.SeverityWarning, fmt.Errorf("importing link with id [%d] failed: %w", link.Id, app.ErrConflict))
+		}
+		return errs.E(ctx, fmt.Errorf("importing link with id [%d] failed: %w", link.Id, err))
+	}
+	return nil
+}
+
+func (p *postgresLinkStore) Update(ctx context.Context, link *app.Link) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("postgres.Update"), errs.SetDefaultErrsKind(errs.KindStore))
+	if err := p.exec(ctx, "UPDATE links SET target_url = $2, expired_at = $3, rules = $4 WHERE id = $1", link.Id, link.TargetUrl, link.ExpiredAt, app.EncodeRules(link.Rules)); err != nil {
+		if err == pgx.ErrNoRows {
+			return errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		if isUniqueViolation(err) {
+			return errs.E(ctx, errs.SeverityWarning, fmt.Errorf("updating link with id [%d] failed: %w", link.Id, app.ErrConflict))
+		}
+		return errs.E(ctx, fmt.Errorf("updating link with id [%d] failed: %w", link.Id, err))
+	}
+	return nil
+}
+
+func (p *postgresLinkStore) SetDeleted(ctx context.Context, id int) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("postgres.SetDel"), errs.SetDefaultErrsKind(errs.KindStore))
+	if err := p.exec(ctx, "UPDATE links SET deleted_at = $2 WHERE id = $1", id, time.Now().UTC()); err != nil {
+		if err == pgx.ErrNoRows {
+			return errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return errs.E(ctx, fmt.Errorf("setting deleted link with id [%d] failed: %w", id, err))
+	}
+	return nil
+}
+
+func (p *postgresLinkStore) Restore(ctx context.Context, id int) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("postgres.Restore"), errs.SetDefaultErrsKind(errs.KindStore))
+	if err := p.exec(ctx, "UPDATE links SET deleted_at = NULL WHERE id = $1", id); err != nil {
+		if err == pgx.ErrNoRows {
+			return errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return errs.E(ctx, fmt.Errorf("restoring link with id [%d] failed: %w", id, err))
+	}
+	return nil
+}
+
+func (p *postgresLinkStore) Delete(ctx context.Context, id int) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("postgres.Delete"), errs.SetDefaultErrsKind(errs.KindStore))
+	if err := p.exec(ctx, "DELETE FROM links WHERE id = $1", id); err != nil {
+		if err == pgx.ErrNoRows {
+			return errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return errs.E(ctx, fmt.Errorf("deleting link with id [%d] failed: %w", id, err))
+	}
+	return nil
+}
+
+func (p *postgresLinkStore) Close(ctx context.Context) errs.Error {
+	p.pool.Close()
+	return nil
+}
+
+// exec executes statement affecting single row, pgx.ErrNoRows is returned if there is no one
+func (p *postgresLinkStore) exec(ctx context.Context, query string, args ...interface{}) error {
+	tag, err := p.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package postgres_store
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// migrationsLock is key of advisory lock taken while migrating to keep concurrently started instances in order
+const migrationsLock = 0x5368_75726c
+
+// migrations are applied in order once, applied ones are registered in schema_migrations table
+var migrations = []string{
+	`CREATE TABLE links (
+		id SERIAL PRIMARY KEY,
+		target_url TEXT NOT NULL,
+		alias TEXT,
+		created_at TIMESTAMPTZ NOT NULL,
+		expired_at TIMESTAMPTZ,
+		deleted_at TIMESTAMPTZ,
+		hits INTEGER NOT NULL DEFAULT 0,
+		CONSTRAINT links_target_url_key UNIQUE (target_url),
+		CONSTRAINT links_alias_key UNIQUE (alias)
+	)`,
+	`ALTER TABLE links ADD COLUMN owner TEXT NOT NULL DEFAULT '',
+		DROP CONSTRAINT links_target_url_key,
+		ADD CONSTRAINT links_owner_target_url_key UNIQUE (owner, target_url);
+	CREATE TABLE api_keys (
+		id TEXT PRIMARY KEY,
+		owner TEXT NOT NULL,
+		hash TEXT NOT NULL,
+		admin BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMPTZ NOT NULL
+	)`,
+	`ALTER TABLE links ADD COLUMN password_hash TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE links ADD COLUMN max_hits INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE links ADD COLUMN active_from TIMESTAMPTZ`,
+	`ALTER TABLE links ADD COLUMN redirect_type INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE links ADD COLUMN query_passthrough TEXT NOT NULL DEFAULT '',
+		ADD COLUMN path_passthrough BOOLEAN NOT NULL DEFAULT FALSE`,
+	`ALTER TABLE links ADD COLUMN utm TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE links ADD COLUMN rules TEXT NOT NULL DEFAULT ''`,
+}
+
+func migrate(ctx context.Context, pool *pgxpool.Pool) error {
+	return pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", migrationsLock); err != nil {
+			return fmt.Errorf("locking schema failed: %w", err)
+		}
+		if _, err := tx.Exec(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)"); err != nil {
+			return fmt.Errorf("creating schema migrations failed: %w", err)
+		}
+		var version int
+		if err := tx.QueryRow(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version); err != nil {
+			return fmt.Errorf("getting schema version failed: %w", err)
+		}
+		if version > len(migrations) {
+			return fmt.Errorf("schema version [%d] is newer than supported [%d]", version, len(migrations))
+		}
+		for ; version < len(migrations); version++ {
+			if _, err := tx.Exec(ctx, migrations[version]); err != nil {
+				return fmt.Errorf("applying migration [%d] failed: %w", version+1, err)
+			}
+			if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", version+1); err != nil {
+				return fmt.Errorf("registering migration [%d] failed: %w", version+1, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,104 @@
+package redis_store
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/nj-eka/shurl/app"
+	cu "github.com/nj-eka/shurl/internal/contexts"
+	"github.com/nj-eka/shurl/internal/errs"
+)
+
+var _ app.KeyStore = &redisLinkStore{}
+
+// api keys are kept in hashes (prefix + "apikey:" + id) listed in set (prefix + "apikeys")
+func (r *redisLinkStore) apiKeyKey(id string) string {
+	return r.prefix + "apikey:" + id
+}
+
+func fromKeyHash(h map[string]string) (*app.ApiKey, error) {
+	createdAt, err := fromRedisTime(h["created"])
+	if err != nil || createdAt == nil {
+		return nil, fmt.Errorf("invalid api key [%s] created at [%s]: %v", h["id"], h["created"], err)
+	}
+	return &app.ApiKey{Id: h["id"], Owner: h["owner"], Hash: h["hash"], Admin: h["admin"] == "1", CreatedAt: *createdAt}, nil
+}
+
+func (r *redisLinkStore) AddKey(ctx context.Context, key *app.ApiKey) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("redis.AddKey"), errs.SetDefaultErrsKind(errs.KindStore))
+	admin := 0
+	if key.Admin {
+		admin = 1
+	}
+	createdAt := key.CreatedAt
+	n, err := addKeyScript.Run(
+		ctx,
+		r.client,
+		[]string{r.apiKeyKey(key.Id), r.prefix + "apikeys"},
+		key.Id, key.Owner, key.Hash, admin, toRedisTime(&createdAt),
+	).Int()
+	if err != nil {
+		return errs.E(ctx, fmt.Errorf("adding api key [%s] failed: %w", key.Id, err))
+	}
+	if n == 0 {
+		return errs.E(ctx, errs.SeverityWarning, fmt.Errorf("api key [%s]: %w", key.Id, app.ErrConflict))
+	}
+	return nil
+}
+
+func (r *redisLinkStore) GetKey(ctx context.Context, id string) (*app.ApiKey, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("redis.GetKey"), errs.SetDefaultErrsKind(errs.KindStore))
+	h, err := r.client.HGetAll(ctx, r.apiKeyKey(id)).Result()
+	if err != nil {
+		return nil, errs.E(ctx, fmt.Errorf("getting api key [%s] failed: %w", id, err))
+	}
+	if len(h) == 0 {
+		return nil, errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+	}
+	key, err := fromKeyHash(h)
+	if err != nil {
+		return nil, errs.E(ctx, fmt.Errorf("getting api key [%s] failed: %w", id, err))
+	}
+	return key, nil
+}
+
+func (r *redisLinkStore) ListKeys(ctx context.Context) ([]*app.ApiKey, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("redis.ListKeys"), errs.SetDefaultErrsKind(errs.KindStore))
+	ids, err := r.client.SMembers(ctx, r.prefix+"apikeys").Result()
+	if err != nil {
+		return nil, errs.E(ctx, fmt.Errorf("listing api keys failed: %w", err))
+	}
+	cmds := make([]*redis.StringStringMapCmd, 0, len(ids))
+	if _, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, id := range ids {
+			cmds = append(cmds, pipe.HGetAll(ctx, r.apiKeyKey(id)))
+		}
+		return nil
+	}); err != nil {
+		return nil, errs.E(ctx, fmt.Errorf("listing api keys failed: %w", err))
+	}
+	result := make([]*app.ApiKey, 0, len(ids))
+	for _, cmd := range cmds {
+		if len(cmd.Val()) == 0 {
+			continue
+		}
+		key, err := fromKeyHash(cmd.Val())
+		if err != nil {
+			return nil, errs.E(ctx, fmt.Errorf("listing api keys failed: %w", err))
+		}
+		result = append(result, key)
+	}
+	return result, nil
+}
+
+func (r *redisLinkStore) DeleteKey(ctx context.Context, id string) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("redis.DeleteKey"), errs.SetDefaultErrsKind(errs.KindStore))
+	n, err := deleteKeyScript.Run(ctx, r.client, []string{r.apiKeyKey(id), r.prefix + "apikeys"}, id).Int()
+	if err != nil {
+		return errs.E(ctx, fmt.Errorf("deleting api key [%s] failed: %w", id, err))
+	}
+	if n == 0 {
+		return errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package redis_store
+
+import (
+	"fmt"
+	"github.com/nj-eka/shurl/app"
+	"strconv"
+	"time"
+)
+
+// link hash fields, times are kept as unix nanoseconds ("" = not set)
+const (
+	fieldId        = "id"
+	fieldTargetUrl = "url"
+	fieldOwner     = "owner"
+	fieldAlias     = "alias"
+	fieldCreatedAt = "created"
+	fieldExpiredAt = "expired"
+	fieldDeletedAt = "deleted"
+	fieldHits      = "hits"
+	// optional fields are set only if link has them
+	fieldPassword   = "password"
+	fieldMaxHits    = "maxhits"
+	fieldActiveFrom = "active"
+	fieldRedirect   = "redirect"
+	fieldQuery      = "query"
+	fieldPath       = "path"
+	fieldUtm        = "utm"
+	fieldRules      = "rules"
+)
+
+func toRedisTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func fromRedisTime(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	ns, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	t := time.Unix(0, ns).UTC()
+	return &t, nil
+}
+
+// fromHash makes link from fields of its hash (as HGETALL returns)
+func fromHash(h map[string]string) (*app.Link, error) {
+	var link app.Link
+	var err error
+	if link.Id, err = strconv.Atoi(h[fieldId]); err != nil {
+		return nil, fmt.Errorf("invalid link id [%s]: %w", h[fieldId], err)
+	}
+	link.TargetUrl = h[fieldTargetUrl]
+	link.Owner = h[fieldOwner]
+	link.Alias = h[fieldAlias]
+	link.PasswordHash = h[fieldPassword]
+	link.QueryPassthrough = app.QueryPassthrough(h[fieldQuery])
+	link.PathPassthrough = h[fieldPath] != ""
+	if link.Utm, err = app.DecodeUtm(h[fieldUtm]); err != nil {
+		return nil, fmt.Errorf("invalid link [%d] utm [%s]: %w", link.Id, h[fieldUtm], err)
+	}
+	if link.Rules, err = app.DecodeRules(h[fieldRules]); err != nil {
+		return nil, fmt.Errorf("invalid link [%d] rules [%s]: %w", link.Id, h[fieldRules], err)
+	}
+	createdAt, err := fromRedisTime(h[fieldCreatedAt])
+	if err != nil || createdAt == nil {
+		return nil, fmt.Errorf("invalid link [%d] created at [%s]: %v", link.Id, h[fieldCreatedAt], err)
+	}
+	link.CreatedAt = *createdAt
+	if link.ExpiredAt, err = fromRedisTime(h[fieldExpiredAt]); err != nil {
+		return nil, fmt.Errorf("invalid link [%d] expired at [%s]: %w", link.Id, h[fieldExpiredAt], err)
+	}
+	if link.DeletedAt, err = fromRedisTime(h[fieldDeletedAt]); err != nil {
+		return nil, fmt.Errorf("invalid link [%d] deleted at [%s]: %w", link.Id, h[fieldDeletedAt], err)
+	}
+	if link.Hits, err = strconv.Atoi(h[fieldHits]); err != nil {
+		return nil, fmt.Errorf("invalid link [%d] hits [%s]: %w", link.Id, h[fieldHits], err)
+	}
+	if link.ActiveFrom, err = fromRedisTime(h[fieldActiveFrom]); err != nil {
+		return nil, fmt.Errorf("invalid link [%d] active from [%s]: %w", link.Id, h[fieldActiveFrom], err)
+	}
+	if s := h[fieldRedirect]; s != "" {
+		if link.RedirectType, err = strconv.Atoi(s); err != nil {
+			return nil, fmt.Errorf("invalid link [%d] redirect type [%s]: %w", link.Id, s, err)
+		}
+	}
+	if s := h[fieldMaxHits]; s != "" {
+		if link.MaxHits, err = strconv.Atoi(s); err != nil {
+			return nil, fmt.Errorf("invalid link [%d] max hits [%s]: %w", link.Id, s, err)
+		}
+	}
+	return &link, nil
+}
+
+// optionalPairs returns field / value pairs of optional link fields that are set
+func optionalPairs(link *app.Link) []interface{} {
+	var pairs []interface{}
+	if link.PasswordHash != "" {
+		pairs = append(pairs, fieldPassword, link.PasswordHash)
+	}
+	if link.MaxHits > 0 {
+		pairs = append(pairs, fieldMaxHits, link.MaxHits)
+	}
+	if link.ActiveFrom != nil {
+		pairs = append(pairs, fieldActiveFrom, toRedisTime(link.ActiveFrom))
+	}
+	if link.RedirectType != 0 {
+		pairs = append(pairs, fieldRedirect, link.RedirectType)
+	}
+	if link.QueryPassthrough != "" {
+		pairs = append(pairs, fieldQuery, string(link.QueryPassthrough))
+	}
+	if link.PathPassthrough {
+		pairs = append(pairs, fieldPath, 1)
+	}
+	if !link.Utm.IsZero() {
+		pairs = append(pairs, fieldUtm, link.Utm.Encode())
+	}
+	if len(link.Rules) > 0 {
+		pairs = append(pairs, fieldRules, app.EncodeRules(link.Rules))
+	}
+	return pairs
+}
+
+// fromPairs makes link from flat list of hash fields and values (as HGETALL returns to lua script)
+func fromPairs(pairs []interface{}) (*app.Link, error) {
+	h := make(map[string]string, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		h[fmt.Sprint(pairs[i])] = fmt.Sprint(pairs[i+1])
+	}
+	return fromHash(h)
+}
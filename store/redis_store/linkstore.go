@@ -0,0 +1,519 @@
+package redis_store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/nj-eka/shurl/app"
+	"github.com/nj-eka/shurl/config"
+	cu "github.com/nj-eka/shurl/internal/contexts"
+	"github.com/nj-eka/shurl/internal/errs"
+	"github.com/nj-eka/shurl/utils/strutils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultPrefix = "shurl:"
+
+// listChunk is number of set members List reads at once
+const listChunk = 100
+
+// retry is returned by scripts if link fields read in advance are changed since,
+// script is rerun maxRetries times at most
+const (
+	retry      = -2
+	maxRetries = 64
+)
+
+var errContended = errors.New("link is changed concurrently too often")
+
+// DefaultExpireDelay keeps expired links till they are reaped with default retention
+const DefaultExpireDelay = app.DefaultReapRetention
+
+var _ app.LinkStore = &redisLinkStore{}
+
+// redisLinkStore keeps links in hashes (prefix + "link:" + id) indexed by url (of owner) and alias keys
+// and listed in ids and created sorted sets,
+// all link keys expire (natively) at ExpiredAt + expireDelay
+type redisLinkStore struct {
+	client      *redis.Client
+	prefix      string
+	expireDelay time.Duration
+}
+
+func NewRedisLinkStore(ctx context.Context, cfg config.RedisStoreConfig) (app.LinkStore, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("redis.Init"))
+	opts, err := redis.ParseURL(cfg.URL)
+	if err != nil {
+		return nil, errs.E(ctx, errs.KindStore, errs.KindInvalidValue, fmt.Errorf("parsing redis url failed: %w", err))
+	}
+	client := redis.NewClient(opts)
+	if err = client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, errs.E(ctx, errs.KindStore, fmt.Errorf("connecting to redis [%s] failed: %w", opts.Addr, err))
+	}
+	prefix := cfg.Prefix
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	return &redisLinkStore{client: client, prefix: prefix, expireDelay: ExpireDelay(cfg)}, nil
+}
+
+// ExpireDelay returns delay of native expiration of links configured by cfg
+func ExpireDelay(cfg config.RedisStoreConfig) time.Duration {
+	if cfg.ExpireDelay == 0 {
+		return DefaultExpireDelay
+	}
+	return cfg.ExpireDelay
+}
+
+func (r *redisLinkStore) linkKey(id int) string {
+	return r.prefix + "link:" + strconv.Itoa(id)
+}
+
+// urlKey is key of url index, urls of links without owner are indexed as before owners appeared
+func (r *redisLinkStore) urlKey(owner, url string) string {
+	if owner != "" {
+		return r.prefix + "owner:" + owner + ":url:" + url
+	}
+	return r.prefix + "url:" + url
+}
+
+func (r *redisLinkStore) aliasKey(alias string) string {
+	return r.prefix + "alias:" + alias
+}
+
+// expireAt returns unix time (rounded up to seconds) link keys expire at, 0 - never
+func (r *redisLinkStore) expireAt(expiredAt *time.Time) int64 {
+	if expiredAt == nil {
+		return 0
+	}
+	return expiredAt.Add(r.expireDelay + time.Second - 1).Unix()
+}
+
+// indexed are fields of stored link its index keys and set members are built from ("" - no link)
+type indexed struct {
+	url, alias, owner, created string
+}
+
+// args returns fields in order scripts check them to be unchanged
+func (x indexed) args() []interface{} {
+	return []interface{}{x.url, x.alias, x.owner, x.created}
+}
+
+func (r *redisLinkStore) readIndexed(ctx context.Context, id int) (indexed, error) {
+	vals, err := r.client.HMGet(ctx, r.linkKey(id), fieldTargetUrl, fieldAlias, fieldOwner, fieldCreatedAt).Result()
+	if err != nil {
+		return indexed{}, err
+	}
+	s := make([]string, len(vals))
+	for i, v := range vals {
+		if v != nil {
+			s[i] = fmt.Sprint(v)
+		}
+	}
+	return indexed{url: s[0], alias: s[1], owner: s[2], created: s[3]}, nil
+}
+
+// createdMember is member of created set ordering links by created time (as kept in link hash) and id lexicographically
+func createdMember(createdAt string, id int) string {
+	ns, _ := strconv.ParseInt(createdAt, 10, 64)
+	return fmt.Sprintf("%019d:%019d", ns, id)
+}
+
+// member returns member of set query is ordered by at cursor position
+func member(q *app.ListQuery, c *app.ListCursor) string {
+	if q.SortBy == app.SortByCreatedAt {
+		return createdMember(toRedisTime(&c.CreatedAt), c.Id)
+	}
+	return strconv.Itoa(c.Id)
+}
+
+// memberId returns link id of ids or created set member
+func memberId(m string) string {
+	if i := strings.LastIndexByte(m, ':'); i >= 0 {
+		return strings.TrimLeft(m[i+1:], "0")
+	}
+	return m
+}
+
+// intOf returns int value of cmd, 0 if there is no key
+func intOf(cmd *redis.StringCmd) (int, error) {
+	n, err := cmd.Int()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return n, err
+}
+
+func (r *redisLinkStore) Create(ctx context.Context, link *app.Link) (int, bool, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("redis.Create"), errs.SetDefaultErrsKind(errs.KindStore))
+	now := time.Now().UTC()
+	createdAt := toRedisTime(&now)
+	urlKey := r.urlKey(link.Owner, link.TargetUrl)
+	for i := 0; i < maxRetries; i++ {
+		var existing, next *redis.StringCmd
+		if _, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			existing, next = pipe.Get(ctx, urlKey), pipe.Get(ctx, r.prefix+"next")
+			return nil
+		}); err != nil && err != redis.Nil {
+			return -1, false, errs.E(ctx, fmt.Errorf("adding link [%s] failed: %w", strutils.Truncate(link.TargetUrl, 24, "..."), err))
+		}
+		// link with the same url is returned, otherwise link is added with id next to counter
+		id, err := intOf(existing)
+		exists := 1
+		if err == nil && id == 0 {
+			id, err = intOf(next)
+			id, exists = id+1, 0
+		}
+		if err != nil {
+			return -1, false, errs.E(ctx, fmt.Errorf("adding link [%s] failed: %w", strutils.Truncate(link.TargetUrl, 24, "..."), err))
+		}
+		args := []interface{}{
+			id,
+			exists,
+			link.TargetUrl,
+			link.Alias,
+			createdAt,
+			toRedisTime(link.ExpiredAt),
+			r.expireAt(link.ExpiredAt),
+			link.Owner,
+			createdMember(createdAt, id),
+		}
+		res, err := createScript.Run(
+			ctx,
+			r.client,
+			[]string{urlKey, r.aliasKey(link.Alias), r.prefix + "next", r.prefix + "ids", r.prefix + "created", r.linkKey(id)},
+			append(args, optionalPairs(link)...)...,
+		).Int64Slice()
+		if err != nil {
+			return -1, false, errs.E(ctx, fmt.Errorf("adding link [%s] failed: %w", strutils.Truncate(link.TargetUrl, 24, "..."), err))
+		}
+		switch res[0] {
+		case retry:
+			continue
+		case -1:
+			return -1, false, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("adding link with alias [%s] failed: %w", link.Alias, app.ErrAliasExists))
+		}
+		return int(res[0]), res[1] == 1, nil
+	}
+	return -1, false, errs.E(ctx, fmt.Errorf("adding link [%s] failed: %w", strutils.Truncate(link.TargetUrl, 24, "..."), errContended))
+}
+
+func (r *redisLinkStore) Get(ctx context.Context, id int) (*app.Link, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("redis.Get"), errs.SetDefaultErrsKind(errs.KindStore))
+	h, err := r.client.HGetAll(ctx, r.linkKey(id)).Result()
+	if err != nil {
+		return nil, errs.E(ctx, fmt.Errorf("getting link with id [%d] failed: %w", id, err))
+	}
+	if len(h) == 0 {
+		return nil, errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+	}
+	link, err := fromHash(h)
+	if err != nil {
+		return nil, errs.E(ctx, fmt.Errorf("getting link with id [%d] failed: %w", id, err))
+	}
+	return link, nil
+}
+
+func (r *redisLinkStore) Lookup(ctx context.Context, alias string) (int, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("redis.Lookup"), errs.SetDefaultErrsKind(errs.KindStore))
+	id, err := r.client.Get(ctx, r.aliasKey(alias)).Int()
+	if err != nil {
+		if err == redis.Nil {
+			return -1, errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return -1, errs.E(ctx, fmt.Errorf("looking up link with alias [%s] failed: %w", alias, err))
+	}
+	return id, nil
+}
+
+// List pages links by ids or created set from query cursor on (links ordered by hits are scanned all),
+// members of links expired natively are removed from sets on the way
+func (r *redisLinkStore) List(ctx context.Context, q app.ListQuery) ([]*app.Link, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("redis.List"), errs.SetDefaultErrsKind(errs.KindStore))
+	result := make([]*app.Link, 0)
+	if q.SortBy == app.SortByHits {
+		ids, err := r.client.ZRange(ctx, r.prefix+"ids", 0, -1).Result()
+		if err != nil {
+			return nil, errs.E(ctx, fmt.Errorf("listing links failed: %w", err))
+		}
+		links, err := r.fetch(ctx, r.prefix+"ids", ids)
+		if err != nil {
+			return nil, errs.E(ctx, fmt.Errorf("listing links failed: %w", err))
+		}
+		for _, link := range links {
+			if q.Match(link) {
+				result = append(result, link)
+			}
+		}
+		return q.Sort(result), nil
+	}
+	index, after := r.prefix+"ids", ""
+	if q.SortBy == app.SortByCreatedAt {
+		index = r.prefix + "created"
+	}
+	if q.After != nil {
+		after = member(&q, q.After)
+	}
+	for {
+		members, err := r.indexRange(ctx, &q, index, after)
+		if err != nil {
+			return nil, errs.E(ctx, fmt.Errorf("listing links failed: %w", err))
+		}
+		links, err := r.fetch(ctx, index, members)
+		if err != nil {
+			return nil, errs.E(ctx, fmt.Errorf("listing links failed: %w", err))
+		}
+		for _, link := range links {
+			if q.Match(link) {
+				if result = append(result, link); len(result) == q.Limit {
+					return result, nil
+				}
+			}
+		}
+		if len(members) < listChunk {
+			return result, nil
+		}
+		after = members[len(members)-1]
+	}
+}
+
+// indexRange returns chunk of index members following member after ("" - from the start) in query order
+func (r *redisLinkStore) indexRange(ctx context.Context, q *app.ListQuery, index, after string) ([]string, error) {
+	byCreatedAt := q.SortBy == app.SortByCreatedAt
+	by := &redis.ZRangeBy{Min: "-inf", Max: "+inf", Count: listChunk}
+	if byCreatedAt {
+		by.Min, by.Max = "-", "+"
+	}
+	if after != "" {
+		if q.Desc {
+			by.Max = "(" + after
+		} else {
+			by.Min = "(" + after
+		}
+	}
+	switch {
+	case byCreatedAt && q.Desc:
+		return r.client.ZRevRangeByLex(ctx, index, by).Result()
+	case byCreatedAt:
+		return r.client.ZRangeByLex(ctx, index, by).Result()
+	case q.Desc:
+		return r.client.ZRevRangeByScore(ctx, index, by).Result()
+	default:
+		return r.client.ZRangeByScore(ctx, index, by).Result()
+	}
+}
+
+// fetch gets links of index members keeping their order, members of links that are gone are removed from index
+func (r *redisLinkStore) fetch(ctx context.Context, index string, members []string) ([]*app.Link, error) {
+	cmds := make([]*redis.StringStringMapCmd, 0, len(members))
+	if _, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, m := range members {
+			cmds = append(cmds, pipe.HGetAll(ctx, r.prefix+"link:"+memberId(m)))
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	links := make([]*app.Link, 0, len(members))
+	var gone []interface{}
+	for i, cmd := range cmds {
+		if len(cmd.Val()) == 0 {
+			gone = append(gone, members[i])
+			continue
+		}
+		link, err := fromHash(cmd.Val())
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	if len(gone) > 0 {
+		_ = r.client.ZRem(ctx, index, gone...).Err() // best effort
+	}
+	return links, nil
+}
+
+func (r *redisLinkStore) Hit(ctx context.Context, id int) (*app.Link, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("redis.Hit"), errs.SetDefaultErrsKind(errs.KindStore))
+	res, err := hitScript.Run(ctx, r.client, []string{r.linkKey(id)}).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return nil, errs.E(ctx, fmt.Errorf("hitting link with id [%d] failed: %w", id, err))
+	}
+	pairs, ok := res.([]interface{})
+	if !ok {
+		return nil, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("hitting link with id [%d] failed: %w", id, app.ErrHitsExhausted))
+	}
+	link, err := fromPairs(pairs)
+	if err != nil {
+		return nil, errs.E(ctx, fmt.Errorf("hitting link with id [%d] failed: %w", id, err))
+	}
+	return link, nil
+}
+
+func (r *redisLinkStore) AddHits(ctx context.Context, deltas map[int]int) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("redis.AddHits"), errs.SetDefaultErrsKind(errs.KindStore))
+	if len(deltas) == 0 {
+		return nil
+	}
+	keys, args := make([]string, 0, len(deltas)), make([]interface{}, 0, len(deltas))
+	for id, delta := range deltas {
+		keys, args = append(keys, r.linkKey(id)), append(args, delta)
+	}
+	if err := addHitsScript.Run(ctx, r.client, keys, args...).Err(); err != nil {
+		return errs.E(ctx, fmt.Errorf("adding hits of [%d] links failed: %w", len(deltas), err))
+	}
+	return nil
+}
+
+func (r *redisLinkStore) Import(ctx context.Context, link *app.Link) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("redis.Import"), errs.SetDefaultErrsKind(errs.KindStore))
+	createdAt := toRedisTime(&link.CreatedAt)
+	for i := 0; i < maxRetries; i++ {
+		prev, err := r.readIndexed(ctx, link.Id)
+		if err != nil {
+			return errs.E(ctx, fmt.Errorf("importing link with id [%d] failed: %w", link.Id, err))
+		}
+		args := []interface{}{
+			link.Id,
+			link.TargetUrl,
+			link.Alias,
+			createdAt,
+			toRedisTime(link.ExpiredAt),
+			toRedisTime(link.DeletedAt),
+			link.Hits,
+			r.expireAt(link.ExpiredAt),
+			link.Owner,
+			createdMember(createdAt, link.Id),
+		}
+		args = append(append(args, prev.args()...), createdMember(prev.created, link.Id))
+		n, err := importScript.Run(
+			ctx,
+			r.client,
+			[]string{
+				r.linkKey(link.Id), r.urlKey(link.Owner, link.TargetUrl), r.aliasKey(link.Alias), r.prefix + "next", r.prefix + "ids", r.prefix + "created",
+				r.urlKey(prev.owner, prev.url), r.aliasKey(prev.alias),
+			},
+			append(args, optionalPairs(link)...)...,
+		).Int()
+		if err != nil {
+			return errs.E(ctx, fmt.Errorf("importing link with id [%d] failed: %w", link.Id, err))
+		}
+		switch n {
+		case retry:
+			continue
+		case 0:
+			return errs.E(ctx, errs.SeverityWarning, fmt.Errorf("importing link with id [%d] failed: %w", link.Id, app.ErrConflict))
+		}
+		return nil
+	}
+	return errs.E(ctx, fmt.Errorf("importing link with id [%d] failed: %w", link.Id, errContended))
+}
+
+func (r *redisLinkStore) Update(ctx context.Context, link *app.Link) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("redis.Update"), errs.SetDefaultErrsKind(errs.KindStore))
+	for i := 0; i < maxRetries; i++ {
+		cur, err := r.readIndexed(ctx, link.Id)
+		if err != nil {
+			return errs.E(ctx, fmt.Errorf("updating link with id [%d] failed: %w", link.Id, err))
+		}
+		if cur.url == "" {
+			return errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		args := []interface{}{
+			link.TargetUrl,
+			toRedisTime(link.ExpiredAt),
+			r.expireAt(link.ExpiredAt),
+			link.Id,
+			app.EncodeRules(link.Rules),
+		}
+		n, err := updateScript.Run(
+			ctx,
+			r.client,
+			[]string{r.linkKey(link.Id), r.urlKey(cur.owner, link.TargetUrl), r.urlKey(cur.owner, cur.url), r.aliasKey(cur.alias)},
+			append(args, cur.args()[:3]...)...,
+		).Int()
+		if err != nil {
+			return errs.E(ctx, fmt.Errorf("updating link with id [%d] failed: %w", link.Id, err))
+		}
+		switch n {
+		case retry:
+			continue
+		case 0:
+			return errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		case -1:
+			return errs.E(ctx, errs.SeverityWarning, fmt.Errorf("updating link with id [%d] failed: %w", link.Id, app.ErrConflict))
+		}
+		return nil
+	}
+	return errs.E(ctx, fmt.Errorf("updating link with id [%d] failed: %w", link.Id, errContended))
+}
+
+func (r *redisLinkStore) SetDeleted(ctx context.Context, id int) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("redis.SetDel"), errs.SetDefaultErrsKind(errs.KindStore))
+	deletedAt := time.Now().UTC()
+	n, err := setDeletedScript.Run(ctx, r.client, []string{r.linkKey(id)}, toRedisTime(&deletedAt)).Int()
+	if err != nil {
+		return errs.E(ctx, fmt.Errorf("setting deleted link with id [%d] failed: %w", id, err))
+	}
+	if n == 0 {
+		return errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *redisLinkStore) Restore(ctx context.Context, id int) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("redis.Restore"), errs.SetDefaultErrsKind(errs.KindStore))
+	n, err := restoreScript.Run(ctx, r.client, []string{r.linkKey(id)}).Int()
+	if err != nil {
+		return errs.E(ctx, fmt.Errorf("restoring link with id [%d] failed: %w", id, err))
+	}
+	if n == 0 {
+		return errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *redisLinkStore) Delete(ctx context.Context, id int) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("redis.Delete"), errs.SetDefaultErrsKind(errs.KindStore))
+	for i := 0; i < maxRetries; i++ {
+		cur, err := r.readIndexed(ctx, id)
+		if err != nil {
+			return errs.E(ctx, fmt.Errorf("deleting link with id [%d] failed: %w", id, err))
+		}
+		if cur.url == "" {
+			return errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		n, err := deleteScript.Run(
+			ctx,
+			r.client,
+			[]string{r.linkKey(id), r.prefix + "ids", r.prefix + "created", r.urlKey(cur.owner, cur.url), r.aliasKey(cur.alias)},
+			append(append([]interface{}{id}, cur.args()...), createdMember(cur.created, id))...,
+		).Int()
+		if err != nil {
+			return errs.E(ctx, fmt.Errorf("deleting link with id [%d] failed: %w", id, err))
+		}
+		switch n {
+		case retry:
+			continue
+		case 0:
+			return errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return nil
+	}
+	return errs.E(ctx, fmt.Errorf("deleting link with id [%d] failed: %w", id, errContended))
+}
+
+func (r *redisLinkStore) Close(ctx context.Context) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("redis.Fin"), errs.SetDefaultErrsKind(errs.KindStore))
+	if err := r.client.Close(); err != nil {
+		return errs.E(ctx, errs.SeverityCritical, fmt.Errorf("closing redis client failed: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package redis_store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/nj-eka/shurl/app"
+	"github.com/nj-eka/shurl/config"
+	"github.com/nj-eka/shurl/store/storetest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newStore opens store on its own miniredis (cfg url is ignored)
+func newStore(t *testing.T, cfg config.RedisStoreConfig) (app.LinkStore, *miniredis.Miniredis) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mr.Close)
+	cfg.URL = "redis://" + mr.Addr()
+	store, err := NewRedisLinkStore(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close(context.Background())
+	})
+	return store, mr
+}
+
+func Test_redisLinkStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) app.LinkStore {
+		store, _ := newStore(t, config.RedisStoreConfig{ExpireDelay: time.Hour})
+		return store
+	})
+}
+
+func Test_redisLinkStore_ListPages(t *testing.T) {
+	ctx := context.Background()
+	s, mr := newStore(t, config.RedisStoreConfig{})
+	owner, expiredAt := "alice", time.Now().Add(time.Minute)
+	var ownIds []int
+	kept := 1 // imported one
+	for i := 1; i <= 2*listChunk+50; i++ {
+		link := &app.Link{TargetUrl: fmt.Sprintf("https://example.com/%d", i)}
+		switch i % 3 {
+		case 0:
+			link.Owner = owner
+		case 1:
+			link.ExpiredAt = &expiredAt
+		}
+		if _, _, err := s.Create(ctx, link); err != nil {
+			t.Fatal(err)
+		}
+		if link.Owner != "" {
+			ownIds = append(ownIds, i)
+		}
+		if link.ExpiredAt == nil {
+			kept++
+		}
+	}
+	// imported link created before others goes first by created time
+	if err := s.Import(ctx, &app.Link{Id: 1000, TargetUrl: "https://example.com/imported", Owner: owner, CreatedAt: time.Now().Add(-time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	mr.FastForward(2*time.Minute + DefaultExpireDelay)
+	list := func(q app.ListQuery) []int {
+		var ids []int
+		for {
+			links, err := s.List(ctx, q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, link := range links {
+				ids = append(ids, link.Id)
+			}
+			if len(links) < q.Limit || q.Limit == 0 {
+				return ids
+			}
+			q.After = app.CursorOf(links[len(links)-1])
+		}
+	}
+	now := time.Now()
+	if got := len(list(app.ListQuery{SortBy: app.SortById, Now: now})); got != kept {
+		t.Errorf("List() of not expired got %d links, want %d", got, kept)
+	}
+	if got, _ := mr.ZMembers(DefaultPrefix + "ids"); len(got) != kept {
+		t.Errorf("ids of expired links are not removed, got %d members", len(got))
+	}
+	want := []int{1000}
+	want = append(want, ownIds...)
+	if got := list(app.ListQuery{SortBy: app.SortByCreatedAt, Owner: &owner, Limit: 30, Now: now}); !reflect.DeepEqual(got, want) {
+		t.Errorf("List() by created got = %v, want %v", got, want)
+	}
+	for i, j := 0, len(want)-1; i < j; i, j = i+1, j-1 {
+		want[i], want[j] = want[j], want[i]
+	}
+	if got := list(app.ListQuery{SortBy: app.SortByCreatedAt, Desc: true, Owner: &owner, Limit: 7, Now: now}); !reflect.DeepEqual(got, want) {
+		t.Errorf("List() by created desc got = %v, want %v", got, want)
+	}
+	if err := s.Delete(ctx, 1000); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := mr.ZMembers(DefaultPrefix + "created"); len(got) != kept-1 {
+		t.Errorf("created set of deleted link is not removed, got %d members", len(got))
+	}
+}
+
+func Test_redisLinkStore_CreateConcurrent(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newStore(t, config.RedisStoreConfig{})
+	const n = 8
+	type result struct {
+		id    int
+		added bool
+		err   error
+	}
+	results := make(chan result, 2*n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			id, added, err := s.Create(ctx, &app.Link{TargetUrl: "https://example.com/same"})
+			results <- result{id, added, err}
+			id, added, err = s.Create(ctx, &app.Link{TargetUrl: fmt.Sprintf("https://example.com/%d", i)})
+			results <- result{id, added, err}
+		}(i)
+	}
+	sameId, added, ids := 0, 0, make(map[int]bool)
+	for i := 0; i < 2*n; i++ {
+		r := <-results
+		if r.err != nil {
+			t.Fatalf("Create() gotErr = %v", r.err)
+		}
+		if r.added {
+			added++
+			ids[r.id] = true
+		} else {
+			sameId = r.id
+		}
+	}
+	if added != n+1 || len(ids) != n+1 || !ids[sameId] {
+		t.Errorf("Create() added %d links with %d ids (same url id %d), want %d links with distinct ids", added, len(ids), sameId, n+1)
+	}
+}
+
+func Test_redisLinkStore_Expire(t *testing.T) {
+	ctx := context.Background()
+	store, mr := newStore(t, config.RedisStoreConfig{ExpireDelay: time.Hour})
+	expiredAt := time.Now().Add(time.Minute)
+	id, _, err := store.Create(ctx, &app.Link{TargetUrl: "https://example.com", Alias: "example", ExpiredAt: &expiredAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr.FastForward(time.Hour)
+	if _, err := store.Get(ctx, id); err != nil {
+		t.Errorf("Get() before expire delay gotErr = %v", err)
+	}
+	mr.FastForward(2 * time.Minute)
+	if _, err := store.Get(ctx, id); !errors.Is(err, app.ErrNotFound) {
+		t.Errorf("Get() after expire delay gotErr = %v, want %v", err, app.ErrNotFound)
+	}
+	if _, err := store.Lookup(ctx, "example"); !errors.Is(err, app.ErrNotFound) {
+		t.Errorf("Lookup() after expire delay gotErr = %v, want %v", err, app.ErrNotFound)
+	}
+	gotId, gotAdded, err := store.Create(ctx, &app.Link{TargetUrl: "https://example.com", Alias: "example"})
+	if err != nil || !gotAdded || gotId == id {
+		t.Errorf("Create() after expire got = %v, %v, %v, want new link", gotId, gotAdded, err)
+	}
+	mr.FastForward(24 * time.Hour)
+	if _, err := store.Get(ctx, gotId); err != nil {
+		t.Errorf("Get() of not expirable link gotErr = %v", err)
+	}
+}
+
+func Test_ExpireDelay(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.RedisStoreConfig
+		want time.Duration
+	}{
+		{"default delay covers default retention", config.RedisStoreConfig{}, app.DefaultReapRetention},
+		{"configured delay", config.RedisStoreConfig{ExpireDelay: time.Hour}, time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExpireDelay(tt.cfg); got != tt.want {
+				t.Errorf("ExpireDelay() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
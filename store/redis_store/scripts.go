@@ -0,0 +1,247 @@
+package redis_store
+
+import "github.com/go-redis/redis/v8"
+
+// scripts keep multi-key link updates atomic, every key they touch is passed in KEYS
+// (so store prefix is to carry hash tag, e.g. "{shurl}:", for all keys of store to share redis cluster slot),
+// keys built from fields of stored link are read in advance and scripts return -2 (retry) if the fields are changed since
+
+// createScript adds link with id next to id counter or returns existing one with the same url (setting alias if it has none),
+// it returns {id, added}, {-1, 0} if alias is taken or {-2, 0} if url index or id counter is changed since it was read
+// KEYS: url index, alias index (of requested alias), id counter, ids set, created set, link key (of existing or next id)
+// ARGV: id (of existing or next link), existing (0 / 1), url, alias, created at, expired at, expire at (unix seconds, 0 = persist), owner,
+// created set member, optional field / value pairs
+var createScript = redis.NewScript(`
+local id = redis.call('GET', KEYS[1])
+if ARGV[2] == '1' then
+	if id ~= ARGV[1] then
+		return {-2, 0}
+	end
+	local alias = redis.call('HGET', KEYS[6], 'alias')
+	if ARGV[4] ~= '' and ARGV[4] ~= alias then
+		if alias and alias ~= '' then
+			return {-1, 0}
+		end
+		if not redis.call('SET', KEYS[2], id, 'NX') then
+			return {-1, 0}
+		end
+		redis.call('HSET', KEYS[6], 'alias', ARGV[4])
+		-- alias index expires along with link
+		local ttl = redis.call('PTTL', KEYS[6])
+		if ttl > 0 then
+			redis.call('PEXPIRE', KEYS[2], ttl)
+		end
+	end
+	return {tonumber(id), 0}
+end
+if id or tonumber(redis.call('GET', KEYS[3]) or '0') + 1 ~= tonumber(ARGV[1]) then
+	return {-2, 0}
+end
+if ARGV[4] ~= '' and redis.call('EXISTS', KEYS[2]) == 1 then
+	return {-1, 0}
+end
+id = ARGV[1]
+redis.call('SET', KEYS[3], id)
+redis.call('HSET', KEYS[6], 'id', id, 'url', ARGV[3], 'owner', ARGV[8], 'alias', ARGV[4], 'created', ARGV[5], 'expired', ARGV[6], 'deleted', '', 'hits', 0)
+for i = 10, #ARGV, 2 do
+	redis.call('HSET', KEYS[6], ARGV[i], ARGV[i + 1])
+end
+redis.call('SET', KEYS[1], id)
+redis.call('ZADD', KEYS[4], id, id)
+redis.call('ZADD', KEYS[5], 0, ARGV[9])
+local keys = {KEYS[6], KEYS[1]}
+if ARGV[4] ~= '' then
+	redis.call('SET', KEYS[2], id)
+	table.insert(keys, KEYS[2])
+end
+if ARGV[7] ~= '0' then
+	for _, k in ipairs(keys) do
+		redis.call('EXPIREAT', k, ARGV[7])
+	end
+end
+return {tonumber(id), 1}
+`)
+
+// hitScript increments hits of existing link and returns its hash fields or -1 if link is exhausted
+// KEYS: link key
+var hitScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return false
+end
+local limit = tonumber(redis.call('HGET', KEYS[1], 'maxhits') or '0')
+if limit > 0 and tonumber(redis.call('HGET', KEYS[1], 'hits')) >= limit then
+	return -1
+end
+redis.call('HINCRBY', KEYS[1], 'hits', 1)
+return redis.call('HGETALL', KEYS[1])
+`)
+
+// addHitsScript increments hits of existing links
+// KEYS: link keys, ARGV: deltas
+var addHitsScript = redis.NewScript(`
+for i, key in ipairs(KEYS) do
+	if redis.call('EXISTS', key) == 1 then
+		redis.call('HINCRBY', key, 'hits', ARGV[i])
+	end
+end
+return 0
+`)
+
+// importScript saves link hash replacing the one with the same id,
+// it returns 0 if url or alias belongs to another link or -2 if replaced link is changed since it was read
+// KEYS: link key, url index, alias index (empty alias = no index), id counter, ids set, created set, url index and alias index of replaced link
+// ARGV: id, url, alias, created at, expired at, deleted at, hits, expire at (unix seconds, 0 = persist), owner, created set member,
+// url, alias, owner, created at ('' = no link) and created set member of replaced link, optional field / value pairs
+var importScript = redis.NewScript(`
+local id = redis.call('GET', KEYS[2])
+if id and id ~= ARGV[1] then
+	return 0
+end
+if ARGV[3] ~= '' then
+	id = redis.call('GET', KEYS[3])
+	if id and id ~= ARGV[1] then
+		return 0
+	end
+end
+local prev = redis.call('HMGET', KEYS[1], 'url', 'alias', 'owner', 'created')
+for i = 1, 4 do
+	if (prev[i] or '') ~= ARGV[10 + i] then
+		return -2
+	end
+end
+if prev[1] then
+	redis.call('DEL', KEYS[7])
+	redis.call('ZREM', KEYS[6], ARGV[15])
+end
+if prev[2] and prev[2] ~= '' then
+	redis.call('DEL', KEYS[8])
+end
+-- optional fields of replaced link are not to be left
+redis.call('DEL', KEYS[1])
+redis.call('HSET', KEYS[1], 'id', ARGV[1], 'url', ARGV[2], 'owner', ARGV[9], 'alias', ARGV[3], 'created', ARGV[4], 'expired', ARGV[5], 'deleted', ARGV[6], 'hits', ARGV[7])
+for i = 16, #ARGV, 2 do
+	redis.call('HSET', KEYS[1], ARGV[i], ARGV[i + 1])
+end
+local keys = {KEYS[1], KEYS[2]}
+redis.call('SET', KEYS[2], ARGV[1])
+if ARGV[3] ~= '' then
+	redis.call('SET', KEYS[3], ARGV[1])
+	table.insert(keys, KEYS[3])
+end
+for _, k in ipairs(keys) do
+	if ARGV[8] ~= '0' then
+		redis.call('EXPIREAT', k, ARGV[8])
+	else
+		redis.call('PERSIST', k)
+	end
+end
+redis.call('ZADD', KEYS[5], ARGV[1], ARGV[1])
+redis.call('ZADD', KEYS[6], 0, ARGV[10])
+if tonumber(redis.call('GET', KEYS[4]) or '0') < tonumber(ARGV[1]) then
+	redis.call('SET', KEYS[4], ARGV[1])
+end
+return 1
+`)
+
+// setDeletedScript sets deleted time of existing link, it returns 0 if there is no one
+// KEYS: link key, ARGV: deleted at
+var setDeletedScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'deleted', ARGV[1])
+return 1
+`)
+
+// updateScript sets url, expiration and rules of existing link moving its url index (of link owner),
+// it returns 0 if there is no link, -1 if url belongs to another one or -2 if link is changed since it was read
+// KEYS: link key, url index (of new url), url index and alias index of link
+// ARGV: url, expired at, expire at (unix seconds, 0 = persist), id, rules ('' = none), url, alias and owner of link
+var updateScript = redis.NewScript(`
+local link = redis.call('HMGET', KEYS[1], 'url', 'alias', 'owner')
+for i = 1, 3 do
+	if (link[i] or '') ~= ARGV[5 + i] then
+		return -2
+	end
+end
+if not link[1] then
+	return 0
+end
+if link[1] ~= ARGV[1] then
+	if not redis.call('SET', KEYS[2], ARGV[4], 'NX') then
+		return -1
+	end
+	redis.call('DEL', KEYS[3])
+end
+redis.call('HSET', KEYS[1], 'url', ARGV[1], 'expired', ARGV[2])
+if ARGV[5] ~= '' then
+	redis.call('HSET', KEYS[1], 'rules', ARGV[5])
+else
+	redis.call('HDEL', KEYS[1], 'rules')
+end
+local keys = {KEYS[1], KEYS[2]}
+if link[2] and link[2] ~= '' then
+	table.insert(keys, KEYS[4])
+end
+for _, k in ipairs(keys) do
+	if ARGV[3] ~= '0' then
+		redis.call('EXPIREAT', k, ARGV[3])
+	else
+		redis.call('PERSIST', k)
+	end
+end
+return 1
+`)
+
+// restoreScript clears deleted time of existing link, it returns 0 if there is no one
+// KEYS: link key
+var restoreScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('HDEL', KEYS[1], 'deleted')
+return 1
+`)
+
+// deleteScript removes link with its indexes, it returns 0 if there is no one or -2 if link is changed since it was read
+// KEYS: link key, ids set, created set, url index and alias index of link
+// ARGV: id, url, alias, owner and created at of link, created set member
+var deleteScript = redis.NewScript(`
+local link = redis.call('HMGET', KEYS[1], 'url', 'alias', 'owner', 'created')
+for i = 1, 4 do
+	if (link[i] or '') ~= ARGV[1 + i] then
+		return -2
+	end
+end
+if not link[1] then
+	return 0
+end
+redis.call('DEL', KEYS[1], KEYS[4])
+if link[2] and link[2] ~= '' then
+	redis.call('DEL', KEYS[5])
+end
+redis.call('ZREM', KEYS[2], ARGV[1])
+redis.call('ZREM', KEYS[3], ARGV[6])
+return 1
+`)
+
+// addKeyScript saves api key hash unless key with the same id exists, it returns 0 if it does
+// KEYS: key hash, keys set, ARGV: id, owner, hash, admin (0 / 1), created at
+var addKeyScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'id', ARGV[1], 'owner', ARGV[2], 'hash', ARGV[3], 'admin', ARGV[4], 'created', ARGV[5])
+redis.call('SADD', KEYS[2], ARGV[1])
+return 1
+`)
+
+// deleteKeyScript removes api key, it returns 0 if there is no one
+// KEYS: key hash, keys set, ARGV: id
+var deleteKeyScript = redis.NewScript(`
+if redis.call('DEL', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('SREM', KEYS[2], ARGV[1])
+return 1
+`)
@@ -0,0 +1,88 @@
+package sqlite_store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/nj-eka/shurl/app"
+	cu "github.com/nj-eka/shurl/internal/contexts"
+	"github.com/nj-eka/shurl/internal/errs"
+	"time"
+)
+
+var _ app.KeyStore = &sqliteLinkStore{}
+
+const keyColumns = "id, owner, hash, admin, created_at"
+
+func scanKey(row scanner) (*app.ApiKey, error) {
+	var (
+		key       app.ApiKey
+		createdAt int64
+	)
+	if err := row.Scan(&key.Id, &key.Owner, &key.Hash, &key.Admin, &createdAt); err != nil {
+		return nil, err
+	}
+	key.CreatedAt = time.Unix(0, createdAt).UTC()
+	return &key, nil
+}
+
+func (s *sqliteLinkStore) AddKey(ctx context.Context, key *app.ApiKey) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("sqlite.AddKey"), errs.SetDefaultErrsKind(errs.KindStore))
+	if _, err := s.db.ExecContext(
+		ctx,
+		"INSERT INTO api_keys ("+keyColumns+") VALUES (?, ?, ?, ?, ?)",
+		key.Id, key.Owner, key.Hash, key.Admin, key.CreatedAt.UnixNano(),
+	); err != nil {
+		if isUniqueViolation(err) || isPrimaryKeyViolation(err) {
+			return errs.E(ctx, errs.SeverityWarning, fmt.Errorf("api key [%s]: %w", key.Id, app.ErrConflict))
+		}
+		return errs.E(ctx, fmt.Errorf("adding api key [%s] failed: %w", key.Id, err))
+	}
+	return nil
+}
+
+func (s *sqliteLinkStore) GetKey(ctx context.Context, id string) (*app.ApiKey, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("sqlite.GetKey"), errs.SetDefaultErrsKind(errs.KindStore))
+	key, err := scanKey(s.db.QueryRowContext(ctx, "SELECT "+keyColumns+" FROM api_keys WHERE id = ?", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return nil, errs.E(ctx, fmt.Errorf("getting api key [%s] failed: %w", id, err))
+	}
+	return key, nil
+}
+
+func (s *sqliteLinkStore) ListKeys(ctx context.Context) ([]*app.ApiKey, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("sqlite.ListKeys"), errs.SetDefaultErrsKind(errs.KindStore))
+	rows, err := s.db.QueryContext(ctx, "SELECT "+keyColumns+" FROM api_keys ORDER BY created_at")
+	if err != nil {
+		return nil, errs.E(ctx, fmt.Errorf("listing api keys failed: %w", err))
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	result := make([]*app.ApiKey, 0)
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			return nil, errs.E(ctx, fmt.Errorf("listing api keys failed: %w", err))
+		}
+		result = append(result, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errs.E(ctx, fmt.Errorf("listing api keys failed: %w", err))
+	}
+	return result, nil
+}
+
+func (s *sqliteLinkStore) DeleteKey(ctx context.Context, id string) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("sqlite.DeleteKey"), errs.SetDefaultErrsKind(errs.KindStore))
+	if err := s.exec(ctx, "DELETE FROM api_keys WHERE id = ?", id); err != nil {
+		if err == sql.ErrNoRows {
+			return errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return errs.E(ctx, fmt.Errorf("deleting api key [%s] failed: %w", id, err))
+	}
+	return nil
+}
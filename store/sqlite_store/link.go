@@ -0,0 +1,135 @@
+package sqlite_store
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/nj-eka/shurl/app"
+	"strings"
+	"time"
+)
+
+const linkColumns = "id, target_url, alias, created_at, expired_at, deleted_at, hits, owner, password_hash, max_hits, active_from, redirect_type, query_passthrough, path_passthrough, utm, rules"
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanLink scans row of linkColumns
+func scanLink(row scanner) (*app.Link, error) {
+	var (
+		link                             app.Link
+		alias                            sql.NullString
+		createdAt                        int64
+		expiredAt, deletedAt, activeFrom sql.NullInt64
+		queryPassthrough, utm, rules     string
+	)
+	if err := row.Scan(&link.Id, &link.TargetUrl, &alias, &createdAt, &expiredAt, &deletedAt, &link.Hits, &link.Owner, &link.PasswordHash, &link.MaxHits, &activeFrom, &link.RedirectType, &queryPassthrough, &link.PathPassthrough, &utm, &rules); err != nil {
+		return nil, err
+	}
+	link.Alias = alias.String
+	link.CreatedAt = time.Unix(0, createdAt).UTC()
+	link.ExpiredAt = fromNullTime(expiredAt)
+	link.DeletedAt = fromNullTime(deletedAt)
+	link.ActiveFrom = fromNullTime(activeFrom)
+	link.QueryPassthrough = app.QueryPassthrough(queryPassthrough)
+	var err error
+	if link.Utm, err = app.DecodeUtm(utm); err != nil {
+		return nil, fmt.Errorf("invalid link [%d] utm [%s]: %w", link.Id, utm, err)
+	}
+	if link.Rules, err = app.DecodeRules(rules); err != nil {
+		return nil, fmt.Errorf("invalid link [%d] rules [%s]: %w", link.Id, rules, err)
+	}
+	return &link, nil
+}
+
+// times are kept as unix nanoseconds
+func toNullTime(t *time.Time) sql.NullInt64 {
+	if t == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.UnixNano(), Valid: true}
+}
+
+func fromNullTime(n sql.NullInt64) *time.Time {
+	if !n.Valid {
+		return nil
+	}
+	t := time.Unix(0, n.Int64).UTC()
+	return &t
+}
+
+// empty alias is kept as NULL to be out of unique index
+func toNullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// listQuery builds select of links matched by app.ListQuery in query order
+func listQuery(q *app.ListQuery) (string, []interface{}) {
+	var where []string
+	var args []interface{}
+	now := q.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if q.Owner != nil {
+		where = append(where, "owner = ?")
+		args = append(args, *q.Owner)
+	}
+	if q.Deleted != nil {
+		if *q.Deleted {
+			where = append(where, "deleted_at IS NOT NULL AND deleted_at < ?")
+		} else {
+			where = append(where, "(deleted_at IS NULL OR deleted_at >= ?)")
+		}
+		args = append(args, now.UnixNano())
+	}
+	if q.Expired != nil {
+		if *q.Expired {
+			where = append(where, "expired_at IS NOT NULL AND expired_at < ?")
+		} else {
+			where = append(where, "(expired_at IS NULL OR expired_at >= ?)")
+		}
+		args = append(args, now.UnixNano())
+	}
+	column, order, cmp := "", "ASC", ">"
+	if q.Desc {
+		order, cmp = "DESC", "<"
+	}
+	var after interface{}
+	switch q.SortBy {
+	case app.SortByCreatedAt:
+		column = "created_at"
+		if q.After != nil {
+			after = q.After.CreatedAt.UnixNano()
+		}
+	case app.SortByHits:
+		column = "hits"
+		if q.After != nil {
+			after = q.After.Hits
+		}
+	}
+	if q.After != nil {
+		if column == "" {
+			where = append(where, fmt.Sprintf("id %s ?", cmp))
+			args = append(args, q.After.Id)
+		} else {
+			where = append(where, fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", column, cmp))
+			args = append(args, after, after, q.After.Id)
+		}
+	}
+	var sb strings.Builder
+	sb.WriteString("SELECT " + linkColumns + " FROM links")
+	if len(where) > 0 {
+		sb.WriteString(" WHERE " + strings.Join(where, " AND "))
+	}
+	sb.WriteString(" ORDER BY ")
+	if column != "" {
+		sb.WriteString(column + " " + order + ", ")
+	}
+	sb.WriteString("id " + order)
+	if q.Limit > 0 {
+		sb.WriteString(" LIMIT ?")
+		args = append(args, q.Limit)
+	}
+	return sb.String(), args
+}
@@ -0,0 +1,280 @@
+package sqlite_store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/nj-eka/shurl/app"
+	"github.com/nj-eka/shurl/config"
+	cu "github.com/nj-eka/shurl/internal/contexts"
+	"github.com/nj-eka/shurl/internal/errs"
+	"github.com/nj-eka/shurl/utils/strutils"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	"strings"
+	"time"
+)
+
+var _ app.LinkStore = &sqliteLinkStore{}
+
+type sqliteLinkStore struct {
+	db *sql.DB
+}
+
+func NewSqliteLinkStore(ctx context.Context, cfg config.SqliteStoreConfig) (app.LinkStore, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("sqlite.Init"))
+	// WAL journal lets readers (admin tools) work while server writes,
+	// transactions (all of them write) take write lock at once not to fail with SQLITE_BUSY upgrading read lock (busy timeout is not applied to)
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)&_txlock=immediate", cfg.FilePath, cfg.Timeout.Milliseconds())
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, errs.E(ctx, errs.KindStore, fmt.Errorf("opening sqlite db [%s] failed: %w", cfg.FilePath, err))
+	}
+	if err = migrate(ctx, db); err != nil {
+		_ = db.Close()
+		return nil, errs.E(ctx, errs.KindStore, fmt.Errorf("migrating sqlite db [%s] failed: %w", cfg.FilePath, err))
+	}
+	return &sqliteLinkStore{db: db}, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var se *sqlite.Error
+	return errors.As(err, &se) && se.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
+
+// isAliasViolation checks whether err is violation of unique alias index (links_alias)
+func isAliasViolation(err error) bool {
+	return isUniqueViolation(err) && strings.Contains(err.Error(), "links.alias")
+}
+
+func isPrimaryKeyViolation(err error) bool {
+	var se *sqlite.Error
+	return errors.As(err, &se) && se.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
+
+func (s *sqliteLinkStore) Create(ctx context.Context, link *app.Link) (int, bool, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("sqlite.Create"), errs.SetDefaultErrsKind(errs.KindStore))
+	var ie error
+	id, added := -1, false
+	tx, ie := s.db.BeginTx(ctx, nil)
+	if ie == nil {
+		defer func() {
+			_ = tx.Rollback()
+		}()
+		var alias sql.NullString
+		if ie = tx.QueryRowContext(ctx, "SELECT id, alias FROM links WHERE owner = ? AND target_url = ?", link.Owner, link.TargetUrl).Scan(&id, &alias); ie == nil {
+			if link.Alias != "" && link.Alias != alias.String {
+				if alias.Valid {
+					ie = app.ErrAliasExists
+				} else {
+					_, ie = tx.ExecContext(ctx, "UPDATE links SET alias = ? WHERE id = ?", link.Alias, id)
+				}
+			}
+		} else if ie == sql.ErrNoRows {
+			var res sql.Result
+			if res, ie = tx.ExecContext(
+				ctx,
+				`INSERT INTO links (target_url, owner, alias, created_at, expired_at, password_hash, max_hits, active_from, redirect_type, query_passthrough, path_passthrough, utm, rules)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				link.TargetUrl, link.Owner, toNullString(link.Alias), time.Now().UTC().UnixNano(), toNullTime(link.ExpiredAt), link.PasswordHash, link.MaxHits,
+				toNullTime(link.ActiveFrom), link.RedirectType, string(link.QueryPassthrough), link.PathPassthrough, link.Utm.Encode(), app.EncodeRules(link.Rules),
+			); ie == nil {
+				var lastId int64
+				lastId, ie = res.LastInsertId()
+				id, added = int(lastId), true
+			}
+		}
+		if ie == nil {
+			if ie = tx.Commit(); ie == nil {
+				return id, added, nil
+			}
+		}
+		if ie == app.ErrAliasExists || isAliasViolation(ie) {
+			return -1, false, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("adding link with alias [%s] failed: %w", link.Alias, app.ErrAliasExists))
+		}
+	}
+	return -1, false, errs.E(ctx, fmt.Errorf("adding link [%s] failed: %w", strutils.Truncate(link.TargetUrl, 24, "..."), ie))
+}
+
+func (s *sqliteLinkStore) Get(ctx context.Context, id int) (*app.Link, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("sqlite.Get"), errs.SetDefaultErrsKind(errs.KindStore))
+	link, err := scanLink(s.db.QueryRowContext(ctx, "SELECT "+linkColumns+" FROM links WHERE id = ?", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return nil, errs.E(ctx, fmt.Errorf("getting link with id [%d] failed: %w", id, err))
+	}
+	return link, nil
+}
+
+func (s *sqliteLinkStore) Lookup(ctx context.Context, alias string) (int, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("sqlite.Lookup"), errs.SetDefaultErrsKind(errs.KindStore))
+	var id int
+	if err := s.db.QueryRowContext(ctx, "SELECT id FROM links WHERE alias = ?", alias).Scan(&id); err != nil {
+		if err == sql.ErrNoRows {
+			return -1, errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return -1, errs.E(ctx, fmt.Errorf("looking up link with alias [%s] failed: %w", alias, err))
+	}
+	return id, nil
+}
+
+func (s *sqliteLinkStore) List(ctx context.Context, q app.ListQuery) ([]*app.Link, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("sqlite.List"), errs.SetDefaultErrsKind(errs.KindStore))
+	query, args := listQuery(&q)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errs.E(ctx, fmt.Errorf("listing links failed: %w", err))
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	result := make([]*app.Link, 0)
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, errs.E(ctx, fmt.Errorf("listing links failed: %w", err))
+		}
+		result = append(result, link)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errs.E(ctx, fmt.Errorf("listing links failed: %w", err))
+	}
+	return result, nil
+}
+
+func (s *sqliteLinkStore) Hit(ctx context.Context, id int) (*app.Link, errs.Error) {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("sqlite.Hit"), errs.SetDefaultErrsKind(errs.KindStore))
+	link, err := scanLink(s.db.QueryRowContext(ctx, "UPDATE links SET hits = hits + 1 WHERE id = ? AND (max_hits = 0 OR hits < max_hits) RETURNING "+linkColumns, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// link is either absent or exhausted
+			var exists int
+			if err = s.db.QueryRowContext(ctx, "SELECT 1 FROM links WHERE id = ?", id).Scan(&exists); err == nil {
+				return nil, errs.E(ctx, errs.SeverityWarning, fmt.Errorf("hitting link with id [%d] failed: %w", id, app.ErrHitsExhausted))
+			}
+		}
+		if err == sql.ErrNoRows {
+			return nil, errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return nil, errs.E(ctx, fmt.Errorf("hitting link with id [%d] failed: %w", id, err))
+	}
+	return link, nil
+}
+
+func (s *sqliteLinkStore) AddHits(ctx context.Context, deltas map[int]int) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("sqlite.AddHits"), errs.SetDefaultErrsKind(errs.KindStore))
+	var ie error
+	tx, ie := s.db.BeginTx(ctx, nil)
+	if ie == nil {
+		defer func() {
+			_ = tx.Rollback()
+		}()
+		for id, delta := range deltas {
+			if _, ie = tx.ExecContext(ctx, "UPDATE links SET hits = hits + ? WHERE id = ?", delta, id); ie != nil {
+				break
+			}
+		}
+		if ie == nil {
+			if ie = tx.Commit(); ie == nil {
+				return nil
+			}
+		}
+	}
+	return errs.E(ctx, fmt.Errorf("adding hits of [%d] links failed: %w", len(deltas), ie))
+}
+
+// Import relies on AUTOINCREMENT sequence to be advanced by explicitly inserted ids
+func (s *sqliteLinkStore) Import(ctx context.Context, link *app.Link) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("sqlite.Import"), errs.SetDefaultErrsKind(errs.KindStore))
+	if _, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO links (`+linkColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET target_url = excluded.target_url, owner = excluded.owner, alias = excluded.alias, created_at = excluded.created_at,
+		expired_at = excluded.expired_at, deleted_at = excluded.deleted_at, hits = excluded.hits, password_hash = excluded.password_hash,
+		max_hits = excluded.max_hits, active_from = excluded.active_from, redirect_type = excluded.redirect_type,
+		query_passthrough = excluded.query_passthrough, path_passthrough = excluded.path_passthrough, utm = excluded.utm,
+		rules = excluded.rules`,
+		link.Id, link.TargetUrl, toNullString(link.Alias), link.CreatedAt.UnixNano(), toNullTime(link.ExpiredAt), toNullTime(link.DeletedAt), link.Hits, link.Owner,
+		link.PasswordHash, link.MaxHits, toNullTime(link.ActiveFrom), link.RedirectType, string(link.QueryPassthrough), link.PathPassthrough,
+		link.Utm.Encode(), app.EncodeRules(link.Rules),
+	); err != nil {
+		if isUniqueViolation(err) {
+			return errs.E(ctx, errs.SeverityWarning, fmt.Errorf("importing link with id [%d] failed: %w", link.Id, app.ErrConflict))
+		}
+		return errs.E(ctx, fmt.Errorf("importing link with id [%d] failed: %w", link.Id, err))
+	}
+	return nil
+}
+
+func (s *sqliteLinkStore) Update(ctx context.Context, link *app.Link) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("sqlite.Update"), errs.SetDefaultErrsKind(errs.KindStore))
+	if err := s.exec(ctx, "UPDATE links SET target_url = ?, expired_at = ?, rules = ? WHERE id = ?", link.TargetUrl, toNullTime(link.ExpiredAt), app.EncodeRules(link.Rules), link.Id); err != nil {
+		if err == sql.ErrNoRows {
+			return errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		if isUniqueViolation(err) {
+			return errs.E(ctx, errs.SeverityWarning, fmt.Errorf("updating link with id [%d] failed: %w", link.Id, app.ErrConflict))
+		}
+		return errs.E(ctx, fmt.Errorf("updating link with id [%d] failed: %w", link.Id, err))
+	}
+	return nil
+}
+
+func (s *sqliteLinkStore) SetDeleted(ctx context.Context, id int) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("sqlite.SetDel"), errs.SetDefaultErrsKind(errs.KindStore))
+	deletedAt := time.Now().UTC()
+	if err := s.exec(ctx, "UPDATE links SET deleted_at = ? WHERE id = ?", deletedAt.UnixNano(), id); err != nil {
+		if err == sql.ErrNoRows {
+			return errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return errs.E(ctx, fmt.Errorf("setting deleted link with id [%d] failed: %w", id, err))
+	}
+	return nil
+}
+
+func (s *sqliteLinkStore) Restore(ctx context.Context, id int) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("sqlite.Restore"), errs.SetDefaultErrsKind(errs.KindStore))
+	if err := s.exec(ctx, "UPDATE links SET deleted_at = NULL WHERE id = ?", id); err != nil {
+		if err == sql.ErrNoRows {
+			return errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return errs.E(ctx, fmt.Errorf("restoring link with id [%d] failed: %w", id, err))
+	}
+	return nil
+}
+
+func (s *sqliteLinkStore) Delete(ctx context.Context, id int) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("sqlite.Delete"), errs.SetDefaultErrsKind(errs.KindStore))
+	if err := s.exec(ctx, "DELETE FROM links WHERE id = ?", id); err != nil {
+		if err == sql.ErrNoRows {
+			return errs.E(ctx, errs.SeverityWarning, app.ErrNotFound)
+		}
+		return errs.E(ctx, fmt.Errorf("deleting link with id [%d] failed: %w", id, err))
+	}
+	return nil
+}
+
+func (s *sqliteLinkStore) Close(ctx context.Context) errs.Error {
+	ctx = cu.BuildContext(ctx, cu.AddContextOperation("sqlite.Fin"), errs.SetDefaultErrsKind(errs.KindStore))
+	if err := s.db.Close(); err != nil {
+		return errs.E(ctx, errs.SeverityCritical, fmt.Errorf("closing sqlite db failed: %w", err))
+	}
+	return nil
+}
+
+// exec executes statement affecting single row, sql.ErrNoRows is returned if there is no one
+func (s *sqliteLinkStore) exec(ctx context.Context, query string, args ...interface{}) error {
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package sqlite_store
+
+import (
+	"context"
+	"github.com/nj-eka/shurl/app"
+	"github.com/nj-eka/shurl/config"
+	"github.com/nj-eka/shurl/store/storetest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newStore opens store in temp dir
+func newStore(t *testing.T) app.LinkStore {
+	store, err := NewSqliteLinkStore(context.Background(), config.SqliteStoreConfig{FilePath: filepath.Join(t.TempDir(), "links.sqlite"), Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close(context.Background())
+	})
+	return store
+}
+
+func Test_sqliteLinkStore(t *testing.T) {
+	storetest.Run(t, newStore)
+}
+
+func Test_sqliteLinkStore_CreateConcurrent(t *testing.T) {
+	ctx := context.Background()
+	s := newStore(t)
+	const n = 16
+	type result struct {
+		id    int
+		added bool
+		err   error
+	}
+	results := make(chan result, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			id, added, err := s.Create(ctx, &app.Link{TargetUrl: "https://example.com/same", Alias: "same"})
+			results <- result{id, added, err}
+		}()
+	}
+	added := 0
+	for i := 0; i < n; i++ {
+		r := <-results
+		if r.err != nil || r.id != 1 {
+			t.Errorf("Create() got = %v, %v, want 1, <nil>", r.id, r.err)
+		}
+		if r.added {
+			added++
+		}
+	}
+	if added != 1 {
+		t.Errorf("Create() added %d links, want 1", added)
+	}
+}
@@ -0,0 +1,68 @@
+package sqlite_store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrations are applied in order once, number of applied ones is kept in PRAGMA user_version
+var migrations = []string{
+	`CREATE TABLE links (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		target_url TEXT NOT NULL,
+		alias TEXT,
+		created_at INTEGER NOT NULL,
+		expired_at INTEGER,
+		deleted_at INTEGER,
+		hits INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE UNIQUE INDEX links_target_url ON links (target_url);
+	CREATE UNIQUE INDEX links_alias ON links (alias);`,
+	`ALTER TABLE links ADD COLUMN owner TEXT NOT NULL DEFAULT '';
+	DROP INDEX links_target_url;
+	CREATE UNIQUE INDEX links_owner_target_url ON links (owner, target_url);
+	CREATE TABLE api_keys (
+		id TEXT PRIMARY KEY,
+		owner TEXT NOT NULL,
+		hash TEXT NOT NULL,
+		admin INTEGER NOT NULL DEFAULT 0,
+		created_at INTEGER NOT NULL
+	);`,
+	`ALTER TABLE links ADD COLUMN password_hash TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE links ADD COLUMN max_hits INTEGER NOT NULL DEFAULT 0;`,
+	`ALTER TABLE links ADD COLUMN active_from INTEGER;`,
+	`ALTER TABLE links ADD COLUMN redirect_type INTEGER NOT NULL DEFAULT 0;`,
+	`ALTER TABLE links ADD COLUMN query_passthrough TEXT NOT NULL DEFAULT '';
+	ALTER TABLE links ADD COLUMN path_passthrough INTEGER NOT NULL DEFAULT 0;`,
+	`ALTER TABLE links ADD COLUMN utm TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE links ADD COLUMN rules TEXT NOT NULL DEFAULT '';`,
+}
+
+func migrate(ctx context.Context, db *sql.DB) error {
+	var version int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("getting schema version failed: %w", err)
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("schema version [%d] is newer than supported [%d]", version, len(migrations))
+	}
+	for ; version < len(migrations); version++ {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, migrations[version]); err == nil {
+			// pragma doesn't support parameters
+			_, err = tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", version+1))
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("applying migration [%d] failed: %w", version+1, err)
+		}
+	}
+	return nil
+}
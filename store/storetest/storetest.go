@@ -0,0 +1,671 @@
+// Package storetest is conformance suite of app.LinkStore implementations
+package storetest
+
+import (
+	"context"
+	"errors"
+	"github.com/nj-eka/shurl/app"
+	"github.com/nj-eka/shurl/internal/errs"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var expiredAt = time.Now().Truncate(time.Microsecond) // postgres keeps microseconds
+
+// Run runs suite against empty store made by newStore (which is to close it on test cleanup),
+// suite steps share the store in the order they are run (link ids are expected to be sequential from 1)
+func Run(t *testing.T, newStore func(t *testing.T) app.LinkStore) {
+	store := newStore(t)
+	t.Run("Create", func(t *testing.T) {
+		testCreate(t, store)
+	})
+	t.Run("Get", func(t *testing.T) {
+		testGet(t, store)
+	})
+	t.Run("Lookup", func(t *testing.T) {
+		testLookup(t, store)
+	})
+	t.Run("Hit", func(t *testing.T) {
+		testHit(t, store)
+	})
+	t.Run("List", func(t *testing.T) {
+		testList(t, store)
+	})
+	t.Run("AddHits", func(t *testing.T) {
+		testAddHits(t, store)
+	})
+	t.Run("Update", func(t *testing.T) {
+		testUpdate(t, store)
+	})
+	t.Run("SetDeleted", func(t *testing.T) {
+		testSetDeleted(t, store)
+	})
+	t.Run("Restore", func(t *testing.T) {
+		testRestore(t, store)
+	})
+	t.Run("Delete", func(t *testing.T) {
+		testDelete(t, store)
+	})
+	t.Run("Import", func(t *testing.T) {
+		testImport(t, store)
+	})
+	t.Run("Owner", func(t *testing.T) {
+		testOwner(t, store)
+	})
+	t.Run("Keys", func(t *testing.T) {
+		testKeys(t, store)
+	})
+	t.Run("Password", func(t *testing.T) {
+		testPassword(t, store)
+	})
+	t.Run("MaxHits", func(t *testing.T) {
+		testMaxHits(t, store)
+	})
+	t.Run("ActiveFrom", func(t *testing.T) {
+		testActiveFrom(t, store)
+	})
+	t.Run("RedirectType", func(t *testing.T) {
+		testRedirectType(t, store)
+	})
+	t.Run("Passthrough", func(t *testing.T) {
+		testPassthrough(t, store)
+	})
+	t.Run("Utm", func(t *testing.T) {
+		testUtm(t, store)
+	})
+	t.Run("Rules", func(t *testing.T) {
+		testRules(t, store)
+	})
+}
+
+func testCreate(t *testing.T, store app.LinkStore) {
+	ctx := context.Background()
+	type args struct {
+		targetUrl string
+		alias     string
+		expiredAt *time.Time
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantKey   int
+		wantAdded bool
+		wantErr   error
+	}{
+		{"add invalid url", args{"https//stackoverflow.com", "", nil}, 1, true, nil},
+		{"add first url", args{"https://stackoverflow.com", "", nil}, 2, true, nil},
+		{"add first url update", args{"https://stackoverflow.com", "", &expiredAt}, 2, false, nil},
+		{"add second url", args{"https://stackoverflow.com/questions", "", &expiredAt}, 3, true, nil},
+		{"add aliased url", args{"https://go.dev", "go_dev", nil}, 4, true, nil},
+		{"add aliased url again", args{"https://go.dev", "go_dev", nil}, 4, false, nil},
+		{"add taken alias", args{"https://golang.org", "go_dev", nil}, -1, false, app.ErrAliasExists},
+		{"add another alias to aliased url", args{"https://go.dev", "golang", nil}, -1, false, app.ErrAliasExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotKey, gotAdded, gotErr := store.Create(ctx, &app.Link{TargetUrl: tt.args.targetUrl, Alias: tt.args.alias, ExpiredAt: tt.args.expiredAt})
+			if gotKey != tt.wantKey {
+				t.Errorf("Create() gotKey = %v, want %v", gotKey, tt.wantKey)
+			}
+			if gotAdded != tt.wantAdded {
+				t.Errorf("Create() gotAdded = %v, want %v", gotAdded, tt.wantAdded)
+			}
+			if tt.wantErr != nil || gotErr != nil {
+				if ee, ok := tt.wantErr.(errs.Error); ok {
+					if ee == gotErr {
+						return
+					}
+				}
+				if !errors.Is(gotErr, tt.wantErr) {
+					t.Errorf("CreateToken() gotErr = %v, want %v", gotErr, tt.wantErr)
+				}
+			}
+		})
+	}
+}
+
+func testGet(t *testing.T, store app.LinkStore) {
+	ctx := context.Background()
+	type args struct {
+		key int
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantLink  *app.Link
+		wantErrIs error
+	}{
+		{"get not existed", args{68734}, nil, app.ErrNotFound},
+		{"get first link", args{2}, &app.Link{
+			Id:        2,
+			TargetUrl: "https://stackoverflow.com",
+			ExpiredAt: nil, // expiration isn't set to existing link by Create
+			Hits:      0,
+		}, nil},
+		{"get second link", args{3}, &app.Link{
+			Id:        3,
+			TargetUrl: "https://stackoverflow.com/questions",
+			ExpiredAt: &expiredAt,
+			Hits:      0,
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotLink, gotErr := store.Get(ctx, tt.args.key)
+			if tt.wantErrIs != nil || gotErr != nil {
+				if ee, ok := tt.wantErrIs.(errs.Error); ok {
+					if ee == gotErr {
+						return
+					}
+				}
+				if !errors.Is(gotErr, tt.wantErrIs) {
+					t.Errorf("Get() gotErr = %v, want %v", gotErr, tt.wantErrIs)
+					return
+				}
+			}
+			if gotLink == tt.wantLink {
+				return
+			}
+			if (gotLink != nil && tt.wantLink == nil) || (gotLink == nil && tt.wantLink != nil) {
+				t.Errorf("Get() got = %v, want %v", gotLink, tt.wantLink)
+				return
+			}
+			if gotLink.Id != tt.wantLink.Id ||
+				gotLink.TargetUrl != tt.wantLink.TargetUrl ||
+				(gotLink.ExpiredAt == nil) != (tt.wantLink.ExpiredAt == nil) ||
+				(gotLink.ExpiredAt != nil && gotLink.ExpiredAt.UnixNano() != tt.wantLink.ExpiredAt.UnixNano()) ||
+				gotLink.Hits != tt.wantLink.Hits {
+				t.Errorf("Get() got = %v, want %v", gotLink, tt.wantLink)
+			}
+		})
+	}
+}
+
+func testLookup(t *testing.T, store app.LinkStore) {
+	ctx := context.Background()
+	type args struct {
+		alias string
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantId    int
+		wantErrIs error
+	}{
+		{"lookup not existed", args{"golang"}, -1, app.ErrNotFound},
+		{"lookup aliased link", args{"go_dev"}, 4, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotId, gotErr := store.Lookup(ctx, tt.args.alias)
+			if tt.wantErrIs != nil || gotErr != nil {
+				if !errors.Is(gotErr, tt.wantErrIs) {
+					t.Errorf("Lookup() gotErr = %v, want %v", gotErr, tt.wantErrIs)
+					return
+				}
+			}
+			if gotId != tt.wantId {
+				t.Errorf("Lookup() gotId = %v, want %v", gotId, tt.wantId)
+			}
+		})
+	}
+}
+
+func testHit(t *testing.T, store app.LinkStore) {
+	ctx := context.Background()
+	type args struct {
+		key int
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantLink  *app.Link
+		wantErrIs error
+	}{
+		{"hit not existed", args{1454987}, nil, app.ErrNotFound},
+		{"hit first link - 1", args{2}, &app.Link{
+			Id:   2,
+			Hits: 1,
+		}, nil},
+		{"hit first link - 2", args{2}, &app.Link{
+			Id:   2,
+			Hits: 2,
+		}, nil},
+		{"hit second link", args{3}, &app.Link{
+			Id:        3,
+			TargetUrl: "https://stackoverflow.com/questions",
+			ExpiredAt: &expiredAt,
+			Hits:      1,
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotLink, gotErr := store.Hit(ctx, tt.args.key)
+			if tt.wantErrIs != nil || gotErr != nil {
+				if ee, ok := tt.wantErrIs.(errs.Error); ok {
+					if ee == gotErr {
+						return
+					}
+				}
+				if !errors.Is(gotErr, tt.wantErrIs) {
+					t.Errorf("HitLink() gotErr = %v, want %v", gotErr, tt.wantErrIs)
+					return
+				}
+			}
+			if gotLink == tt.wantLink {
+				return
+			}
+			if (gotLink != nil && tt.wantLink == nil) || (gotLink == nil && tt.wantLink != nil) {
+				t.Errorf("HitLink() got = %v, want %v", gotLink, tt.wantLink)
+				return
+			}
+			if (gotLink.Id != tt.wantLink.Id) || (gotLink.Hits != tt.wantLink.Hits) {
+				t.Errorf("HitLink() got = %v, want %v", gotLink, tt.wantLink)
+			}
+		})
+	}
+}
+
+func testList(t *testing.T, store app.LinkStore) {
+	ctx := context.Background()
+	yes, no := true, false
+	now := time.Now()
+	tests := []struct {
+		name    string
+		query   app.ListQuery
+		wantIds []int
+	}{
+		{"list all", app.ListQuery{SortBy: app.SortById, Now: now}, []int{1, 2, 3, 4}},
+		{"list all desc", app.ListQuery{SortBy: app.SortById, Desc: true, Now: now}, []int{4, 3, 2, 1}},
+		{"list by hits desc", app.ListQuery{SortBy: app.SortByHits, Desc: true, Now: now}, []int{2, 3, 4, 1}},
+		{"list by created", app.ListQuery{SortBy: app.SortByCreatedAt, Now: now}, []int{1, 2, 3, 4}},
+		{"list expired", app.ListQuery{SortBy: app.SortById, Expired: &yes, Now: now}, []int{3}},
+		{"list not expired", app.ListQuery{SortBy: app.SortById, Expired: &no, Now: now}, []int{1, 2, 4}},
+		{"list deleted", app.ListQuery{SortBy: app.SortById, Deleted: &yes, Now: now}, []int{}},
+		{"list first page", app.ListQuery{SortBy: app.SortById, Limit: 2, Now: now}, []int{1, 2}},
+		{"list next page", app.ListQuery{SortBy: app.SortById, Limit: 2, After: &app.ListCursor{Id: 2}, Now: now}, []int{3, 4}},
+		{"list next page by hits", app.ListQuery{SortBy: app.SortByHits, Desc: true, After: &app.ListCursor{Id: 3, Hits: 1}, Now: now}, []int{4, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotLinks, gotErr := store.List(ctx, tt.query)
+			if gotErr != nil {
+				t.Errorf("List() gotErr = %v", gotErr)
+				return
+			}
+			gotIds := make([]int, 0, len(gotLinks))
+			for _, link := range gotLinks {
+				gotIds = append(gotIds, link.Id)
+			}
+			if !reflect.DeepEqual(gotIds, tt.wantIds) {
+				t.Errorf("List() got = %v, want %v", gotIds, tt.wantIds)
+			}
+		})
+	}
+}
+
+func testAddHits(t *testing.T, store app.LinkStore) {
+	ctx := context.Background()
+	if err := store.AddHits(ctx, map[int]int{2: 3, 3: 1, 1454987: 5}); err != nil {
+		t.Fatalf("AddHits() gotErr = %v", err)
+	}
+	for id, wantHits := range map[int]int{2: 5, 3: 2} {
+		gotLink, gotErr := store.Get(ctx, id)
+		if gotErr != nil || gotLink.Hits != wantHits {
+			t.Errorf("AddHits() got = %v, %v, want hits %v", gotLink, gotErr, wantHits)
+		}
+	}
+}
+
+func testUpdate(t *testing.T, store app.LinkStore) {
+	ctx := context.Background()
+	later := time.Now().Add(time.Hour).Truncate(time.Microsecond)
+	tests := []struct {
+		name      string
+		link      *app.Link
+		wantErrIs error
+	}{
+		{"update not existed", &app.Link{Id: 5465, TargetUrl: "https://go.dev/doc"}, app.ErrNotFound},
+		{"update url and expiration", &app.Link{Id: 4, TargetUrl: "https://go.dev/doc", ExpiredAt: &later}, nil},
+		{"update to url of another link", &app.Link{Id: 4, TargetUrl: "https://stackoverflow.com"}, app.ErrConflict},
+		{"update url back", &app.Link{Id: 4, TargetUrl: "https://go.dev"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotErr := store.Update(ctx, tt.link)
+			if tt.wantErrIs != nil || gotErr != nil {
+				if !errors.Is(gotErr, tt.wantErrIs) {
+					t.Errorf("Update() gotErr = %v, want %v", gotErr, tt.wantErrIs)
+				}
+				return
+			}
+			gotLink, err := store.Get(ctx, tt.link.Id)
+			if err != nil || gotLink.TargetUrl != tt.link.TargetUrl || gotLink.Alias != "go_dev" ||
+				(gotLink.ExpiredAt == nil) != (tt.link.ExpiredAt == nil) ||
+				(gotLink.ExpiredAt != nil && gotLink.ExpiredAt.UnixNano() != tt.link.ExpiredAt.UnixNano()) {
+				t.Errorf("Get() after Update() got = %v, %v, want %v", gotLink, err, tt.link)
+			}
+			if id, added, err := store.Create(ctx, &app.Link{TargetUrl: tt.link.TargetUrl}); err != nil || id != tt.link.Id || added {
+				t.Errorf("Create() of updated url got = %v, %v, %v, want %v", id, added, err, tt.link.Id)
+			}
+		})
+	}
+}
+
+func testSetDeleted(t *testing.T, store app.LinkStore) {
+	ctx := context.Background()
+	type args struct {
+		key int
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantErrIs error
+	}{
+		{"delete not existed", args{5465}, app.ErrNotFound},
+		{"delete second link - 1", args{3}, nil},
+		{"delete second link - 2", args{3}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotErr := store.SetDeleted(ctx, tt.args.key)
+			if tt.wantErrIs != nil || gotErr != nil {
+				if ee, ok := tt.wantErrIs.(errs.Error); ok {
+					if ee == gotErr {
+						return
+					}
+				}
+				if !errors.Is(gotErr, tt.wantErrIs) {
+					t.Errorf("SetDeleted() gotErr = %v, want %v", gotErr, tt.wantErrIs)
+					return
+				}
+			}
+		})
+	}
+}
+
+func testRestore(t *testing.T, store app.LinkStore) {
+	ctx := context.Background()
+	type args struct {
+		key int
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantErrIs error
+	}{
+		{"restore not existed", args{5465}, app.ErrNotFound},
+		{"restore deleted link", args{3}, nil},
+		{"restore not deleted link", args{3}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotErr := store.Restore(ctx, tt.args.key)
+			if tt.wantErrIs != nil || gotErr != nil {
+				if !errors.Is(gotErr, tt.wantErrIs) {
+					t.Errorf("Restore() gotErr = %v, want %v", gotErr, tt.wantErrIs)
+				}
+				return
+			}
+			if link, err := store.Get(ctx, tt.args.key); err != nil || link.DeletedAt != nil {
+				t.Errorf("Get() after Restore() got = %v, %v, want not deleted", link, err)
+			}
+		})
+	}
+}
+
+func testDelete(t *testing.T, store app.LinkStore) {
+	ctx := context.Background()
+	type args struct {
+		key int
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantErrIs error
+	}{
+		{"delete not existed", args{5465}, app.ErrNotFound},
+		{"delete second link - 1", args{3}, nil},
+		{"delete second link - 2", args{3}, app.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotErr := store.Delete(ctx, tt.args.key)
+			if tt.wantErrIs != nil || gotErr != nil {
+				if ee, ok := tt.wantErrIs.(errs.Error); ok {
+					if ee == gotErr {
+						return
+					}
+				}
+				if !errors.Is(gotErr, tt.wantErrIs) {
+					t.Errorf("Delete() gotErr = %v, want %v", gotErr, tt.wantErrIs)
+					return
+				}
+			}
+		})
+	}
+}
+
+func testImport(t *testing.T, store app.LinkStore) {
+	ctx := context.Background()
+	createdAt := time.Now().Add(-time.Hour).UTC()
+	tests := []struct {
+		name      string
+		link      app.Link
+		wantErrIs error
+	}{
+		{"import new link", app.Link{Id: 10, TargetUrl: "https://imported.test", Alias: "imported", CreatedAt: createdAt, Hits: 7}, nil},
+		{"import same link again", app.Link{Id: 10, TargetUrl: "https://imported.test/v2", CreatedAt: createdAt, Hits: 8}, nil},
+		{"import link with url of another one", app.Link{Id: 12, TargetUrl: "https://go.dev", CreatedAt: createdAt}, app.ErrConflict},
+		{"import link with alias of another one", app.Link{Id: 12, TargetUrl: "https://golang.org", Alias: "go_dev", CreatedAt: createdAt}, app.ErrConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotErr := store.Import(ctx, &tt.link)
+			if tt.wantErrIs != nil || gotErr != nil {
+				if !errors.Is(gotErr, tt.wantErrIs) {
+					t.Errorf("Import() gotErr = %v, want %v", gotErr, tt.wantErrIs)
+				}
+				return
+			}
+			gotLink, gotErr := store.Get(ctx, tt.link.Id)
+			if gotErr != nil || gotLink.TargetUrl != tt.link.TargetUrl || gotLink.Alias != tt.link.Alias || gotLink.Hits != tt.link.Hits {
+				t.Errorf("Import() got = %v, %v, want %v", gotLink, gotErr, tt.link)
+			}
+		})
+	}
+	if _, gotErr := store.Lookup(ctx, "imported"); !errors.Is(gotErr, app.ErrNotFound) {
+		t.Errorf("Lookup() of replaced alias gotErr = %v, want %v", gotErr, app.ErrNotFound)
+	}
+	if gotId, _, gotErr := store.Create(ctx, &app.Link{TargetUrl: "https://created.after.import"}); gotErr != nil || gotId != 11 {
+		t.Errorf("Create() after Import() got = %v, %v, want %v", gotId, gotErr, 11)
+	}
+}
+
+func testOwner(t *testing.T, store app.LinkStore) {
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		link      app.Link
+		wantId    int
+		wantAdded bool
+	}{
+		{"add url of ownerless link by owner", app.Link{TargetUrl: "https://go.dev", Owner: "alice"}, 12, true},
+		{"add the same url by the same owner", app.Link{TargetUrl: "https://go.dev", Owner: "alice"}, 12, false},
+		{"add the same url by another owner", app.Link{TargetUrl: "https://go.dev", Owner: "bob"}, 13, true},
+		{"add the same url without owner", app.Link{TargetUrl: "https://go.dev"}, 4, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotId, gotAdded, gotErr := store.Create(ctx, &tt.link)
+			if gotErr != nil || gotId != tt.wantId || gotAdded != tt.wantAdded {
+				t.Errorf("Create() got = %v, %v, %v, want %v, %v", gotId, gotAdded, gotErr, tt.wantId, tt.wantAdded)
+			}
+		})
+	}
+	if gotLink, gotErr := store.Get(ctx, 12); gotErr != nil || gotLink.Owner != "alice" {
+		t.Errorf("Get() got = %v, %v, want owner alice", gotLink, gotErr)
+	}
+	owner := "bob"
+	if gotLinks, gotErr := store.List(ctx, app.ListQuery{Owner: &owner}); gotErr != nil || len(gotLinks) != 1 || gotLinks[0].Id != 13 {
+		t.Errorf("List() of owner got = %v, %v, want [13]", gotLinks, gotErr)
+	}
+	if gotErr := store.Update(ctx, &app.Link{Id: 13, TargetUrl: "https://go.dev/doc", Owner: "bob"}); gotErr != nil {
+		t.Errorf("Update() to url of link of another owner gotErr = %v", gotErr)
+	}
+	if gotErr := store.Update(ctx, &app.Link{Id: 12, TargetUrl: "https://go.dev/doc", Owner: "alice"}); gotErr != nil {
+		t.Errorf("Update() to url of another owner's link gotErr = %v", gotErr)
+	}
+	if gotErr := store.Update(ctx, &app.Link{Id: 12, TargetUrl: "https://go.dev", Owner: "alice"}); gotErr != nil {
+		t.Errorf("Update() back gotErr = %v", gotErr)
+	}
+}
+
+func testKeys(t *testing.T, store app.LinkStore) {
+	ctx := context.Background()
+	keys := store.(app.KeyStore)
+	key := &app.ApiKey{Id: "a1b2c3", Owner: "alice", Hash: "hash", Admin: true, CreatedAt: time.Now().UTC().Truncate(time.Microsecond)}
+	if gotErr := keys.AddKey(ctx, key); gotErr != nil {
+		t.Fatalf("AddKey() gotErr = %v", gotErr)
+	}
+	if gotErr := keys.AddKey(ctx, &app.ApiKey{Id: "a1b2c3", Owner: "bob", Hash: "hash2", CreatedAt: time.Now()}); !errors.Is(gotErr, app.ErrConflict) {
+		t.Errorf("AddKey() with taken id gotErr = %v, want %v", gotErr, app.ErrConflict)
+	}
+	gotKey, gotErr := keys.GetKey(ctx, "a1b2c3")
+	if gotErr != nil || gotKey.Owner != key.Owner || gotKey.Hash != key.Hash || gotKey.Admin != key.Admin || !gotKey.CreatedAt.Equal(key.CreatedAt) {
+		t.Errorf("GetKey() got = %v, %v, want %v", gotKey, gotErr, key)
+	}
+	if gotKeys, gotErr := keys.ListKeys(ctx); gotErr != nil || len(gotKeys) != 1 {
+		t.Errorf("ListKeys() got = %v, %v, want 1 key", gotKeys, gotErr)
+	}
+	if gotErr = keys.DeleteKey(ctx, "a1b2c3"); gotErr != nil {
+		t.Errorf("DeleteKey() gotErr = %v", gotErr)
+	}
+	if gotErr = keys.DeleteKey(ctx, "a1b2c3"); !errors.Is(gotErr, app.ErrNotFound) {
+		t.Errorf("DeleteKey() of deleted key gotErr = %v, want %v", gotErr, app.ErrNotFound)
+	}
+	if _, gotErr = keys.GetKey(ctx, "a1b2c3"); !errors.Is(gotErr, app.ErrNotFound) {
+		t.Errorf("GetKey() of deleted key gotErr = %v, want %v", gotErr, app.ErrNotFound)
+	}
+}
+
+func testPassword(t *testing.T, store app.LinkStore) {
+	ctx := context.Background()
+	link := &app.Link{TargetUrl: "https://go.dev/secret", PasswordHash: "hash"}
+	if gotId, gotAdded, gotErr := store.Create(ctx, link); gotErr != nil || gotId != 14 || !gotAdded {
+		t.Fatalf("Create() got = %v, %v, %v, want 14, true", gotId, gotAdded, gotErr)
+	}
+	if gotLink, gotErr := store.Get(ctx, 14); gotErr != nil || gotLink.PasswordHash != "hash" {
+		t.Errorf("Get() got = %v, %v, want password hash", gotLink, gotErr)
+	}
+	if gotLink, gotErr := store.Hit(ctx, 14); gotErr != nil || gotLink.PasswordHash != "hash" {
+		t.Errorf("Hit() got = %v, %v, want password hash", gotLink, gotErr)
+	}
+	imported := &app.Link{Id: 14, TargetUrl: "https://go.dev/secret", CreatedAt: time.Now().UTC()}
+	if gotErr := store.Import(ctx, imported); gotErr != nil {
+		t.Fatalf("Import() gotErr = %v", gotErr)
+	}
+	if gotLink, gotErr := store.Get(ctx, 14); gotErr != nil || gotLink.PasswordHash != "" {
+		t.Errorf("Get() of imported got = %v, %v, want no password hash", gotLink, gotErr)
+	}
+}
+
+func testMaxHits(t *testing.T, store app.LinkStore) {
+	ctx := context.Background()
+	if gotId, gotAdded, gotErr := store.Create(ctx, &app.Link{TargetUrl: "https://go.dev/once", MaxHits: 2}); gotErr != nil || gotId != 15 || !gotAdded {
+		t.Fatalf("Create() got = %v, %v, %v, want 15, true", gotId, gotAdded, gotErr)
+	}
+	tests := []struct {
+		name      string
+		wantHits  int
+		wantErrIs error
+	}{
+		{"first hit", 1, nil},
+		{"last hit", 2, nil},
+		{"hit of exhausted link", 0, app.ErrHitsExhausted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotLink, gotErr := store.Hit(ctx, 15)
+			if !errors.Is(gotErr, tt.wantErrIs) || gotErr == nil && (gotLink.Hits != tt.wantHits || gotLink.MaxHits != 2) {
+				t.Errorf("Hit() got = %v, %v, want hits %v, %v", gotLink, gotErr, tt.wantHits, tt.wantErrIs)
+			}
+		})
+	}
+	if gotLink, gotErr := store.Get(ctx, 15); gotErr != nil || gotLink.Hits != 2 || !gotLink.IsExhausted() {
+		t.Errorf("Get() got = %v, %v, want exhausted link", gotLink, gotErr)
+	}
+}
+
+func testActiveFrom(t *testing.T, store app.LinkStore) {
+	ctx := context.Background()
+	activeFrom := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	if gotId, gotAdded, gotErr := store.Create(ctx, &app.Link{TargetUrl: "https://go.dev/soon", ActiveFrom: &activeFrom}); gotErr != nil || gotId != 16 || !gotAdded {
+		t.Fatalf("Create() got = %v, %v, %v, want 16, true", gotId, gotAdded, gotErr)
+	}
+	gotLink, gotErr := store.Get(ctx, 16)
+	if gotErr != nil || gotLink.ActiveFrom == nil || !gotLink.ActiveFrom.Equal(activeFrom) || gotLink.IsActive(time.Now().UTC()) {
+		t.Errorf("Get() got = %v, %v, want link active from %v", gotLink, gotErr, activeFrom)
+	}
+}
+
+func testRedirectType(t *testing.T, store app.LinkStore) {
+	ctx := context.Background()
+	if gotId, gotAdded, gotErr := store.Create(ctx, &app.Link{TargetUrl: "https://go.dev/moved", RedirectType: http.StatusMovedPermanently}); gotErr != nil || gotId != 17 || !gotAdded {
+		t.Fatalf("Create() got = %v, %v, %v, want 17, true", gotId, gotAdded, gotErr)
+	}
+	if gotLink, gotErr := store.Hit(ctx, 17); gotErr != nil || gotLink.RedirectType != http.StatusMovedPermanently {
+		t.Errorf("Hit() got = %v, %v, want redirect type %v", gotLink, gotErr, http.StatusMovedPermanently)
+	}
+}
+
+func testPassthrough(t *testing.T, store app.LinkStore) {
+	ctx := context.Background()
+	link := &app.Link{TargetUrl: "https://go.dev/wildcard", QueryPassthrough: app.QueryOverride, PathPassthrough: true}
+	if gotId, gotAdded, gotErr := store.Create(ctx, link); gotErr != nil || gotId != 18 || !gotAdded {
+		t.Fatalf("Create() got = %v, %v, %v, want 18, true", gotId, gotAdded, gotErr)
+	}
+	if gotLink, gotErr := store.Get(ctx, 18); gotErr != nil || gotLink.QueryPassthrough != app.QueryOverride || !gotLink.PathPassthrough {
+		t.Errorf("Get() got = %v, %v, want passthrough of query [%v] and path", gotLink, gotErr, app.QueryOverride)
+	}
+}
+
+func testUtm(t *testing.T, store app.LinkStore) {
+	ctx := context.Background()
+	utm := &app.Utm{Source: "news letter", Campaign: "launch&co"}
+	if gotId, gotAdded, gotErr := store.Create(ctx, &app.Link{TargetUrl: "https://go.dev/utm", Utm: utm}); gotErr != nil || gotId != 19 || !gotAdded {
+		t.Fatalf("Create() got = %v, %v, %v, want 19, true", gotId, gotAdded, gotErr)
+	}
+	if gotLink, gotErr := store.Get(ctx, 19); gotErr != nil || gotLink.Utm == nil || *gotLink.Utm != *utm {
+		t.Errorf("Get() got = %v, %v, want utm %v", gotLink, gotErr, utm)
+	}
+}
+
+func testRules(t *testing.T, store app.LinkStore) {
+	ctx := context.Background()
+	rules := []app.Rule{
+		{Platform: app.PlatformIOS, TargetUrl: "https://apps.apple.com/app/go"},
+		{Language: "de", CIDR: "10.0.0.0/8", TargetUrl: "https://go.dev/de"},
+	}
+	if gotId, gotAdded, gotErr := store.Create(ctx, &app.Link{TargetUrl: "https://go.dev/rules", Rules: rules}); gotErr != nil || gotId != 20 || !gotAdded {
+		t.Fatalf("Create() got = %v, %v, %v, want 20, true", gotId, gotAdded, gotErr)
+	}
+	if gotLink, gotErr := store.Get(ctx, 20); gotErr != nil || app.EncodeRules(gotLink.Rules) != app.EncodeRules(rules) {
+		t.Errorf("Get() got = %v, %v, want rules %v", gotLink, gotErr, rules)
+	}
+	rules = rules[1:]
+	if gotErr := store.Update(ctx, &app.Link{Id: 20, TargetUrl: "https://go.dev/rules", Rules: rules}); gotErr != nil {
+		t.Fatalf("Update() error = %v", gotErr)
+	}
+	if gotLink, gotErr := store.Get(ctx, 20); gotErr != nil || app.EncodeRules(gotLink.Rules) != app.EncodeRules(rules) {
+		t.Errorf("Get() got = %v, %v, want rules %v", gotLink, gotErr, rules)
+	}
+	if gotErr := store.Update(ctx, &app.Link{Id: 20, TargetUrl: "https://go.dev/rules"}); gotErr != nil {
+		t.Fatalf("Update() error = %v", gotErr)
+	}
+	if gotLink, gotErr := store.Get(ctx, 20); gotErr != nil || gotLink.Rules != nil {
+		t.Errorf("Get() got = %v, %v, want no rules", gotLink, gotErr)
+	}
+}
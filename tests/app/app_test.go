@@ -3,18 +3,39 @@ package app_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/nj-eka/shurl/app"
 	"github.com/nj-eka/shurl/app/hashid_tokenizer"
 	"github.com/nj-eka/shurl/config"
 	"github.com/nj-eka/shurl/internal/errs"
 	"github.com/nj-eka/shurl/store/bolt_store"
+	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 )
 
-var ap *app.App
+var (
+	ap        *app.App
+	linkStore app.LinkStore
+	hitStore  app.HitStore
+	tokenizer app.Tokenizer
+	auditor   = &testAuditor{}
+)
+
+type testAuditor struct {
+	events []*app.AuditEvent
+}
+
+func (ta *testAuditor) Audit(_ context.Context, event *app.AuditEvent) errs.Error {
+	ta.events = append(ta.events, event)
+	return nil
+}
+
 var id2key = map[int]string{
 	0: "EZead",
 	1: "EdGed",
@@ -24,7 +45,8 @@ var expiredAt = time.Now().AddDate(0, 0, 1)
 
 func appInit() {
 	ctx := context.Background()
-	tokenizer, err := hashid_tokenizer.NewHashidTokenizer(&config.HashidTokenizerConfig{
+	var err error
+	tokenizer, err = hashid_tokenizer.NewHashidTokenizer(&config.HashidTokenizerConfig{
 		Salt:      "ecafbaf0-1bcc-11ec-9621-0242ac130002",
 		MinLength: 5,
 		Alphabet:  "0123456789_abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ",
@@ -37,7 +59,12 @@ func appInit() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	ap = app.NewApp(store, tokenizer)
+	hitStore, err = bolt_store.NewBoltHitStore(ctx, store)
+	if err != nil {
+		log.Fatal(err)
+	}
+	linkStore = store
+	ap = app.NewApp(store, tokenizer, app.WithHitStore(hitStore), app.WithBackuper(store.(app.Backuper)), app.WithAuditor(auditor))
 }
 
 func TestMain(m *testing.M) {
@@ -52,6 +79,7 @@ func TestApp_CreateToken(t *testing.T) {
 	ctx := context.Background()
 	type args struct {
 		targetUrl string
+		alias     string
 		expiredAt *time.Time
 	}
 	tests := []struct {
@@ -61,14 +89,19 @@ func TestApp_CreateToken(t *testing.T) {
 		wantAdded bool
 		wantErr   error
 	}{
-		{"add invalid url", args{"https//stackoverflow.com", nil}, "", false, app.ErrInvalidUrl},
-		{"add first url", args{"https://stackoverflow.com", nil}, id2key[1], true, nil},
-		{"add first url update", args{"https://stackoverflow.com", &expiredAt}, id2key[1], false, nil},
-		{"add second url", args{"https://stackoverflow.com/questions", &expiredAt}, id2key[2], true, nil},
+		{"add invalid url", args{"https//stackoverflow.com", "", nil}, "", false, app.ErrInvalidUrl},
+		{"add first url", args{"https://stackoverflow.com", "", nil}, id2key[1], true, nil},
+		{"add first url update", args{"https://stackoverflow.com", "", &expiredAt}, id2key[1], false, nil},
+		{"add second url", args{"https://stackoverflow.com/questions", "", &expiredAt}, id2key[2], true, nil},
+		{"add aliased url", args{"https://go.dev", "go_dev", &expiredAt}, "go_dev", true, nil},
+		{"add taken alias", args{"https://golang.org", "go_dev", nil}, "", false, app.ErrAliasExists},
+		{"add reserved alias", args{"https://golang.org", "openapi", nil}, "", false, app.ErrInvalidAlias},
+		{"add alias out of alphabet", args{"https://golang.org", "go-dev", nil}, "", false, app.ErrInvalidAlias},
+		{"add alias decodable as token", args{"https://golang.org", id2key[0], nil}, "", false, app.ErrInvalidAlias},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotKey, gotAdded, gotErr := ap.CreateToken(ctx, tt.args.targetUrl, tt.args.expiredAt)
+			gotKey, gotAdded, gotErr := ap.CreateToken(ctx, &app.Link{TargetUrl: tt.args.targetUrl, Alias: tt.args.alias, ExpiredAt: tt.args.expiredAt})
 			if gotKey != tt.wantKey {
 				t.Errorf("CreateToken() gotKey = %v, want %v", gotKey, tt.wantKey)
 			}
@@ -104,7 +137,7 @@ func TestApp_GetLink(t *testing.T) {
 		{"get first link", args{id2key[1]}, &app.Link{
 			Id:        1,
 			TargetUrl: "https://stackoverflow.com",
-			ExpiredAt: &expiredAt,
+			ExpiredAt: nil, // expiration isn't set to existing link by CreateToken
 			Hits:      0,
 		}, nil},
 		{"get second link", args{id2key[2]}, &app.Link{
@@ -113,6 +146,13 @@ func TestApp_GetLink(t *testing.T) {
 			ExpiredAt: &expiredAt,
 			Hits:      0,
 		}, nil},
+		{"get aliased link", args{"go_dev"}, &app.Link{
+			Id:        3,
+			TargetUrl: "https://go.dev",
+			ExpiredAt: &expiredAt,
+			Hits:      0,
+		}, nil},
+		{"get not existed alias", args{"golang"}, nil, app.ErrNotFound},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -137,7 +177,8 @@ func TestApp_GetLink(t *testing.T) {
 			}
 			if gotLink.Id != tt.wantLink.Id ||
 				gotLink.TargetUrl != tt.wantLink.TargetUrl ||
-				gotLink.ExpiredAt.UnixNano() != tt.wantLink.ExpiredAt.UnixNano() ||
+				(gotLink.ExpiredAt == nil) != (tt.wantLink.ExpiredAt == nil) ||
+				(gotLink.ExpiredAt != nil && gotLink.ExpiredAt.UnixNano() != tt.wantLink.ExpiredAt.UnixNano()) ||
 				gotLink.Hits != tt.wantLink.Hits {
 				t.Errorf("GetLink() got = %v, want %v", gotLink, tt.wantLink)
 			}
@@ -173,10 +214,14 @@ func TestApp_HitLink(t *testing.T) {
 			ExpiredAt: &expiredAt,
 			Hits:      1,
 		}, nil},
+		{"hit aliased link", args{"go_dev"}, &app.Link{
+			Id:   3,
+			Hits: 1,
+		}, nil},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotLink, gotErr := ap.HitLink(ctx, tt.args.key)
+			gotLink, gotErr := ap.HitLink(ctx, tt.args.key, &app.Hit{Referer: "https://example.com", UserAgent: "test", ClientIP: "127.0.0.1"})
 			if tt.wantErrIs != nil || gotErr != nil {
 				if ee, ok := tt.wantErrIs.(errs.Error); ok {
 					if ee == gotErr {
@@ -205,6 +250,212 @@ func TestApp_HitLink(t *testing.T) {
 	}
 }
 
+func TestApp_ListHits(t *testing.T) {
+	ctx := context.Background()
+	type args struct {
+		key  string
+		from time.Time
+		to   time.Time
+	}
+	now := time.Now()
+	tests := []struct {
+		name      string
+		args      args
+		wantHits  int
+		wantErrIs error
+	}{
+		{"hits of not existed", args{"DApEj4wbneowA", time.Time{}, now}, 0, app.ErrNotFound},
+		{"hits of first link", args{id2key[1], time.Time{}, now}, 2, nil},
+		{"hits of aliased link", args{"go_dev", time.Time{}, now}, 1, nil},
+		{"hits of first link in future", args{id2key[1], now, now.Add(time.Hour)}, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotHits, gotErr := ap.ListHits(ctx, tt.args.key, tt.args.from, tt.args.to, 0)
+			if tt.wantErrIs != nil || gotErr != nil {
+				if !errors.Is(gotErr, tt.wantErrIs) {
+					t.Errorf("ListHits() gotErr = %v, want %v", gotErr, tt.wantErrIs)
+				}
+				return
+			}
+			if len(gotHits) != tt.wantHits {
+				t.Errorf("ListHits() got = %v hits, want %v", len(gotHits), tt.wantHits)
+				return
+			}
+			for _, hit := range gotHits {
+				if hit.Referer != "https://example.com" || hit.UserAgent != "test" || hit.ClientIP != "127.0.0.1" {
+					t.Errorf("ListHits() got = %v", hit)
+				}
+			}
+		})
+	}
+}
+
+func TestApp_Stats(t *testing.T) {
+	ctx := context.Background()
+	type args struct {
+		key  string
+		g    app.Granularity
+		from time.Time
+		to   time.Time
+	}
+	now := time.Now().UTC()
+	tests := []struct {
+		name        string
+		args        args
+		wantBuckets int
+		wantHits    int
+		wantErrIs   error
+	}{
+		{"stats of not existed", args{"DApEj4wbneowA", app.GranularityHour, now.Add(-time.Hour), now}, 0, 0, app.ErrNotFound},
+		{"hourly stats of first link", args{id2key[1], app.GranularityHour, now.Add(-2 * time.Hour), now}, 3, 2, nil},
+		{"daily stats of aliased link", args{"go_dev", app.GranularityDay, now, now}, 1, 1, nil},
+		{"hourly stats in future", args{"go_dev", app.GranularityHour, now.Add(2 * time.Hour), now.Add(3 * time.Hour)}, 2, 0, nil},
+		{"invalid range", args{"go_dev", app.GranularityHour, now, now.Add(-2 * time.Hour)}, 0, 0, app.ErrInvalidRange},
+		{"invalid granularity", args{"go_dev", "week", now.Add(-time.Hour), now}, 0, 0, app.ErrInvalidRange},
+		{"too many buckets", args{"go_dev", app.GranularityHour, now.AddDate(-1, 0, 0), now}, 0, 0, app.ErrInvalidRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStats, gotErr := ap.Stats(ctx, tt.args.key, tt.args.g, tt.args.from, tt.args.to)
+			if tt.wantErrIs != nil || gotErr != nil {
+				if !errors.Is(gotErr, tt.wantErrIs) {
+					t.Errorf("Stats() gotErr = %v, want %v", gotErr, tt.wantErrIs)
+				}
+				return
+			}
+			gotHits := 0
+			for _, bucket := range gotStats {
+				gotHits += bucket.Hits
+			}
+			if len(gotStats) != tt.wantBuckets || gotHits != tt.wantHits {
+				t.Errorf("Stats() got = %v buckets with %v hits, want %v with %v", len(gotStats), gotHits, tt.wantBuckets, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestApp_UniqueVisitors(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	tests := []struct {
+		name      string
+		key       string
+		from, to  time.Time
+		want      int
+		wantErrIs error
+	}{
+		{"uniques of not existed", "DApEj4wbneowA", now.AddDate(0, 0, -1), now, 0, app.ErrNotFound},
+		{"uniques of first link", id2key[1], now.AddDate(0, 0, -1), now, 1, nil},
+		{"uniques of aliased link", "go_dev", now, now, 1, nil},
+		{"uniques in past", "go_dev", now.AddDate(0, 0, -10), now.AddDate(0, 0, -5), 0, nil},
+		{"invalid range", "go_dev", now, now.AddDate(0, 0, -1), 0, app.ErrInvalidRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotErr := ap.UniqueVisitors(ctx, tt.key, tt.from, tt.to)
+			if tt.wantErrIs != nil || gotErr != nil {
+				if !errors.Is(gotErr, tt.wantErrIs) {
+					t.Errorf("UniqueVisitors() gotErr = %v, want %v", gotErr, tt.wantErrIs)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("UniqueVisitors() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	link, err := ap.GetLink(ctx, id2key[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.UniqueVisitors != 1 {
+		t.Errorf("GetLink() got UniqueVisitors = %v, want 1", link.UniqueVisitors)
+	}
+}
+
+func TestApp_ListLinks(t *testing.T) {
+	ctx := context.Background()
+	var cursor *app.ListCursor
+	tests := []struct {
+		name     string
+		query    app.ListQuery
+		wantKeys []string
+		wantNext bool
+	}{
+		{"list first page", app.ListQuery{SortBy: app.SortById, Limit: 2}, []string{id2key[1], id2key[2]}, true},
+		{"list last page", app.ListQuery{SortBy: app.SortById, Limit: 2}, []string{"go_dev"}, false},
+		{"list by hits", app.ListQuery{SortBy: app.SortByHits, Desc: true}, []string{id2key[1], "go_dev", id2key[2]}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.query.After = cursor
+			gotLinks, gotNext, gotErr := ap.ListLinks(ctx, tt.query)
+			if gotErr != nil {
+				t.Errorf("ListLinks() gotErr = %v", gotErr)
+				return
+			}
+			gotKeys := make([]string, 0, len(gotLinks))
+			for _, link := range gotLinks {
+				gotKeys = append(gotKeys, link.Key)
+			}
+			if !reflect.DeepEqual(gotKeys, tt.wantKeys) {
+				t.Errorf("ListLinks() got = %v, want %v", gotKeys, tt.wantKeys)
+			}
+			if (gotNext != nil) != tt.wantNext {
+				t.Errorf("ListLinks() gotNext = %v, want %v", gotNext, tt.wantNext)
+			}
+			cursor = gotNext
+		})
+	}
+}
+
+func TestApp_UpdateLink(t *testing.T) {
+	ctx := context.Background()
+	later := time.Now().Add(time.Hour)
+	url := func(s string) *string { return &s }
+	tests := []struct {
+		name      string
+		key       string
+		patch     *app.LinkPatch
+		wantUrl   string
+		wantErrIs error
+	}{
+		{"update invalid url", id2key[1], &app.LinkPatch{TargetUrl: url("https//go.dev")}, "", app.ErrInvalidUrl},
+		{"update not existed", "DApEj4wbneowA", &app.LinkPatch{ExpiredAt: &later}, "", app.ErrNotFound},
+		{"update expiration", id2key[1], &app.LinkPatch{ExpiredAt: &later}, "https://stackoverflow.com", nil},
+		{"update url", "go_dev", &app.LinkPatch{TargetUrl: url("https://go.dev/doc")}, "https://go.dev/doc", nil},
+		{"update to url of another link", "go_dev", &app.LinkPatch{TargetUrl: url("https://stackoverflow.com")}, "", app.ErrConflict},
+		{"update url back", "go_dev", &app.LinkPatch{TargetUrl: url("https://go.dev")}, "https://go.dev", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotLink, gotErr := ap.UpdateLink(ctx, tt.key, tt.patch)
+			if tt.wantErrIs != nil || gotErr != nil {
+				if !errors.Is(gotErr, tt.wantErrIs) {
+					t.Errorf("UpdateLink() gotErr = %v, want %v", gotErr, tt.wantErrIs)
+				}
+				return
+			}
+			link, err := ap.GetLink(ctx, tt.key)
+			if err != nil || link.TargetUrl != tt.wantUrl || gotLink.TargetUrl != tt.wantUrl {
+				t.Errorf("GetLink() after UpdateLink() got = %v, %v, want url %v", link, err, tt.wantUrl)
+				return
+			}
+			if tt.patch.ExpiredAt != nil && (link.ExpiredAt == nil || link.ExpiredAt.UnixNano() != tt.patch.ExpiredAt.UnixNano()) {
+				t.Errorf("GetLink() after UpdateLink() got expiredAt = %v, want %v", link.ExpiredAt, tt.patch.ExpiredAt)
+			}
+		})
+	}
+	// expiration of existing link isn't changed by CreateToken
+	if _, _, err := ap.CreateToken(ctx, &app.Link{TargetUrl: "https://go.dev"}); err != nil {
+		t.Fatal(err)
+	}
+	if link, err := ap.GetLink(ctx, "go_dev"); err != nil || link.ExpiredAt == nil || link.ExpiredAt.UnixNano() != expiredAt.UnixNano() {
+		t.Errorf("GetLink() after CreateToken() got = %v, %v, want expiredAt %v", link, err, expiredAt)
+	}
+}
+
 func TestApp_DeleteLink(t *testing.T) {
 	ctx := context.Background()
 	type args struct {
@@ -236,3 +487,723 @@ func TestApp_DeleteLink(t *testing.T) {
 		})
 	}
 }
+
+func TestApp_RestoreLink(t *testing.T) {
+	ctx := context.Background()
+	type args struct {
+		key string
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantErrIs error
+	}{
+		{"restore not existed", args{"DApEj4wbneowA"}, app.ErrNotFound},
+		{"restore not deleted link", args{"go_dev"}, app.ErrNotDeleted},
+		{"restore deleted link", args{id2key[1]}, nil},
+		{"restore restored link", args{id2key[1]}, app.ErrNotDeleted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotErr := ap.RestoreLink(ctx, tt.args.key)
+			if tt.wantErrIs != nil || gotErr != nil {
+				if !errors.Is(gotErr, tt.wantErrIs) {
+					t.Errorf("RestoreLink() gotErr = %v, want %v", gotErr, tt.wantErrIs)
+				}
+				return
+			}
+			if link, err := ap.GetLink(ctx, tt.args.key); err != nil || link.DeletedAt != nil {
+				t.Errorf("GetLink() after RestoreLink() got = %v, %v, want not deleted", link, err)
+			}
+		})
+	}
+	if err := ap.DeleteLink(app.WithPrincipal(ctx, &app.Principal{Admin: true}), id2key[1]); err != nil {
+		t.Fatal(err)
+	}
+	// deleted links are reaped after retention period so they can't be restored
+	reaper, err := app.NewReaper(linkStore, nil, config.ReaperConfig{Retention: time.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if gotErr := app.NewApp(linkStore, tokenizer, app.WithReaper(reaper)).RestoreLink(ctx, id2key[1]); !errors.Is(gotErr, app.ErrRetentionExpired) {
+		t.Errorf("RestoreLink() after retention gotErr = %v, want %v", gotErr, app.ErrRetentionExpired)
+	}
+	var gotActions []string
+	for _, event := range auditor.events {
+		gotActions = append(gotActions, fmt.Sprintf("%s %d", event.Action, event.LinkId))
+	}
+	wantActions := []string{"update 1", "update 3", "update 3", "delete 1", "delete 2", "restore 1", "delete 1"}
+	if last := auditor.events[len(auditor.events)-1]; !last.Admin || last.Owner != "" {
+		t.Errorf("audit trail got last event by %q admin %v, want by admin", last.Owner, last.Admin)
+	}
+	if !reflect.DeepEqual(gotActions, wantActions) {
+		t.Errorf("audit trail got = %v, want %v", gotActions, wantActions)
+	}
+}
+
+func TestApp_Backup(t *testing.T) {
+	ctx := context.Background()
+	defer func() {
+		_ = os.Remove("snapshot.db")
+	}()
+	f, err := os.Create("snapshot.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, gotErr := ap.Backup(ctx, f)
+	_ = f.Close()
+	if gotErr != nil || n == 0 {
+		t.Fatalf("Backup() got = %v, %v", n, gotErr)
+	}
+	gotCount, gotErr := bolt_store.ValidateSnapshot(ctx, "snapshot.db")
+	if gotErr != nil || gotCount != 3 {
+		t.Errorf("ValidateSnapshot() got = %v, %v, want %v", gotCount, gotErr, 3)
+	}
+	if _, gotErr = app.NewApp(nil, nil).Backup(ctx, ioutil.Discard); !errors.Is(gotErr, app.ErrNotSupported) {
+		t.Errorf("Backup() without backuper gotErr = %v, want %v", gotErr, app.ErrNotSupported)
+	}
+}
+
+func TestApp_Reap(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	dryReaper, err := app.NewReaper(linkStore, nil, config.ReaperConfig{Retention: time.Hour, Batch: 1, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reaper, err := app.NewReaper(linkStore, nil, config.ReaperConfig{Retention: time.Hour, Batch: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		reaper      *app.Reaper
+		now         time.Time
+		wantExpired int
+		wantDeleted int
+	}{
+		{"nothing to reap within retention", reaper, now, 0, 0},
+		{"dry run", dryReaper, now.AddDate(0, 0, 2), 1, 2},
+		{"dry run again", dryReaper, now.AddDate(0, 0, 2), 1, 2},
+		{"reap", reaper, now.AddDate(0, 0, 2), 1, 2},
+		{"nothing left to reap", reaper, now.AddDate(0, 0, 2), 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotExpired, gotDeleted, gotErr := tt.reaper.Reap(ctx, tt.now)
+			if gotErr != nil || gotExpired != tt.wantExpired || gotDeleted != tt.wantDeleted {
+				t.Errorf("Reap() got = %v, %v, %v, want %v, %v", gotExpired, gotDeleted, gotErr, tt.wantExpired, tt.wantDeleted)
+			}
+		})
+	}
+	if got := dryReaper.Stats(); !got.DryRun || got.Runs != 2 || got.Expired != 2 || got.Deleted != 4 {
+		t.Errorf("Stats() of dry run got = %+v", got)
+	}
+	if got := reaper.Stats(); got.Runs != 3 || got.Expired != 1 || got.Deleted != 2 || got.Errors != 0 {
+		t.Errorf("Stats() got = %+v", got)
+	}
+	if links, err := linkStore.List(ctx, app.ListQuery{SortBy: app.SortById}); err != nil || len(links) != 0 {
+		t.Errorf("List() after Reap() got = %v, %v, want none", links, err)
+	}
+}
+
+func TestApp_ReapHits(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	dryReaper, err := app.NewReaper(linkStore, hitStore, config.ReaperConfig{Retention: time.Hour, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reaper, err := app.NewReaper(linkStore, hitStore, config.ReaperConfig{Retention: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _, err := linkStore.Create(ctx, &app.Link{TargetUrl: "https://go.dev/reaped-hits"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := hitStore.AddHit(ctx, &app.Hit{LinkId: id, Time: now.Add(time.Duration(i) * time.Minute), ClientIP: "127.0.0.1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := linkStore.SetDeleted(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	// records are hits, counters of time buckets of all granularities and sketches of uniques time buckets and of all time
+	wantPurged := 2 + 1
+	for _, g := range app.Granularities {
+		buckets, err := hitStore.Stats(ctx, id, g, time.Time{}, now.AddDate(1, 0, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantPurged += len(buckets)
+		if g == app.UniquesGranularity {
+			wantPurged += len(buckets)
+		}
+	}
+	if _, deleted, err := dryReaper.Reap(ctx, now.Add(2*time.Hour)); err != nil || deleted != 1 || dryReaper.Stats().Purged != 0 {
+		t.Errorf("Reap() in dry run got = %v, %v, purged %v, want 1 deleted and none purged", deleted, err, dryReaper.Stats().Purged)
+	}
+	if _, deleted, err := reaper.Reap(ctx, now.Add(2*time.Hour)); err != nil || deleted != 1 || reaper.Stats().Purged != wantPurged {
+		t.Errorf("Reap() got = %v, %v, purged %v, want 1 deleted and %v purged", deleted, err, reaper.Stats().Purged, wantPurged)
+	}
+	if hits, err := hitStore.ListHits(ctx, id, time.Time{}, now.AddDate(1, 0, 0), 0); err != nil || len(hits) != 0 {
+		t.Errorf("ListHits() after Reap() got = %v, %v, want none", hits, err)
+	}
+	if uniques, err := hitStore.Uniques(ctx, id, time.Time{}, time.Time{}); err != nil || uniques != 0 {
+		t.Errorf("Uniques() after Reap() got = %v, %v, want none", uniques, err)
+	}
+}
+
+func TestApp_ReapExpiredDeleted(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	reaper, err := app.NewReaper(linkStore, nil, config.ReaperConfig{Retention: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiredAt := now.Add(-2 * time.Hour)
+	id, _, err := linkStore.Create(ctx, &app.Link{TargetUrl: "https://go.dev/expired-deleted", ExpiredAt: &expiredAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := linkStore.SetDeleted(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	// link expired long ago but deleted within retention is kept to be restored
+	if gotExpired, gotDeleted, gotErr := reaper.Reap(ctx, now.Add(time.Minute)); gotErr != nil || gotExpired != 0 || gotDeleted != 0 {
+		t.Errorf("Reap() within retention got = %v, %v, %v, want 0, 0", gotExpired, gotDeleted, gotErr)
+	}
+	if _, err := linkStore.Get(ctx, id); err != nil {
+		t.Errorf("Get() after Reap() within retention gotErr = %v, want link kept", err)
+	}
+	if gotExpired, gotDeleted, gotErr := reaper.Reap(ctx, now.Add(2*time.Hour)); gotErr != nil || gotExpired != 0 || gotDeleted != 1 {
+		t.Errorf("Reap() after retention got = %v, %v, %v, want 0, 1", gotExpired, gotDeleted, gotErr)
+	}
+	if _, err := linkStore.Get(ctx, id); !errors.Is(err, app.ErrNotFound) {
+		t.Errorf("Get() after Reap() gotErr = %v, want %v", err, app.ErrNotFound)
+	}
+}
+
+func TestApp_ApiKeys(t *testing.T) {
+	ctx := context.Background()
+	keys := linkStore.(app.KeyStore)
+	hits, err := bolt_store.NewBoltHitStore(ctx, linkStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	owned := app.NewApp(linkStore, tokenizer, app.WithHitStore(hits), app.WithKeyStore(keys))
+	if _, _, gotErr := app.IssueApiKey(ctx, keys, "alice smith", false); !errors.Is(gotErr, app.ErrInvalidOwner) {
+		t.Errorf("IssueApiKey() with invalid owner gotErr = %v, want %v", gotErr, app.ErrInvalidOwner)
+	}
+	principals := make(map[string]context.Context)
+	var aliceToken string
+	for _, owner := range []string{"alice", "bob", "root"} {
+		token, key, err := app.IssueApiKey(ctx, keys, owner, owner == "root")
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := owned.Authenticate(ctx, token)
+		if err != nil || p.Owner != owner || p.Admin != key.Admin {
+			t.Fatalf("Authenticate() got = %v, %v, want principal %s", p, err, owner)
+		}
+		principals[owner] = app.WithPrincipal(ctx, p)
+		if owner == "alice" {
+			aliceToken = token
+		}
+	}
+	for _, token := range []string{"", "unknown.secret", aliceToken[:len(aliceToken)-1] + "x"} {
+		if _, gotErr := owned.Authenticate(ctx, token); !errors.Is(gotErr, app.ErrUnauthorized) {
+			t.Errorf("Authenticate(%q) gotErr = %v, want %v", token, gotErr, app.ErrUnauthorized)
+		}
+	}
+	if _, gotErr := ap.Authenticate(ctx, aliceToken); !errors.Is(gotErr, app.ErrNotSupported) {
+		t.Errorf("Authenticate() without key store gotErr = %v, want %v", gotErr, app.ErrNotSupported)
+	}
+
+	if _, _, gotErr := owned.CreateToken(ctx, &app.Link{TargetUrl: "https://go.dev"}); !errors.Is(gotErr, app.ErrUnauthorized) {
+		t.Errorf("CreateToken() by anonymous gotErr = %v, want %v", gotErr, app.ErrUnauthorized)
+	}
+	aliceKey, added, gotErr := owned.CreateToken(principals["alice"], &app.Link{TargetUrl: "https://go.dev"})
+	if gotErr != nil || !added {
+		t.Fatalf("CreateToken() by alice got = %v, %v, %v", aliceKey, added, gotErr)
+	}
+	bobKey, added, gotErr := owned.CreateToken(principals["bob"], &app.Link{TargetUrl: "https://go.dev"})
+	if gotErr != nil || !added || bobKey == aliceKey {
+		t.Fatalf("CreateToken() of the same url by bob got = %v, %v, %v, want new link", bobKey, added, gotErr)
+	}
+	if link, gotErr := owned.GetLink(ctx, aliceKey); gotErr != nil || link.Owner != "alice" {
+		t.Errorf("GetLink() got = %v, %v, want link of alice", link, gotErr)
+	}
+
+	listTests := []struct {
+		name      string
+		ctx       context.Context
+		wantCount int
+		wantErrIs error
+	}{
+		{"anonymous", ctx, 0, app.ErrUnauthorized},
+		{"owner", principals["bob"], 1, nil},
+		{"admin", principals["root"], 2, nil},
+	}
+	for _, tt := range listTests {
+		t.Run("list by "+tt.name, func(t *testing.T) {
+			links, _, gotErr := owned.ListLinks(tt.ctx, app.ListQuery{SortBy: app.SortById})
+			if !errors.Is(gotErr, tt.wantErrIs) || len(links) != tt.wantCount {
+				t.Errorf("ListLinks() got = %v, %v, want %v links, %v", links, gotErr, tt.wantCount, tt.wantErrIs)
+			}
+		})
+	}
+
+	url := "https://go.dev/doc"
+	manageTests := []struct {
+		name      string
+		do        func(ctx context.Context) errs.Error
+		ctx       context.Context
+		wantErrIs error
+	}{
+		{"update by anonymous", func(ctx context.Context) errs.Error {
+			_, err := owned.UpdateLink(ctx, aliceKey, &app.LinkPatch{TargetUrl: &url})
+			return err
+		}, ctx, app.ErrUnauthorized},
+		{"update by another owner", func(ctx context.Context) errs.Error {
+			_, err := owned.UpdateLink(ctx, aliceKey, &app.LinkPatch{TargetUrl: &url})
+			return err
+		}, principals["bob"], app.ErrForbidden},
+		{"update by admin", func(ctx context.Context) errs.Error {
+			_, err := owned.UpdateLink(ctx, aliceKey, &app.LinkPatch{TargetUrl: &url})
+			return err
+		}, principals["root"], nil},
+		{"stats by another owner", func(ctx context.Context) errs.Error {
+			_, err := owned.Stats(ctx, aliceKey, app.GranularityDay, time.Now().AddDate(0, 0, -1), time.Now())
+			return err
+		}, principals["bob"], app.ErrForbidden},
+		{"stats by owner", func(ctx context.Context) errs.Error {
+			_, err := owned.Stats(ctx, aliceKey, app.GranularityDay, time.Now().AddDate(0, 0, -1), time.Now())
+			return err
+		}, principals["alice"], nil},
+		{"delete by another owner", func(ctx context.Context) errs.Error {
+			return owned.DeleteLink(ctx, aliceKey)
+		}, principals["bob"], app.ErrForbidden},
+		{"delete by owner", func(ctx context.Context) errs.Error {
+			return owned.DeleteLink(ctx, aliceKey)
+		}, principals["alice"], nil},
+		{"restore by another owner", func(ctx context.Context) errs.Error {
+			return owned.RestoreLink(ctx, aliceKey)
+		}, principals["bob"], app.ErrForbidden},
+	}
+	for _, tt := range manageTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if gotErr := tt.do(tt.ctx); !errors.Is(gotErr, tt.wantErrIs) {
+				t.Errorf("gotErr = %v, want %v", gotErr, tt.wantErrIs)
+			}
+		})
+	}
+}
+
+func TestApp_Password(t *testing.T) {
+	ctx := context.Background()
+	limited := app.NewApp(linkStore, tokenizer, app.WithPasswordAttempts(config.PasswordConfig{MaxAttempts: 2, Window: time.Hour}))
+	url, password := "https://go.dev/private", "s3cret"
+	key, added, gotErr := limited.CreateToken(ctx, &app.Link{TargetUrl: url, Password: password})
+	if gotErr != nil || !added {
+		t.Fatalf("CreateToken() with password got = %v, %v, %v", key, added, gotErr)
+	}
+	createTests := []struct {
+		name      string
+		password  string
+		wantErrIs error
+	}{
+		{"the same url without password", "", app.ErrConflict},
+		{"the same url with another password", "another", app.ErrConflict},
+		{"too long password", string(make([]byte, app.MaxPasswordLength+1)), app.ErrInvalidPassword},
+		{"the same url with the same password", password, nil},
+	}
+	for _, tt := range createTests {
+		t.Run("create "+tt.name, func(t *testing.T) {
+			gotKey, _, gotErr := limited.CreateToken(ctx, &app.Link{TargetUrl: url, Password: tt.password})
+			if !errors.Is(gotErr, tt.wantErrIs) || gotErr == nil && gotKey != key {
+				t.Errorf("CreateToken() got = %v, %v, want %v, %v", gotKey, gotErr, key, tt.wantErrIs)
+			}
+		})
+	}
+	if _, gotErr := limited.HitLink(ctx, key, &app.Hit{}); !errors.Is(gotErr, app.ErrPasswordRequired) {
+		t.Errorf("HitLink() gotErr = %v, want %v", gotErr, app.ErrPasswordRequired)
+	}
+	if link, gotErr := limited.GetLink(ctx, key); gotErr != nil || link.TargetUrl != "" {
+		t.Errorf("GetLink() by anonymous got = %v, %v, want hidden target url", link, gotErr)
+	}
+	if link, gotErr := limited.GetLink(app.WithPrincipal(ctx, &app.Principal{Admin: true}), key); gotErr != nil || link.TargetUrl != url {
+		t.Errorf("GetLink() by admin got = %v, %v, want target url %v", link, gotErr, url)
+	}
+	unlockTests := []struct {
+		name      string
+		password  string
+		wantErrIs error
+	}{
+		{"invalid password", "wrong", app.ErrInvalidPassword},
+		{"empty password", "", app.ErrInvalidPassword},
+		{"valid password after limit exceeded", password, app.ErrTooManyAttempts},
+	}
+	for _, tt := range unlockTests {
+		t.Run("unlock with "+tt.name, func(t *testing.T) {
+			if _, gotErr := limited.UnlockLink(ctx, key, tt.password, &app.Hit{}); !errors.Is(gotErr, tt.wantErrIs) {
+				t.Errorf("UnlockLink() gotErr = %v, want %v", gotErr, tt.wantErrIs)
+			}
+		})
+	}
+	if link, gotErr := ap.UnlockLink(ctx, key, password, &app.Hit{}); gotErr != nil || link.TargetUrl != url || link.Hits != 1 {
+		t.Errorf("UnlockLink() with valid password got = %v, %v, want target url %v and 1 hit", link, gotErr, url)
+	}
+}
+
+func TestApp_PasswordConcurrentAttempts(t *testing.T) {
+	ctx := context.Background()
+	const maxAttempts, guesses = 3, 20
+	limited := app.NewApp(linkStore, tokenizer, app.WithPasswordAttempts(config.PasswordConfig{MaxAttempts: maxAttempts, Window: time.Hour}))
+	key, _, err := limited.CreateToken(ctx, &app.Link{TargetUrl: "https://go.dev/guarded", Password: "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	gotErrs := make(chan error, guesses)
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := limited.UnlockLink(ctx, key, fmt.Sprintf("guess%d", i), &app.Hit{})
+			gotErrs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(gotErrs)
+	// only attempts within the limit reach password check, the rest are rejected up front
+	compared := 0
+	for err := range gotErrs {
+		switch {
+		case errors.Is(err, app.ErrInvalidPassword):
+			compared++
+		case !errors.Is(err, app.ErrTooManyAttempts):
+			t.Errorf("UnlockLink() gotErr = %v, want %v or %v", err, app.ErrInvalidPassword, app.ErrTooManyAttempts)
+		}
+	}
+	if compared != maxAttempts {
+		t.Errorf("UnlockLink() got %d password checks of %d concurrent guesses, want %d", compared, guesses, maxAttempts)
+	}
+}
+
+func TestApp_MaxHits(t *testing.T) {
+	ctx := context.Background()
+	url := "https://go.dev/once"
+	key, added, gotErr := ap.CreateToken(ctx, &app.Link{TargetUrl: url, MaxHits: 1})
+	if gotErr != nil || !added {
+		t.Fatalf("CreateToken() with max hits got = %v, %v, %v", key, added, gotErr)
+	}
+	createTests := []struct {
+		name      string
+		maxHits   int
+		wantErrIs error
+	}{
+		{"negative max hits", -1, app.ErrInvalidMaxHits},
+		{"the same url without max hits", 0, app.ErrConflict},
+		{"the same url with another max hits", 2, app.ErrConflict},
+		{"the same url with the same max hits", 1, nil},
+	}
+	for _, tt := range createTests {
+		t.Run("create "+tt.name, func(t *testing.T) {
+			gotKey, _, gotErr := ap.CreateToken(ctx, &app.Link{TargetUrl: url, MaxHits: tt.maxHits})
+			if !errors.Is(gotErr, tt.wantErrIs) || gotErr == nil && gotKey != key {
+				t.Errorf("CreateToken() got = %v, %v, want %v, %v", gotKey, gotErr, key, tt.wantErrIs)
+			}
+		})
+	}
+	if link, gotErr := ap.HitLink(ctx, key, &app.Hit{}); gotErr != nil || link.TargetUrl != url || link.Hits != 1 {
+		t.Errorf("HitLink() got = %v, %v, want target url %v and 1 hit", link, gotErr, url)
+	}
+	if _, gotErr := ap.HitLink(ctx, key, &app.Hit{}); !errors.Is(gotErr, app.ErrHitsExhausted) {
+		t.Errorf("HitLink() of exhausted link gotErr = %v, want %v", gotErr, app.ErrHitsExhausted)
+	}
+}
+
+func TestApp_ActiveFrom(t *testing.T) {
+	ctx := context.Background()
+	url := "https://go.dev/soon"
+	activeFrom := time.Now().UTC().Add(time.Hour)
+	key, added, gotErr := ap.CreateToken(ctx, &app.Link{TargetUrl: url, ActiveFrom: &activeFrom})
+	if gotErr != nil || !added {
+		t.Fatalf("CreateToken() with active from got = %v, %v, %v", key, added, gotErr)
+	}
+	expiredAt := activeFrom.Add(-time.Minute)
+	createTests := []struct {
+		name      string
+		link      *app.Link
+		wantErrIs error
+	}{
+		{"active from after expiration", &app.Link{TargetUrl: "https://go.dev/late", ActiveFrom: &activeFrom, ExpiredAt: &expiredAt}, app.ErrInvalidActiveFrom},
+		{"the same url without active from", &app.Link{TargetUrl: url}, app.ErrConflict},
+		{"the same url with the same active from", &app.Link{TargetUrl: url, ActiveFrom: &activeFrom}, nil},
+	}
+	for _, tt := range createTests {
+		t.Run("create "+tt.name, func(t *testing.T) {
+			gotKey, _, gotErr := ap.CreateToken(ctx, tt.link)
+			if !errors.Is(gotErr, tt.wantErrIs) || gotErr == nil && gotKey != key {
+				t.Errorf("CreateToken() got = %v, %v, want %v, %v", gotKey, gotErr, key, tt.wantErrIs)
+			}
+		})
+	}
+	if _, gotErr := ap.HitLink(ctx, key, &app.Hit{}); !errors.Is(gotErr, app.ErrNotActive) {
+		t.Errorf("HitLink() gotErr = %v, want %v", gotErr, app.ErrNotActive)
+	}
+	if link, gotErr := ap.GetLink(ctx, key); gotErr != nil || link.ActiveFrom == nil || link.Hits != 0 {
+		t.Errorf("GetLink() got = %v, %v, want link active from %v", link, gotErr, activeFrom)
+	}
+
+	owned := app.NewApp(linkStore, tokenizer, app.WithKeyStore(linkStore.(app.KeyStore)))
+	owner := app.WithPrincipal(ctx, &app.Principal{Owner: "carol"})
+	ownedKey, _, gotErr := owned.CreateToken(owner, &app.Link{TargetUrl: url, ActiveFrom: &activeFrom})
+	if gotErr != nil {
+		t.Fatalf("CreateToken() by owner got = %v, %v", ownedKey, gotErr)
+	}
+	getTests := []struct {
+		name      string
+		ctx       context.Context
+		wantErrIs error
+	}{
+		{"owner", owner, nil},
+		{"admin", app.WithPrincipal(ctx, &app.Principal{Owner: "root", Admin: true}), nil},
+		{"another owner", app.WithPrincipal(ctx, &app.Principal{Owner: "dave"}), app.ErrNotFound},
+		{"anonymous", ctx, app.ErrNotFound},
+	}
+	for _, tt := range getTests {
+		t.Run("get by "+tt.name, func(t *testing.T) {
+			if _, gotErr := owned.GetLink(tt.ctx, ownedKey); !errors.Is(gotErr, tt.wantErrIs) {
+				t.Errorf("GetLink() gotErr = %v, want %v", gotErr, tt.wantErrIs)
+			}
+		})
+	}
+}
+
+func TestApp_RedirectType(t *testing.T) {
+	ctx := context.Background()
+	url := "https://go.dev/moved"
+	key, added, gotErr := ap.CreateToken(ctx, &app.Link{TargetUrl: url, RedirectType: http.StatusPermanentRedirect})
+	if gotErr != nil || !added {
+		t.Fatalf("CreateToken() with redirect type got = %v, %v, %v", key, added, gotErr)
+	}
+	createTests := []struct {
+		name         string
+		url          string
+		redirectType int
+		wantErrIs    error
+	}{
+		{"invalid redirect type", "https://go.dev/ok", http.StatusOK, app.ErrInvalidRedirect},
+		{"the same url with default redirect type", url, 0, app.ErrConflict},
+		{"the same url with the same redirect type", url, http.StatusPermanentRedirect, nil},
+	}
+	for _, tt := range createTests {
+		t.Run("create "+tt.name, func(t *testing.T) {
+			gotKey, _, gotErr := ap.CreateToken(ctx, &app.Link{TargetUrl: tt.url, RedirectType: tt.redirectType})
+			if !errors.Is(gotErr, tt.wantErrIs) || gotErr == nil && gotKey != key {
+				t.Errorf("CreateToken() got = %v, %v, want %v, %v", gotKey, gotErr, key, tt.wantErrIs)
+			}
+		})
+	}
+	if link, gotErr := ap.HitLink(ctx, key, &app.Hit{}); gotErr != nil || link.RedirectType != http.StatusPermanentRedirect {
+		t.Errorf("HitLink() got = %v, %v, want redirect type %v", link, gotErr, http.StatusPermanentRedirect)
+	}
+}
+
+func TestApp_Passthrough(t *testing.T) {
+	ctx := context.Background()
+	if _, _, gotErr := ap.CreateToken(ctx, &app.Link{TargetUrl: "https://go.dev/pass", QueryPassthrough: "merge"}); !errors.Is(gotErr, app.ErrInvalidPassthrough) {
+		t.Errorf("CreateToken() with invalid query passthrough gotErr = %v, want %v", gotErr, app.ErrInvalidPassthrough)
+	}
+	query := map[string][]string{"a": {"2"}, "b": {"3"}}
+	tests := []struct {
+		name      string
+		link      *app.Link
+		path      string
+		want      string
+		wantErrIs error
+	}{
+		{"query isn't passed by default", &app.Link{TargetUrl: "https://go.dev/pass/none?a=1"}, "", "https://go.dev/pass/none?a=1", nil},
+		{"query keeping target params", &app.Link{TargetUrl: "https://go.dev/pass/keep?a=1", QueryPassthrough: app.QueryKeep}, "", "https://go.dev/pass/keep?a=1&b=3", nil},
+		{"query overriding target params", &app.Link{TargetUrl: "https://go.dev/pass/override?c=0&a=1", QueryPassthrough: app.QueryOverride}, "", "https://go.dev/pass/override?a=2&c=0&b=3", nil},
+		{"query appended to target params", &app.Link{TargetUrl: "https://go.dev/pass/append?a=1#top", QueryPassthrough: app.QueryAppend}, "", "https://go.dev/pass/append?a=1&a=2&b=3#top", nil},
+		{"path of wildcard link", &app.Link{TargetUrl: "https://go.dev/pass/dir/", PathPassthrough: true}, "x/../../y z", "https://go.dev/pass/dir/y%20z", nil},
+		{"path and query of wildcard link", &app.Link{TargetUrl: "https://go.dev/pass/both", QueryPassthrough: app.QueryKeep, PathPassthrough: true}, "x", "https://go.dev/pass/both/x?a=2&b=3", nil},
+		{"path of not wildcard link", &app.Link{TargetUrl: "https://go.dev/pass/strict"}, "x", "", app.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, _, err := ap.CreateToken(ctx, tt.link)
+			if err != nil {
+				t.Fatal(err)
+			}
+			link, gotErr := ap.HitLink(ctx, key, &app.Hit{Path: tt.path, Query: query})
+			if !errors.Is(gotErr, tt.wantErrIs) || gotErr == nil && link.TargetUrl != tt.want {
+				t.Errorf("HitLink() got = %v, %v, want target url %v, %v", link, gotErr, tt.want, tt.wantErrIs)
+			}
+		})
+	}
+}
+
+func TestApp_Utm(t *testing.T) {
+	ctx := context.Background()
+	tagged := app.NewApp(linkStore, tokenizer, app.WithUtm(config.UtmConfig{Source: "shurl", Medium: "link"}))
+	if key, _, err := tagged.CreateToken(ctx, &app.Link{TargetUrl: "https://go.dev/utm/dup", Utm: &app.Utm{Source: "a"}}); err != nil {
+		t.Fatalf("CreateToken() got = %v, %v", key, err)
+	}
+	if _, _, gotErr := tagged.CreateToken(ctx, &app.Link{TargetUrl: "https://go.dev/utm/dup", Utm: &app.Utm{Source: "b"}}); !errors.Is(gotErr, app.ErrConflict) {
+		t.Errorf("CreateToken() of the same url with another utm gotErr = %v, want %v", gotErr, app.ErrConflict)
+	}
+	query := map[string][]string{"utm_source": {"request"}, "ref": {"x"}}
+	tests := []struct {
+		name string
+		a    *app.App
+		link *app.Link
+		want string
+	}{
+		{"no utm", ap, &app.Link{TargetUrl: "https://go.dev/utm/none?x=1"}, "https://go.dev/utm/none?x=1"},
+		{"utm of link", ap, &app.Link{TargetUrl: "https://go.dev/utm/own?utm_source=old&x=1", Utm: &app.Utm{Source: "mail", Campaign: "launch"}},
+			"https://go.dev/utm/own?utm_campaign=launch&utm_source=mail&x=1"},
+		{"default utm", tagged, &app.Link{TargetUrl: "https://go.dev/utm/default?utm_medium=own"}, "https://go.dev/utm/default?utm_medium=own&utm_source=shurl"},
+		{"utm of link and default utm", tagged, &app.Link{TargetUrl: "https://go.dev/utm/both", Utm: &app.Utm{Source: "mail", Term: "go"}},
+			"https://go.dev/utm/both?utm_medium=link&utm_source=mail&utm_term=go"},
+		{"utm and query passthrough", tagged, &app.Link{TargetUrl: "https://go.dev/utm/pass", QueryPassthrough: app.QueryKeep},
+			"https://go.dev/utm/pass?utm_medium=link&utm_source=shurl&ref=x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, _, err := tt.a.CreateToken(ctx, tt.link)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if link, gotErr := tt.a.HitLink(ctx, key, &app.Hit{Query: query}); gotErr != nil || link.TargetUrl != tt.want {
+				t.Errorf("HitLink() got = %v, %v, want target url %v", link, gotErr, tt.want)
+			}
+		})
+	}
+}
+
+func TestApp_Rules(t *testing.T) {
+	ctx := context.Background()
+	invalid := [][]app.Rule{
+		{{TargetUrl: "https://go.dev/rules/any"}},
+		{{Platform: "beos", TargetUrl: "https://go.dev/rules/beos"}},
+		{{CIDR: "10.0.0.0/33", TargetUrl: "https://go.dev/rules/net"}},
+		{{Language: "de", TargetUrl: "not url"}},
+	}
+	for _, rules := range invalid {
+		if _, _, gotErr := ap.CreateToken(ctx, &app.Link{TargetUrl: "https://go.dev/rules/invalid", Rules: rules}); !errors.Is(gotErr, app.ErrInvalidRule) {
+			t.Errorf("CreateToken() with rules %v gotErr = %v, want %v", rules, gotErr, app.ErrInvalidRule)
+		}
+	}
+	rules := []app.Rule{
+		{Platform: app.PlatformIOS, TargetUrl: "https://apps.apple.com/app/go"},
+		{Platform: app.PlatformAndroid, Language: "de", TargetUrl: "https://play.google.com/store/apps/go?hl=de"},
+		{Platform: app.PlatformAndroid, TargetUrl: "https://play.google.com/store/apps/go"},
+		{Language: "pt-BR", TargetUrl: "https://go.dev/rules/br"},
+		{CIDR: "10.0.0.0/8", TargetUrl: "https://go.dev/rules/intranet"},
+	}
+	key, _, err := ap.CreateToken(ctx, &app.Link{TargetUrl: "https://go.dev/rules", Rules: rules, QueryPassthrough: app.QueryAppend})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, gotErr := ap.CreateToken(ctx, &app.Link{TargetUrl: "https://go.dev/rules", Rules: rules[1:]}); !errors.Is(gotErr, app.ErrConflict) {
+		t.Errorf("CreateToken() of the same url with another rules gotErr = %v, want %v", gotErr, app.ErrConflict)
+	}
+	const (
+		iphone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+		android = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36"
+		linux   = "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0"
+	)
+	tests := []struct {
+		name     string
+		hit      *app.Hit
+		want     string
+		wantRule int
+	}{
+		{"ios", &app.Hit{UserAgent: iphone, AcceptLanguage: "de-DE", ClientIP: "10.1.1.1"}, "https://apps.apple.com/app/go", 1},
+		{"android of language", &app.Hit{UserAgent: android, AcceptLanguage: "en;q=0.5, de-AT"}, "https://play.google.com/store/apps/go?hl=de", 2},
+		{"android", &app.Hit{UserAgent: android, AcceptLanguage: "en-US,de;q=0.8"}, "https://play.google.com/store/apps/go", 3},
+		{"language of region", &app.Hit{UserAgent: linux, AcceptLanguage: "pt-br,pt;q=0.9"}, "https://go.dev/rules/br", 4},
+		{"language of another region", &app.Hit{UserAgent: linux, AcceptLanguage: "pt-PT"}, "https://go.dev/rules", 0},
+		{"client network", &app.Hit{UserAgent: linux, ClientIP: "10.20.30.40", Query: map[string][]string{"ref": {"x"}}}, "https://go.dev/rules/intranet?ref=x", 5},
+		{"no match", &app.Hit{UserAgent: linux, ClientIP: "192.168.1.1"}, "https://go.dev/rules", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if link, gotErr := ap.HitLink(ctx, key, tt.hit); gotErr != nil || link.TargetUrl != tt.want || tt.hit.Rule != tt.wantRule {
+				t.Errorf("HitLink() got = %v, %v, rule %d, want target url %v, rule %d", link, gotErr, tt.hit.Rule, tt.want, tt.wantRule)
+			}
+		})
+	}
+	hits, err := ap.ListHits(ctx, key, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), 0)
+	if err != nil || len(hits) != len(tests) {
+		t.Fatalf("ListHits() got = %v, %v, want %d hits", hits, err, len(tests))
+	}
+	for i, hit := range hits {
+		if hit.Rule != tests[i].wantRule {
+			t.Errorf("ListHits() got hit [%d] of rule %d, want %d", i, hit.Rule, tests[i].wantRule)
+		}
+	}
+	if _, gotErr := ap.UpdateLink(ctx, key, &app.LinkPatch{Rules: &[]app.Rule{{Platform: "beos", TargetUrl: "https://go.dev/rules/beos"}}}); !errors.Is(gotErr, app.ErrInvalidRule) {
+		t.Errorf("UpdateLink() gotErr = %v, want %v", gotErr, app.ErrInvalidRule)
+	}
+	if link, gotErr := ap.UpdateLink(ctx, key, &app.LinkPatch{Rules: &[]app.Rule{}}); gotErr != nil || link.Rules != nil {
+		t.Fatalf("UpdateLink() got = %v, %v, want no rules", link, gotErr)
+	}
+	if link, gotErr := ap.HitLink(ctx, key, &app.Hit{UserAgent: iphone}); gotErr != nil || link.TargetUrl != "https://go.dev/rules" {
+		t.Errorf("HitLink() after rules are removed got = %v, %v", link, gotErr)
+	}
+}
+
+func TestApp_CreateRenewsDead(t *testing.T) {
+	ctx := context.Background()
+	expiredAt := time.Now().UTC().Add(-time.Hour)
+	var err error
+	expiredId, _, err := linkStore.Create(ctx, &app.Link{TargetUrl: "https://go.dev/renewed/expired", ExpiredAt: &expiredAt, MaxHits: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := linkStore.Hit(ctx, expiredId); err != nil {
+		t.Fatal(err)
+	}
+	expiredKey, err := tokenizer.Encode(expiredId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deletedKey, _, err := ap.CreateToken(ctx, &app.Link{TargetUrl: "https://go.dev/renewed/deleted"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ap.DeleteLink(ctx, deletedKey); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		url     string
+		wantKey string
+	}{
+		{"expired link", "https://go.dev/renewed/expired", expiredKey},
+		{"deleted link", "https://go.dev/renewed/deleted", deletedKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotKey, gotAdded, gotErr := ap.CreateToken(ctx, &app.Link{TargetUrl: tt.url})
+			if gotErr != nil || !gotAdded || gotKey != tt.wantKey {
+				t.Fatalf("CreateToken() got = %v, %v, %v, want %v added", gotKey, gotAdded, gotErr, tt.wantKey)
+			}
+			link, err := ap.GetLink(ctx, gotKey)
+			if err != nil || link.DeletedAt != nil || link.ExpiredAt != nil || link.MaxHits != 0 || link.Hits != 0 {
+				t.Errorf("GetLink() got = %v, %v, want renewed link", link, err)
+			}
+			if _, err := ap.HitLink(ctx, gotKey, &app.Hit{}); err != nil {
+				t.Errorf("HitLink() of renewed link gotErr = %v", err)
+			}
+		})
+	}
+}
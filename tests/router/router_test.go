@@ -0,0 +1,177 @@
+package router_test
+
+import (
+	"context"
+	"github.com/nj-eka/shurl/api/router"
+	"github.com/nj-eka/shurl/app"
+	"github.com/nj-eka/shurl/app/hashid_tokenizer"
+	"github.com/nj-eka/shurl/config"
+	"github.com/nj-eka/shurl/store/bolt_store"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const adminToken = "s3cret"
+
+var (
+	ap        *app.App
+	art       *router.AppRouter
+	store     app.LinkStore
+	tokenizer app.Tokenizer
+)
+
+func routerInit() {
+	ctx := context.Background()
+	var err error
+	tokenizer, err = hashid_tokenizer.NewHashidTokenizer(&config.HashidTokenizerConfig{
+		Salt:      "ecafbaf0-1bcc-11ec-9621-0242ac130002",
+		MinLength: 5,
+		Alphabet:  "0123456789_abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	_ = os.Remove("links.db")
+	store, err = bolt_store.NewBoltLinkStore(ctx, config.BoltStoreConfig{FilePath: "links.db", Timeout: 10 * time.Second})
+	if err != nil {
+		log.Fatal(err)
+	}
+	ap = app.NewApp(store, tokenizer)
+	if art, err = router.NewAppRouter(ctx, ap, &config.RouterConfig{AdminToken: adminToken}); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func TestMain(m *testing.M) {
+	routerInit()
+	code := m.Run()
+	_ = ap.Close(context.TODO())
+	_ = os.Remove("links.db")
+	os.Exit(code)
+}
+
+// serve passes request to handler, authorization = "" - request is anonymous
+func serve(h http.Handler, method, target, authorization, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestRouter_Admin(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+	}{
+		{"admin token", "Bearer " + adminToken, http.StatusNotImplemented}, // reaper is off
+		{"no token", "", http.StatusUnauthorized},
+		{"admin token without scheme", adminToken, http.StatusUnauthorized},
+		{"admin token of another scheme", "Basic " + adminToken, http.StatusUnauthorized},
+		{"invalid token", "Bearer " + adminToken + "x", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serve(art, http.MethodGet, "/admin/reaper", tt.authorization, ""); got.Code != tt.wantStatus {
+				t.Errorf("GET /admin/reaper got status %d, want %d", got.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRouter_RestoreShortUrl(t *testing.T) {
+	key, _, err := ap.CreateToken(context.Background(), &app.Link{TargetUrl: "https://go.dev/restored"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := serve(art, http.MethodDelete, "/"+key, "Bearer "+adminToken, ""); got.Code != http.StatusNoContent {
+		t.Fatalf("DELETE /%s got status %d, want %d", key, got.Code, http.StatusNoContent)
+	}
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"admin token", "Bearer " + adminToken, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serve(art, http.MethodPost, "/"+key+"/restore", tt.authorization, ""); got.Code != tt.wantStatus {
+				t.Errorf("POST /%s/restore got status %d, want %d", key, got.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRouter_UpdateShortUrl(t *testing.T) {
+	ctx := context.Background()
+	key, _, err := ap.CreateToken(ctx, &app.Link{TargetUrl: "https://go.dev/updated"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name          string
+		authorization string
+		targetUrl     string
+		wantStatus    int
+		wantTargetUrl string
+	}{
+		{"anonymous", "", "https://example.com/hijacked", http.StatusUnauthorized, "https://go.dev/updated"},
+		{"admin token", "Bearer " + adminToken, "https://golang.org/updated", http.StatusOK, "https://golang.org/updated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serve(art, http.MethodPatch, "/"+key, tt.authorization, `{"targetUrl": "`+tt.targetUrl+`"}`); got.Code != tt.wantStatus {
+				t.Errorf("PATCH /%s got status %d, want %d", key, got.Code, tt.wantStatus)
+			}
+			if link, err := ap.GetLink(ctx, key); err != nil || link.TargetUrl != tt.wantTargetUrl {
+				t.Errorf("GetLink() after PATCH got = %v, %v, want target url %v", link, err, tt.wantTargetUrl)
+			}
+		})
+	}
+}
+
+func TestRouter_InvalidApiKey(t *testing.T) {
+	ctx := context.Background()
+	keyed := app.NewApp(store, tokenizer, app.WithKeyStore(store.(app.KeyStore)))
+	kart, err := router.NewAppRouter(ctx, keyed, &config.RouterConfig{AdminToken: adminToken, ApiKeys: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _, err := keyed.CreateToken(app.WithPrincipal(ctx, &app.Principal{Owner: "alice"}), &app.Link{TargetUrl: "https://go.dev/public"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{"public redirect", http.MethodGet, "/" + key, "", http.StatusSeeOther},
+		{"public info", http.MethodGet, "/" + key + "/info", "", http.StatusOK},
+		{"create", http.MethodPost, "/", `{"targetUrl": "https://go.dev/anonymous"}`, http.StatusUnauthorized},
+		{"update", http.MethodPatch, "/" + key, `{"targetUrl": "https://example.com/hijacked"}`, http.StatusUnauthorized},
+		{"delete", http.MethodDelete, "/" + key, "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serve(kart, tt.method, tt.target, "Bearer invalid", tt.body); got.Code != tt.wantStatus {
+				t.Errorf("%s %s with invalid api key got status %d, want %d", tt.method, tt.target, got.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -77,6 +77,47 @@ export default class DefaultApi {
       );
     }
 
+    /**
+     * Callback function to receive the result of the deleteShortUrl operation.
+     * @callback module:api/DefaultApi~deleteShortUrlCallback
+     * @param {String} error Error message, if any.
+     * @param data This operation does not return a value.
+     * @param {String} response The complete HTTP response.
+     */
+
+    /**
+     * Delete short url (it can be restored within retention period of deleted links)
+     * @param {String} token short url token
+     * @param {module:api/DefaultApi~deleteShortUrlCallback} callback The callback function, accepting three arguments: error, data, response
+     */
+    deleteShortUrl(token, callback) {
+      let postBody = null;
+      // verify the required parameter 'token' is set
+      if (token === undefined || token === null) {
+        throw new Error("Missing the required parameter 'token' when calling deleteShortUrl");
+      }
+
+      let pathParams = {
+        'token': token
+      };
+      let queryParams = {
+      };
+      let headerParams = {
+      };
+      let formParams = {
+      };
+
+      let authNames = ['bearerAuth'];
+      let contentTypes = [];
+      let accepts = [];
+      let returnType = null;
+      return this.apiClient.callApi(
+        '/{token}', 'DELETE',
+        pathParams, queryParams, headerParams, formParams, postBody,
+        authNames, contentTypes, accepts, returnType, null, callback
+      );
+    }
+
     /**
      * Callback function to receive the result of the getShortUrlInfo operation.
      * @callback module:api/DefaultApi~getShortUrlInfoCallback
@@ -58,6 +58,16 @@
         done();
       });
     });
+    describe('deleteShortUrl', function() {
+      it('should call deleteShortUrl successfully', function(done) {
+        //uncomment below and update the code to test deleteShortUrl
+        //instance.deleteShortUrl(function(error) {
+        //  if (error) throw error;
+        //expect().to.be();
+        //});
+        done();
+      });
+    });
     describe('getShortUrlInfo', function() {
       it('should call getShortUrlInfo successfully', function(done) {
         //uncomment below and update the code to test getShortUrlInfo
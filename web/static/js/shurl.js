@@ -2,7 +2,19 @@
 
 let generateBtn = document.getElementById("generate");
 let targetUrl = document.getElementById("targetUrl");
+let alias = document.getElementById("alias");
 let expiredIn = document.getElementById("expiredIn");
+let password = document.getElementById("password");
+let maxHits = document.getElementById("maxHits");
+let activeFrom = document.getElementById("activeFrom");
+let redirectType = document.getElementById("redirectType");
+let queryPassthrough = document.getElementById("queryPassthrough");
+let pathPassthrough = document.getElementById("pathPassthrough");
+let utmFields = ["source", "medium", "campaign", "term", "content"];
+let rulePlatform = document.getElementById("rulePlatform");
+let ruleLanguage = document.getElementById("ruleLanguage");
+let ruleCidr = document.getElementById("ruleCidr");
+let ruleTargetUrl = document.getElementById("ruleTargetUrl");
 
 async function postData(url = '', data = {}) {
     const response = await fetch(url, {
@@ -17,6 +29,9 @@ async function postData(url = '', data = {}) {
         referrerPolicy: 'no-referrer', // no-referrer, *client
         body: JSON.stringify(data)
     });
+    if (!response.ok) {
+        throw new Error(`${response.status} ${response.statusText}`);
+    }
     return await response.json();
 }
 
@@ -31,15 +46,55 @@ function renderResponse(data) {
 
 generateBtn.onclick = function() {
     const requestShurl = { targetUrl: targetUrl.value }
+    if (alias.value) {
+        requestShurl.alias = alias.value
+    }
     if (expiredIn.value) {
         requestShurl.expiredInDays = parseInt(expiredIn.value)
     }
+    if (password.value) {
+        requestShurl.password = password.value
+    }
+    if (maxHits.value) {
+        requestShurl.maxHits = parseInt(maxHits.value)
+    }
+    if (activeFrom.value) {
+        requestShurl.activeFrom = new Date(activeFrom.value).toISOString()
+    }
+    if (redirectType.value) {
+        requestShurl.redirectType = parseInt(redirectType.value)
+    }
+    if (queryPassthrough.value) {
+        requestShurl.queryPassthrough = queryPassthrough.value
+    }
+    if (pathPassthrough.checked) {
+        requestShurl.pathPassthrough = true
+    }
+    for (const field of utmFields) {
+        const input = document.getElementById("utm" + field[0].toUpperCase() + field.slice(1));
+        if (input.value) {
+            requestShurl.utm = requestShurl.utm || {};
+            requestShurl.utm[field] = input.value;
+        }
+    }
+    if (ruleTargetUrl.value) {
+        const rule = { targetUrl: ruleTargetUrl.value }
+        if (rulePlatform.value) {
+            rule.platform = rulePlatform.value
+        }
+        if (ruleLanguage.value) {
+            rule.language = ruleLanguage.value
+        }
+        if (ruleCidr.value) {
+            rule.cidr = ruleCidr.value
+        }
+        requestShurl.rules = [rule]
+    }
     postData('/', requestShurl)
         .then((data) => {
             renderResponse(data);
-        }
-    );
-        // .catch((err) => {
-        //     console.error(err);
-        // });
+        })
+        .catch((err) => {
+            console.error(err);
+        });
 }